@@ -6,6 +6,7 @@ import (
 
 	"codexie.com/auditlog/internal/config"
 	"codexie.com/auditlog/internal/handler"
+	"codexie.com/auditlog/internal/middleware"
 	"codexie.com/auditlog/internal/svc"
 
 	"github.com/zeromicro/go-zero/core/conf"
@@ -21,10 +22,18 @@ func main() {
 	var c config.Config
 	conf.MustLoad(*configFile, &c)
 
+	// =============命令行子命令=============
+	if flag.NArg() > 0 {
+		runCommand(c, flag.Arg(0), flag.Args()[1:])
+		return
+	}
+
 	server := rest.MustNewServer(c.RestConf)
 	defer server.Stop()
 
 	ctx := svc.NewServiceContext(c)
+	// 解析调用方身份，查询类接口据此做租户隔离，上报和签名下载链接不需要身份
+	server.Use(middleware.NewAuthMiddleware(c.Auth).Handle)
 	handler.RegisterHandlers(server, ctx)
 
 	// =============启动pipelines=============
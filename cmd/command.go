@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"codexie.com/auditlog/internal/config"
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/internal/svc"
+)
+
+// runCommand 执行命令行子命令，用法: auditlog -f etc/auditlog-api.yaml <command> [flags]
+func runCommand(c config.Config, name string, args []string) {
+	switch name {
+	case "verify":
+		os.Exit(runVerify(c, args))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", name)
+		os.Exit(2)
+	}
+}
+
+// runVerify 校验租户哈希链，链完整时退出码为0，断裂为1，执行出错为2
+func runVerify(c config.Config, args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	tenantID := fs.String("tenant", "", "the tenant whose hash chain to verify")
+	fs.Parse(args)
+	if *tenantID == "" {
+		fmt.Fprintln(os.Stderr, "verify: -tenant is required")
+		return 2
+	}
+
+	db := svc.NewMySQL(c.MySQL)
+	res, err := model.VerifyChain(context.Background(), db, *tenantID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verify: %v\n", err)
+		return 2
+	}
+
+	out, _ := json.MarshalIndent(res, "", "  ")
+	fmt.Println(string(out))
+	if !res.Valid {
+		return 1
+	}
+	return 0
+}
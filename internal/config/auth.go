@@ -0,0 +1,6 @@
+package config
+
+// AuthConf 调用方身份认证配置
+type AuthConf struct {
+	AccessSecret string `json:",optional"` // 校验Bearer JWT的HS256密钥，未配置时拒绝所有令牌，需要身份的接口均返回401
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+)
+
+// CheckpointConf 签名检查点配置，按时间窗口为每个租户生成默克尔根并签名
+type CheckpointConf struct {
+	Enabled    bool   `json:",optional"`
+	Window     int64  `json:",default=3600,range=[1:]"` // 窗口长度，单位秒
+	Delay      int64  `json:",default=300,range=[0:]"`  // 窗口结束后延迟生成，等待管道中的数据落库，单位秒
+	MaxCatchup int    `json:",default=24,range=[1:]"`   // 单次最多补齐的窗口数
+	Lateness   int64  `json:",default=3600,range=[0:]"` // 窗口封存后才入库的迟到记录，在该时间内由后续窗口封存，单位秒
+	SigningKey string `json:",optional"`                // base64编码的Ed25519私钥种子(32字节)或私钥(64字节)
+}
+
+// PrivateKey 解析签名私钥，未配置时返回nil
+func (c CheckpointConf) PrivateKey() (ed25519.PrivateKey, error) {
+	if c.SigningKey == "" {
+		return nil, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(c.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode checkpoint signing key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("invalid checkpoint signing key length %d", len(raw))
+	}
+}
@@ -1,15 +1,25 @@
 package config
 
 import (
+	"codexie.com/auditlog/pkg/ratelimit"
 	"codexie.com/auditlog/pkg/scheduler"
+	"codexie.com/auditlog/pkg/tail"
 	"github.com/zeromicro/go-zero/rest"
 )
 
 type Config struct {
 	rest.RestConf
 
-	MySQL     MySQLConf
-	Redis     RedisConf
-	Pipelines []PiplineConfig
-	Scheduler scheduler.ScheduleConfig
+	MySQL      MySQLConf
+	Redis      RedisConf
+	Auth       AuthConf             `json:",optional"`
+	Pipelines  []PiplineConfig
+	Scheduler  scheduler.ScheduleConfig
+	RateLimit  ratelimit.Config     `json:",optional"`
+	Checkpoint CheckpointConf       `json:",optional"`
+	Query      QueryConf            `json:",optional"`
+	Export     ExportConf           `json:",optional"`
+	Stream     StreamConf           `json:",optional"`
+	Tail       tail.Config          `json:",optional"`
+	Sharding   map[string]ShardConf `json:",optional"` // 按实体名配置分表策略，未配置的实体按行数分表
 }
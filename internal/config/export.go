@@ -0,0 +1,36 @@
+package config
+
+import (
+	"strings"
+
+	"codexie.com/auditlog/pkg/blobstore"
+)
+
+// ExportConf 异步导出配置
+type ExportConf struct {
+	Storage         string           `json:",default=local,options=local|s3"` // 导出文件存储方式
+	Dir             string           `json:",default=data/export"`            // local存储的目录，多副本需挂载共享目录
+	S3              blobstore.S3Conf `json:",optional"`                       // s3存储的连接配置
+	PublicURL       string           `json:",optional"`                       // 服务对外地址，用于生成回调中的下载链接，如 https://audit.example.com
+	URLSecret       string           `json:",optional"`                       // 下载链接签名密钥，多副本需配置相同的值；未配置时每次启动随机生成
+	LinkTTL         int64            `json:",default=86400"`                  // 下载链接有效期，单位秒
+	Retention       int64            `json:",default=604800"`                 // 导出文件保留时间，单位秒
+	MaxRows         int64            `json:",default=5000000"`                // 单个任务最多导出的行数
+	BatchSize       int              `json:",default=2000,range=[1:10000]"`   // 每批从数据库读取的行数，不超过model.MaxShardOffset
+	MaxConcurrent   int              `json:",default=2"`                      // 单个实例同时执行的任务数
+	MaxAttempts     int              `json:",default=3"`                      // 实例宕机等原因中断后的最大重试次数
+	LeaseDuration   int64            `json:",default=60"`                     // 任务租约，单位秒，持有者每1/3租期续约一次
+	CallbackTimeout int64            `json:",default=10"`                     // 回调请求超时，单位秒
+	CallbackSecret  string           `json:",optional"`                       // 回调请求体签名密钥，与URLSecret不同；未配置时不接受回调地址
+	CallbackHosts   []string         `json:",optional"`                       // 允许回调的主机名，配置后只能回调这些主机且允许内网地址；未配置时只能回调公网地址
+}
+
+// CallbackHostAllowed 回调地址的主机名是否在白名单中，未配置白名单时返回false
+func (c ExportConf) CallbackHostAllowed(host string) bool {
+	for _, allowed := range c.CallbackHosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
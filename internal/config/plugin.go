@@ -1,14 +1,49 @@
 package config
 
+import "fmt"
+
 // PluginItem 表示单个插件配置项
 type PluginItem struct {
-	Name   string            `yaml:",optional" json:"name"`
-	Config map[string]string `yaml:",optional" json:"config"`
+	Name   string         `yaml:",optional" json:"name"`
+	Config map[string]any `yaml:",optional" json:"config,optional"`
+}
+
+// ExporterItem 表示单个导出插件配置项，即一条导出路由
+// 可为该导出器配置独立的过滤器和转换链，转换作用于数据副本，不影响其他导出器收到的数据
+type ExporterItem struct {
+	PluginItem
+	Id           string       `yaml:"id" json:"id,optional"` // 实例ID，同类型配置多个导出器时区分路由、指标和落盘数据；为空时见InstanceId
+	Filters      []PluginItem `yaml:"filters" json:"filters,optional"`
+	Transformers []PluginItem `yaml:"transformers" json:"transformers,optional"`
+}
+
+// InstanceIds 返回各导出器的实例ID：优先取配置的Id，未配置时类型唯一的取Name，
+// 同类型有多个的取 Name#序号(在Exporters中的下标)
+func (c PluginsConfig) InstanceIds() []string {
+	count := make(map[string]int, len(c.Exporters))
+	for _, e := range c.Exporters {
+		if e.Id == "" {
+			count[e.Name]++
+		}
+	}
+	ids := make([]string, 0, len(c.Exporters))
+	for i, e := range c.Exporters {
+		switch {
+		case e.Id != "":
+			ids = append(ids, e.Id)
+		case count[e.Name] > 1:
+			ids = append(ids, fmt.Sprintf("%s#%d", e.Name, i))
+		default:
+			ids = append(ids, e.Name)
+		}
+	}
+	return ids
 }
 
 // PluginsConfig 表示所有插件的配置
 type PluginsConfig struct {
-	Exporters  []PluginItem `yaml:"exporters" json:"exporters,optional"`
-	Filters    []PluginItem `yaml:"filters" json:"filters,optional"`
-	Lifecycles []PluginItem `yaml:"lifecycles" json:"lifecycles,optional"`
+	Exporters    []ExporterItem `yaml:"exporters" json:"exporters,optional"`
+	Filters      []PluginItem   `yaml:"filters" json:"filters,optional"`
+	Transformers []PluginItem   `yaml:"transformers" json:"transformers,optional"`
+	Lifecycles   []PluginItem   `yaml:"lifecycles" json:"lifecycles,optional"`
 }
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPluginsConfig_InstanceIds(t *testing.T) {
+	exporter := func(name, id string) ExporterItem {
+		return ExporterItem{PluginItem: PluginItem{Name: name}, Id: id}
+	}
+	conf := PluginsConfig{Exporters: []ExporterItem{
+		exporter("mysql", ""),
+		exporter("webhook", ""),
+		exporter("webhook", "siem"),
+		exporter("webhook", ""),
+	}}
+	assert.Equal(t, []string{"mysql", "webhook#1", "siem", "webhook#3"}, conf.InstanceIds())
+}
@@ -0,0 +1,9 @@
+package config
+
+// QueryConf 查询接口配置
+type QueryConf struct {
+	CursorSecret    string `json:",optional"`                               // 分页游标签名密钥，多副本需配置相同的值；未配置时每次启动随机生成
+	Search          string `json:",default=fulltext,options=fulltext|like"` // 关键字搜索方式，like无需全文索引
+	HighlightRadius int    `json:",default=40"`                             // 高亮片段在命中位置前后保留的字符数，0表示返回全文
+	StatsCacheTTL   int    `json:",default=30"`                             // 统计结果在Redis中的缓存时间(秒)，0表示不缓存
+}
@@ -0,0 +1,8 @@
+package config
+
+// ShardConf 实体分表配置
+type ShardConf struct {
+	Strategy  string `json:",default=rows,options=rows|daily|weekly|monthly"` // 分表策略
+	MaxRows   int64  `json:",optional"`                                       // rows策略下单表最大记录数，默认3000万
+	PreCreate int    `json:",default=1"`                                      // 按时间分表时预先创建的后续分表数
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"time"
+
+	"codexie.com/auditlog/internal/constant"
+)
+
+// StreamConf 流式查询配置，行数与时间限制可按角色覆盖
+type StreamConf struct {
+	BatchSize   int                    `json:",default=1000,range=[1:10000]"` // 每批从分表读取的行数，不超过model.MaxShardOffset
+	MaxRows     int64                  `json:",default=100000"`               // 单次请求最多返回的行数
+	MaxDuration int64                  `json:",default=60"`                   // 单次请求最长持续时间，单位秒
+	Roles       map[string]StreamLimit `json:",optional"`                     // 按角色覆盖的限制
+}
+
+// StreamLimit 角色的流式查询限制，未配置的项使用全局值
+type StreamLimit struct {
+	MaxRows     int64 `json:",optional"`
+	MaxDuration int64 `json:",optional"`
+}
+
+// Limit 返回用户的行数与时间限制，时间不超过MAX_STREAM_DURATION
+// 用户有多个已配置的角色时取其中最宽松的值，都未配置时使用全局值
+func (c StreamConf) Limit(roles []string) (int64, time.Duration) {
+	var rows, seconds int64
+	for _, role := range roles {
+		limit, ok := c.Roles[role]
+		if !ok {
+			continue
+		}
+		if limit.MaxRows == 0 {
+			limit.MaxRows = c.MaxRows
+		}
+		if limit.MaxDuration == 0 {
+			limit.MaxDuration = c.MaxDuration
+		}
+		rows, seconds = max(rows, limit.MaxRows), max(seconds, limit.MaxDuration)
+	}
+	if rows == 0 {
+		rows, seconds = c.MaxRows, c.MaxDuration
+	}
+	return rows, min(time.Duration(seconds)*time.Second, constant.MAX_STREAM_DURATION)
+}
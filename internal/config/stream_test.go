@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/internal/constant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamConf_Limit(t *testing.T) {
+	conf := StreamConf{
+		MaxRows:     1000,
+		MaxDuration: 60,
+		Roles: map[string]StreamLimit{
+			"auditor":  {MaxRows: 50000},
+			"exporter": {MaxRows: 20000, MaxDuration: 600},
+			"admin":    {MaxDuration: 7200},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		roles    []string
+		rows     int64
+		duration time.Duration
+	}{
+		{name: "no roles", rows: 1000, duration: time.Minute},
+		{name: "unconfigured role", roles: []string{"viewer"}, rows: 1000, duration: time.Minute},
+		{name: "unset duration falls back to global", roles: []string{"auditor"}, rows: 50000, duration: time.Minute},
+		{name: "unset rows falls back to global", roles: []string{"admin"}, rows: 1000, duration: constant.MAX_STREAM_DURATION},
+		{name: "most permissive of each limit", roles: []string{"viewer", "auditor", "exporter"}, rows: 50000, duration: 10 * time.Minute},
+		{name: "duration capped", roles: []string{"admin", "auditor"}, rows: 50000, duration: constant.MAX_STREAM_DURATION},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, duration := conf.Limit(tt.roles)
+			assert.Equal(t, tt.rows, rows)
+			assert.Equal(t, tt.duration, duration)
+		})
+	}
+}
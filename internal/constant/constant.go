@@ -1,5 +1,7 @@
 package constant
 
+import "time"
+
 const (
 	USER  = "user"
 	ADMIN = "admin"
@@ -8,10 +10,84 @@ const (
 const (
 	MAX_PAGE      = 1000
 	MAX_PAGE_SIZE = 1000
+	MAX_OFFSET    = 10000 // 偏移分页可访问的最大深度(page*page_size)，更深的数据需按游标翻页，与model.MaxShardOffset一致
 )
 
 const (
-	SchedulePosKey = "schedule:pos:"
+	MAX_STATS_DIMENSIONS = 3     // 统计接口最多同时分组的维度数
+	MAX_STATS_TOP        = 1000  // 统计接口top的上限
+	MAX_STATS_BUCKETS    = 10000 // 统计接口时间范围内的最大分桶数，乘以10不超过model.MaxStatsGroups
+)
+
+// MAX_STREAM_DURATION 流式查询的最长持续时间，与api中/stream路由的超时时间一致，配置的时间限制不能超过它
+const MAX_STREAM_DURATION = time.Hour
+
+const (
+	SchedulePosChannel = "schedule:pos:changed" // 分表位置变更通知，消息内容为实体名
 )
 
 var ValidFields = map[string]bool{"created_at": true, "action": true, "resource_type": true}
+
+// QueryFields 查询DSL可过滤的字段及类型(见querydsl.Type*)，在ValidFields排序字段的基础上扩展
+var QueryFields = map[string]string{
+	"created_at":    "time",
+	"action":        "string",
+	"resource_type": "string",
+	"tenant_id":     "string",
+	"user_id":       "string",
+	"username":      "string",
+	"resource_id":   "string",
+	"resource_name": "string",
+	"result":        "string",
+	"module":        "string",
+	"trace_id":      "string",
+	"log_id":        "string",
+	"client_ip":     "ip",
+	"timestamp":     "int",
+	"seq":           "int",
+}
+
+// StatsDimensions 统计接口可分组的维度
+var StatsDimensions = map[string]bool{
+	"action":        true,
+	"result":        true,
+	"module":        true,
+	"user_id":       true,
+	"resource_type": true,
+	"tenant_id":     true,
+}
+
+// StatsIntervals 统计接口支持的分桶间隔
+var StatsIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"6h":  6 * time.Hour,
+	"1d":  24 * time.Hour,
+	"1w":  7 * 24 * time.Hour,
+}
+
+// RecordFields 查询结果可投影的字段，与审计日志表的列名一致
+var RecordFields = map[string]bool{
+	"log_id":          true,
+	"tenant_id":       true,
+	"user_id":         true,
+	"username":        true,
+	"action":          true,
+	"resource_type":   true,
+	"resource_id":     true,
+	"resource_name":   true,
+	"result":          true,
+	"message":         true,
+	"timestamp":       true,
+	"client_ip":       true,
+	"module":          true,
+	"trace_id":        true,
+	"idempotency_key": true,
+	"seq":             true,
+	"prev_hash":       true,
+	"hash":            true,
+	"created_at":      true,
+	"updated_at":      true,
+}
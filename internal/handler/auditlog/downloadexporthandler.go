@@ -0,0 +1,36 @@
+package auditlog
+
+import (
+	"mime"
+	"net/http"
+
+	"codexie.com/auditlog/internal/logic/auditlog"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+// DownloadExportHandler 以附件形式返回导出文件，Range、If-Range等条件请求由http.ServeContent处理
+func DownloadExportHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.ExportDownloadRequest
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := auditlog.NewDownloadExportLogic(r.Context(), svcCtx)
+		file, err := l.DownloadExport(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+		defer file.Content.Close()
+
+		w.Header().Set("Content-Type", file.ContentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+		w.Header().Set("ETag", file.ETag)
+		w.Header().Set("Cache-Control", "private, no-store")
+		http.ServeContent(w, r, file.Name, file.ModTime, file.Content)
+	}
+}
@@ -17,6 +17,11 @@ func ExportLogsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			return
 		}
 
+		if err := req.Validate(r.Context()); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
 		l := auditlog.NewExportLogsLogic(r.Context(), svcCtx)
 		resp, err := l.ExportLogs(&req)
 		if err != nil {
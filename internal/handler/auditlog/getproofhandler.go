@@ -0,0 +1,28 @@
+package auditlog
+
+import (
+	"net/http"
+
+	"codexie.com/auditlog/internal/logic/auditlog"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func GetProofHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.ProofRequest
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := auditlog.NewGetProofLogic(r.Context(), svcCtx)
+		resp, err := l.GetProof(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
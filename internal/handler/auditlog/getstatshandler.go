@@ -0,0 +1,33 @@
+package auditlog
+
+import (
+	"net/http"
+
+	"codexie.com/auditlog/internal/logic/auditlog"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func GetStatsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.StatsRequest
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		if err := req.Validate(r.Context()); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := auditlog.NewGetStatsLogic(r.Context(), svcCtx)
+		resp, err := l.GetStats(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
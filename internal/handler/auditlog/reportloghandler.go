@@ -16,6 +16,10 @@ func ReportLogHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 			httpx.ErrorCtx(r.Context(), w, err)
 			return
 		}
+		// 幂等键也可通过请求头传递
+		if req.IdempotencyKey == "" {
+			req.IdempotencyKey = r.Header.Get("Idempotency-Key")
+		}
 
 		l := auditlog.NewReportLogLogic(r.Context(), svcCtx)
 		resp, err := l.ReportLog(&req)
@@ -0,0 +1,131 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"codexie.com/auditlog/internal/logic/auditlog"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+// StreamLogsHandler 以分块传输逐批输出查询结果
+// NDJSON在HTTP trailer X-Stream-Status/X-Stream-Rows中给出结束状态，SSE以end事件给出
+func StreamLogsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.StreamRequest
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		if err := req.Validate(r.Context()); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		sw := &streamWriter{w: w, sse: req.Format == "sse" || strings.Contains(r.Header.Get("Accept"), "text/event-stream")}
+		l := auditlog.NewStreamLogsLogic(r.Context(), svcCtx)
+		result, err := l.StreamLogs(&req, sw.send)
+		switch {
+		case err == nil:
+			sw.finish(result)
+		case !sw.started:
+			httpx.ErrorCtx(r.Context(), w, err)
+		case r.Context().Err() != nil:
+			logx.WithContext(r.Context()).Infof("stream client disconnected after %d rows", result.Rows)
+		default:
+			logx.WithContext(r.Context()).Errorf("stream interrupted after %d rows: %v", result.Rows, err)
+			sw.fail(err)
+		}
+	}
+}
+
+// streamWriter 首次输出时才写入响应头，之前的错误仍可按普通错误响应返回
+type streamWriter struct {
+	w       http.ResponseWriter
+	sse     bool
+	started bool
+}
+
+func (s *streamWriter) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	h := s.w.Header()
+	if s.sse {
+		h.Set("Content-Type", "text/event-stream")
+	} else {
+		h.Set("Content-Type", "application/x-ndjson")
+		h.Set("Trailer", "X-Stream-Status, X-Stream-Rows")
+	}
+	h.Set("Cache-Control", "no-store")
+	h.Set("X-Accel-Buffering", "no") // 禁止反向代理缓冲
+	s.w.WriteHeader(http.StatusOK)
+}
+
+func (s *streamWriter) send(records []types.LogRecord) error {
+	s.start()
+	var buf []byte
+	for i := range records {
+		data, err := json.Marshal(&records[i])
+		if err != nil {
+			return err
+		}
+		buf = s.appendEvent(buf, "record", data)
+	}
+	if _, err := s.w.Write(buf); err != nil {
+		return err
+	}
+	s.flush()
+	return nil
+}
+
+func (s *streamWriter) finish(result *auditlog.StreamResult) {
+	s.start()
+	if s.sse {
+		data, _ := json.Marshal(result)
+		s.w.Write(s.appendEvent(nil, "end", data))
+	} else {
+		s.w.Header().Set("X-Stream-Status", result.Status)
+		s.w.Header().Set("X-Stream-Rows", strconv.FormatInt(result.Rows, 10))
+	}
+	s.flush()
+}
+
+// fail 已开始输出后发生错误，无法再修改状态码，通过trailer或error事件告知客户端结果不完整
+func (s *streamWriter) fail(err error) {
+	if s.sse {
+		data, _ := json.Marshal(map[string]string{"error": err.Error()})
+		s.w.Write(s.appendEvent(nil, "error", data))
+	} else {
+		s.w.Header().Set("X-Stream-Status", "error")
+	}
+	s.flush()
+}
+
+func (s *streamWriter) appendEvent(buf []byte, event string, data []byte) []byte {
+	if s.sse {
+		return appendSSE(buf, event, data)
+	}
+	buf = append(buf, data...)
+	return append(buf, '\n')
+}
+
+// appendSSE 追加一条SSE事件，data为单行JSON
+func appendSSE(buf []byte, event string, data []byte) []byte {
+	buf = append(buf, "event: "+event+"\ndata: "...)
+	buf = append(buf, data...)
+	return append(buf, "\n\n"...)
+}
+
+func (s *streamWriter) flush() {
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,152 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"codexie.com/auditlog/internal/logic/auditlog"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"golang.org/x/net/websocket"
+)
+
+const tailWriteTimeout = 10 * time.Second // WebSocket单条消息的写超时
+
+// tailMessage WebSocket推送的消息，event与SSE的事件名一致: record/skipped/ping/error
+type tailMessage struct {
+	Event string `json:"event"`
+	Data  any    `json:"data,omitempty"`
+}
+
+// TailLogsHandler 实时推送新写入的日志，WebSocket握手请求以JSON文本帧推送，其余以SSE推送
+// WebSocket握手只接受同源、不带Origin或Tail.AllowedOrigins中的来源，拒绝时返回403
+// 因消费过慢被丢弃的记录数通过skipped事件告知，持续跟不上的订阅者收到error事件后被断开
+func TailLogsHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.TailRequest
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		if err := req.Validate(r.Context()); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := auditlog.NewTailLogsLogic(r.Context(), svcCtx)
+		sub, err := l.Subscribe(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+		defer sub.Close()
+
+		forward := func(ctx context.Context, send func(*auditlog.TailEvent) error) error {
+			return l.Forward(ctx, sub, req.FieldList(), send)
+		}
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			websocket.Server{
+				Handshake: func(_ *websocket.Config, r *http.Request) error {
+					if !svcCtx.Config.Tail.OriginAllowed(r.Header.Get("Origin"), r.Host) {
+						return fmt.Errorf("origin %q is not allowed", r.Header.Get("Origin"))
+					}
+					return nil
+				},
+				Handler: func(ws *websocket.Conn) { serveTailWebSocket(ws, forward) },
+			}.ServeHTTP(w, r)
+			return
+		}
+		serveTailSSE(w, r, forward)
+	}
+}
+
+func serveTailSSE(w http.ResponseWriter, r *http.Request, forward func(context.Context, func(*auditlog.TailEvent) error) error) {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-store")
+	h.Set("X-Accel-Buffering", "no") // 禁止反向代理缓冲
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	flush()
+
+	err := forward(r.Context(), func(e *auditlog.TailEvent) error {
+		var buf []byte
+		for i := range e.Records {
+			data, err := json.Marshal(&e.Records[i])
+			if err != nil {
+				return err
+			}
+			buf = appendSSE(buf, "record", data)
+		}
+		if e.Skipped > 0 {
+			buf = appendSSE(buf, "skipped", skippedData(e.Skipped))
+		}
+		if len(buf) == 0 {
+			buf = []byte(": ping\n\n")
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+		flush()
+		return nil
+	})
+	if err != nil && r.Context().Err() == nil {
+		logx.WithContext(r.Context()).Infof("tail subscription ended: %v", err)
+		data, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Write(appendSSE(nil, "error", data))
+		flush()
+	}
+}
+
+func serveTailWebSocket(ws *websocket.Conn, forward func(context.Context, func(*auditlog.TailEvent) error) error) {
+	defer ws.Close()
+	// 连接被劫持后请求的ctx不再感知断开，由读循环在对端关闭时取消
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		var msg string
+		for websocket.Message.Receive(ws, &msg) == nil {
+		}
+	}()
+
+	send := func(msg *tailMessage) error {
+		ws.SetWriteDeadline(time.Now().Add(tailWriteTimeout))
+		return websocket.JSON.Send(ws, msg)
+	}
+	err := forward(ctx, func(e *auditlog.TailEvent) error {
+		for i := range e.Records {
+			if err := send(&tailMessage{Event: "record", Data: &e.Records[i]}); err != nil {
+				return err
+			}
+		}
+		if e.Skipped > 0 {
+			return send(&tailMessage{Event: "skipped", Data: json.RawMessage(skippedData(e.Skipped))})
+		}
+		if len(e.Records) == 0 {
+			return send(&tailMessage{Event: "ping"})
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		logx.WithContext(ctx).Infof("tail subscription ended: %v", err)
+		send(&tailMessage{Event: "error", Data: map[string]string{"error": err.Error()}})
+	}
+}
+
+func skippedData(n int64) []byte {
+	data, _ := json.Marshal(map[string]int64{"count": n})
+	return data
+}
@@ -14,7 +14,24 @@ func ApiErrorHandler(ctx context.Context, err error) (int, any) {
 	case *apierr.CodeError:
 		// 打印错误日志
 		logx.Errorw("api error", logx.Field("error", err))
-		return http.StatusBadRequest, &types.BaseResponse{
+		status := http.StatusBadRequest
+		switch err.(*apierr.CodeError).RootCode() {
+		case apierr.ErrRateLimited.RootCauseCode, apierr.ErrQuotaExceeded.RootCauseCode:
+			status = http.StatusTooManyRequests
+		case apierr.ErrLogNotFound.RootCauseCode, apierr.ErrExportNotFound.RootCauseCode:
+			status = http.StatusNotFound
+		case apierr.ErrCheckpointMismatch.RootCauseCode, apierr.ErrExportFinished.RootCauseCode:
+			status = http.StatusConflict
+		case apierr.ErrExportNotReady.RootCauseCode:
+			status = http.StatusGone
+		case apierr.ErrDownloadDenied.RootCauseCode, apierr.ErrTenantRequired.RootCauseCode:
+			status = http.StatusForbidden
+		case apierr.ErrUnauthenticated.RootCauseCode:
+			status = http.StatusUnauthorized
+		case apierr.ErrTooManySubscribers.RootCauseCode:
+			status = http.StatusServiceUnavailable
+		}
+		return status, &types.BaseResponse{
 			Code:    err.(*apierr.CodeError).RootCode(),
 			Message: err.Error(),
 		}
@@ -3,6 +3,7 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
 	auditlog "codexie.com/auditlog/internal/handler/auditlog"
 	"codexie.com/auditlog/internal/svc"
@@ -18,6 +19,26 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/export",
 				Handler: auditlog.ExportLogsHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/export",
+				Handler: auditlog.ListExportTasksHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/export/:task_id",
+				Handler: auditlog.GetExportTaskHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodDelete,
+				Path:    "/export/:task_id",
+				Handler: auditlog.CancelExportTaskHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/export/:task_id/download",
+				Handler: auditlog.DownloadExportHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodGet,
 				Path:    "/query",
@@ -28,7 +49,43 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/report",
 				Handler: auditlog.ReportLogHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/verify",
+				Handler: auditlog.VerifyChainHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/proof",
+				Handler: auditlog.GetProofHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/search",
+				Handler: auditlog.SearchLogsHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/stats",
+				Handler: auditlog.GetStatsHandler(serverCtx),
+			},
+		},
+		rest.WithPrefix("/v1/audit"),
+	)
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodGet,
+				Path:    "/stream",
+				Handler: auditlog.StreamLogsHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/tail",
+				Handler: auditlog.TailLogsHandler(serverCtx),
+			},
 		},
 		rest.WithPrefix("/v1/audit"),
+		rest.WithTimeout(3600000*time.Millisecond),
 	)
 }
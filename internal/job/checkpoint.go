@@ -0,0 +1,107 @@
+package job
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"time"
+
+	"codexie.com/auditlog/internal/config"
+	"codexie.com/auditlog/internal/model"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+// CheckpointTask 按时间窗口为每个租户生成签名的默克尔根
+type CheckpointTask struct {
+	nextRunTime time.Time
+	db          *gorm.DB
+	conf        config.CheckpointConf
+	key         ed25519.PrivateKey
+}
+
+func NewCheckpointJob(db *gorm.DB, conf config.CheckpointConf, key ed25519.PrivateKey) *CheckpointTask {
+	return &CheckpointTask{
+		db:   db,
+		conf: conf,
+		key:  key,
+	}
+}
+
+func (c *CheckpointTask) Name() string {
+	return "CheckpointTask"
+}
+
+func (c *CheckpointTask) Priority() int {
+	return 2
+}
+
+func (c *CheckpointTask) ExeInterval() int64 {
+	return 60 // 每60秒检查一次是否有窗口需要生成检查点
+}
+
+// Run 从最近的检查点开始，依次处理已结束且超过延迟时间的窗口
+// 同一窗口的所有租户检查点、树节点与处理进度在一个事务中保存
+// 没有数据的窗口不写检查点，只推进进度，后续运行不再重复扫描
+func (c *CheckpointTask) Run() error {
+	window := time.Duration(c.conf.Window) * time.Second
+	if window <= 0 {
+		return fmt.Errorf("invalid checkpoint window %ds", c.conf.Window)
+	}
+	end := time.Now().Add(-time.Duration(c.conf.Delay) * time.Second).Truncate(window)
+
+	start, err := model.LatestCheckpointEnd(c.db)
+	if err != nil {
+		return fmt.Errorf("failed to get latest checkpoint: %w", err)
+	}
+	// 首次运行或长时间停止后，最多补齐MaxCatchup个窗口
+	if earliest := end.Add(-time.Duration(c.conf.MaxCatchup) * window); start.Before(earliest) {
+		start = earliest
+	}
+
+	for ; start.Before(end); start = start.Add(window) {
+		if err := c.checkpoint(start, start.Add(window)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkpoint 封存窗口内的记录，以及入库时间在Lateness内、此前窗口封存时尚未落库的迟到记录
+// 已封存的记录不再变化，迟到记录不会使已签名的检查点失效
+func (c *CheckpointTask) checkpoint(start, end time.Time) error {
+	ctx := context.Background()
+	from := start.Add(-time.Duration(c.conf.Lateness) * time.Second)
+	tenants, err := model.CheckpointTenants(ctx, c.db, from, end)
+	if err != nil {
+		return fmt.Errorf("failed to get tenants of window %s: %w", start, err)
+	}
+
+	cps := make([]*model.Checkpoint, 0, len(tenants))
+	for _, tenant := range tenants {
+		leaves, err := model.CheckpointLeaves(ctx, c.db, tenant, from, end)
+		if err != nil {
+			return fmt.Errorf("failed to get leaves of tenant %s: %w", tenant, err)
+		}
+		if len(leaves) == 0 {
+			continue
+		}
+		cp := model.NewCheckpoint(tenant, start, end, leaves)
+		cp.Sign(c.key)
+		cps = append(cps, cp)
+	}
+
+	if err := model.SaveCheckpoints(c.db, cps, end); err != nil {
+		return fmt.Errorf("failed to save checkpoints of window %s: %w", start, err)
+	}
+	logx.Infof("checkpoint window [%s, %s) done, tenants: %d", start.Format(time.RFC3339), end.Format(time.RFC3339), len(cps))
+	return nil
+}
+
+func (c *CheckpointTask) NextRunTime() time.Time {
+	return c.nextRunTime
+}
+
+func (c *CheckpointTask) SetNextRunTime(t time.Time) {
+	c.nextRunTime = t
+}
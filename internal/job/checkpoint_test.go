@@ -0,0 +1,160 @@
+package job
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/internal/config"
+	"codexie.com/auditlog/internal/model"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// auditLogDDL 测试用分表结构，sqlite不支持AuditLog声明的全文索引，因此手写建表语句
+const auditLogDDL = `CREATE TABLE %s (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	log_id TEXT, tenant_id TEXT, user_id TEXT, username TEXT, action TEXT,
+	resource_type TEXT, resource_id TEXT, resource_name TEXT, result TEXT, message TEXT,
+	timestamp INTEGER, client_ip TEXT, module TEXT, trace_id TEXT, idempotency_key TEXT,
+	seq INTEGER, prev_hash TEXT, hash TEXT, created_at DATETIME, updated_at DATETIME)`
+
+// sqliteDB 创建内存数据库，包含任务用到的表和一张已登记的活跃分表audit_log_1
+func sqliteDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	require.NoError(t, db.AutoMigrate(&model.ShardCatalog{}, &model.Checkpoint{}, &model.CheckpointNode{},
+		&model.CheckpointProgress{}, &model.ExportJob{}))
+	require.NoError(t, db.Exec(fmt.Sprintf(auditLogDDL, "audit_log_1")).Error)
+	require.NoError(t, model.RegisterShard(db, model.AuditLogName, 1))
+	return db
+}
+
+func insertLog(t *testing.T, db *gorm.DB, logId, tenant string, createdAt time.Time) {
+	t.Helper()
+	log := &model.AuditLog{LogId: logId, TenantID: tenant, Action: "login", Result: "success", CreatedAt: createdAt}
+	require.NoError(t, db.Table("audit_log_1").Create(log).Error)
+}
+
+func checkpoints(t *testing.T, db *gorm.DB) []model.Checkpoint {
+	t.Helper()
+	var cps []model.Checkpoint
+	require.NoError(t, db.Order("window_start, tenant_id").Find(&cps).Error)
+	return cps
+}
+
+func TestCheckpointTask_Run(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	pub := key.Public().(ed25519.PublicKey)
+	conf := config.CheckpointConf{Window: 3600, MaxCatchup: 3, Lateness: 3600}
+	end := time.Now().Truncate(time.Hour)
+
+	type window struct {
+		offset time.Duration // 窗口起点相对end的偏移
+		tenant string
+		leaves int64
+	}
+	tests := []struct {
+		name    string
+		records map[string]time.Duration // log_id -> 入库时间相对end的偏移
+		want    []window
+	}{
+		{
+			name: "empty windows only advance progress",
+		},
+		{
+			name: "records grouped by window",
+			records: map[string]time.Duration{
+				"log-1": -150 * time.Minute, "log-2": -140 * time.Minute, "log-3": -30 * time.Minute,
+			},
+			want: []window{{-3 * time.Hour, "t1", 2}, {-time.Hour, "t1", 1}},
+		},
+		{
+			name: "records before catchup limit are skipped",
+			records: map[string]time.Duration{
+				"log-old": -5 * time.Hour, "log-1": -10 * time.Minute,
+			},
+			want: []window{{-time.Hour, "t1", 1}},
+		},
+		{
+			name: "current window is not sealed",
+			records: map[string]time.Duration{
+				"log-now": time.Minute,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := sqliteDB(t)
+			for logId, offset := range tt.records {
+				insertLog(t, db, logId, "t1", end.Add(offset))
+			}
+
+			task := NewCheckpointJob(db, conf, key)
+			require.NoError(t, task.Run())
+			// 再次运行不重复生成检查点
+			require.NoError(t, task.Run())
+
+			cps := checkpoints(t, db)
+			require.Len(t, cps, len(tt.want))
+			for i, w := range tt.want {
+				assert.True(t, cps[i].WindowStart.Equal(end.Add(w.offset)), "window %d starts at %s", i, cps[i].WindowStart)
+				assert.Equal(t, w.tenant, cps[i].TenantID)
+				assert.Equal(t, w.leaves, cps[i].LeafCount)
+
+				sig, err := base64.StdEncoding.DecodeString(cps[i].Signature)
+				require.NoError(t, err)
+				assert.True(t, ed25519.Verify(pub, cps[i].SignedMessage(), sig))
+			}
+
+			latest, err := model.LatestCheckpointEnd(db)
+			require.NoError(t, err)
+			assert.True(t, latest.Equal(end), "progress at %s", latest)
+		})
+	}
+}
+
+func TestCheckpointTask_LateRecord(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	db := sqliteDB(t)
+	task := NewCheckpointJob(db, config.CheckpointConf{Window: 3600, MaxCatchup: 1, Lateness: 3600}, key)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	insertLog(t, db, "log-1", "t1", start.Add(time.Minute))
+	require.NoError(t, task.checkpoint(start, start.Add(time.Hour)))
+
+	// 窗口封存后才落库的记录由下一个窗口封存，已签名的检查点不变
+	insertLog(t, db, "log-late", "t1", start.Add(2*time.Minute))
+	require.NoError(t, task.checkpoint(start.Add(time.Hour), start.Add(2*time.Hour)))
+	// 超过迟到时间的记录不再封存
+	insertLog(t, db, "log-expired", "t1", start.Add(3*time.Minute))
+	require.NoError(t, task.checkpoint(start.Add(2*time.Hour), start.Add(3*time.Hour)))
+
+	cps := checkpoints(t, db)
+	require.Len(t, cps, 2)
+	assert.Equal(t, int64(1), cps[0].LeafCount)
+	assert.Equal(t, int64(1), cps[1].LeafCount)
+
+	for logId, want := range map[string]*model.Checkpoint{"log-1": &cps[0], "log-late": &cps[1], "log-expired": nil} {
+		leaf, err := model.FindCheckpointLeaf(db, logId)
+		require.NoError(t, err)
+		if want == nil {
+			assert.Nil(t, leaf, logId)
+			continue
+		}
+		require.NotNil(t, leaf, logId)
+		assert.Equal(t, want.Id, leaf.CheckpointID, logId)
+	}
+}
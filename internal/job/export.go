@@ -0,0 +1,417 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"codexie.com/auditlog/internal/config"
+	"codexie.com/auditlog/internal/constant"
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/blobstore"
+	"codexie.com/auditlog/pkg/plugin"
+	"codexie.com/auditlog/pkg/querydsl"
+	"codexie.com/auditlog/pkg/tabular"
+	"codexie.com/auditlog/pkg/util"
+
+	"github.com/google/uuid"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+const callbackRetries = 3
+
+// ExportTask 领取并执行异步导出任务
+// 调度锁保证同一时刻只有一个实例在领取，任务本身通过export_job上的租约归属执行实例，
+// 实例宕机后租约过期，任务会被重新领取并从头执行
+type ExportTask struct {
+	nextRunTime time.Time
+	db          *gorm.DB
+	store       blobstore.Store
+	conf        config.ExportConf
+	searchMode  string // 关键字搜索方式，见model.SearchFullText
+	key         []byte // 下载链接签名密钥
+	holder      string // 本实例的租约持有者标识
+	running     atomic.Int32
+	client      *http.Client
+}
+
+func NewExportJob(db *gorm.DB, store blobstore.Store, conf config.ExportConf, searchMode string, key []byte) *ExportTask {
+	ip, err := util.GetLocalIP()
+	if err != nil {
+		panic(err)
+	}
+	return &ExportTask{
+		db:         db,
+		store:      store,
+		conf:       conf,
+		searchMode: searchMode,
+		key:        key,
+		holder:     ip + "-" + uuid.New().String()[:12],
+		client:     newCallbackClient(conf),
+	}
+}
+
+// newCallbackClient 回调只能连接公网地址或白名单中的主机，且不跟随重定向，避免回调被用于访问内网(SSRF)
+func newCallbackClient(conf config.ExportConf) *http.Client {
+	timeout := time.Duration(conf.CallbackTimeout) * time.Second
+	public := &net.Dialer{Timeout: timeout, Control: util.PublicOnly}
+	trusted := &net.Dialer{Timeout: timeout}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				if host, _, err := net.SplitHostPort(addr); err == nil && conf.CallbackHostAllowed(host) {
+					return trusted.DialContext(ctx, network, addr)
+				}
+				return public.DialContext(ctx, network, addr)
+			},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func (e *ExportTask) Name() string {
+	return "ExportTask"
+}
+
+func (e *ExportTask) Priority() int {
+	return 1
+}
+
+func (e *ExportTask) ExeInterval() int64 {
+	return 5 // 每5秒领取一次新任务
+}
+
+// Run 按空闲并发数领取任务后立即返回，任务在后台协程中执行
+func (e *ExportTask) Run() error {
+	free := e.conf.MaxConcurrent - int(e.running.Load())
+	if free <= 0 {
+		return nil
+	}
+	jobs, err := model.ClaimExportJobs(e.db, e.holder, e.lease(), free)
+	for _, job := range jobs {
+		e.running.Add(1)
+		go func() {
+			defer e.running.Add(-1)
+			defer func() {
+				if r := recover(); r != nil {
+					logx.Errorf("export job %s panic: %v", job.TaskID, r)
+				}
+			}()
+			e.execute(job)
+		}()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim export jobs: %w", err)
+	}
+	return nil
+}
+
+func (e *ExportTask) lease() time.Duration {
+	return time.Duration(e.conf.LeaseDuration) * time.Second
+}
+
+// execute 执行单个任务，租约丢失(任务被取消或被其他实例接管)时放弃执行且不再修改任务
+func (e *ExportTask) execute(job *model.ExportJob) {
+	started := time.Now()
+	job.StartedAt = &started
+	if job.Attempts > e.conf.MaxAttempts {
+		job.Status, job.Error = model.ExportFailed, fmt.Sprintf("interrupted %d times, giving up", job.Attempts-1)
+		e.finish(job)
+		return
+	}
+
+	file, err := os.CreateTemp("", "export-*."+tabular.Ext(job.Format))
+	if err != nil {
+		job.Status, job.Error = model.ExportFailed, err.Error()
+		e.finish(job)
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	// 执行期间(含上传)定期续约并同步进度，租约丢失时中止写出
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var progress, total atomic.Int64
+	var lost atomic.Bool
+	go func() {
+		ticker := time.NewTicker(e.lease() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := model.RenewExportLease(e.db, job, progress.Load(), total.Load(), e.lease())
+				if errors.Is(err, model.ErrExportLeaseLost) {
+					lost.Store(true)
+					cancel()
+					return
+				}
+				if err != nil {
+					logx.Errorf("renew export job %s lease failed: %v", job.TaskID, err)
+				}
+			}
+		}
+	}()
+
+	err = e.write(ctx, job, file, &progress, &total)
+	if lost.Load() {
+		logx.Infof("export job %s canceled or taken over, progress: %d", job.TaskID, progress.Load())
+		return
+	}
+	job.Progress, job.Total = progress.Load(), total.Load()
+	if err != nil {
+		job.Status, job.Error = model.ExportFailed, err.Error()
+		e.finish(job)
+		return
+	}
+
+	if err := e.upload(job, file); err != nil {
+		job.Status, job.Error = model.ExportFailed, err.Error()
+		e.finish(job)
+		return
+	}
+	job.Status = model.ExportSucceeded
+	if !e.finish(job) {
+		// 上传期间任务被取消，删除已上传的文件
+		if err := e.store.Delete(context.Background(), job.FileKey); err != nil {
+			logx.Errorf("delete export file %s failed: %v", job.FileKey, err)
+		}
+	}
+}
+
+// write 跨分表按(created_at, log_id)的键集顺序扫描满足条件的记录，逐批写入文件
+func (e *ExportTask) write(ctx context.Context, job *model.ExportJob, file *os.File, progress, total *atomic.Int64) error {
+	scope, start, end, err := e.scope(job)
+	if err != nil {
+		return err
+	}
+	tables, err := model.ShardTablesInRange(e.db, model.AuditLogName, start, end)
+	if err != nil {
+		return fmt.Errorf("read shard catalog: %w", err)
+	}
+	count, err := model.CountShards(ctx, e.db, tables, scope)
+	if err != nil {
+		return err
+	}
+	if total.Store(count); count > e.conf.MaxRows {
+		return fmt.Errorf("%d rows match the query, exceeding the limit of %d", count, e.conf.MaxRows)
+	}
+
+	columns := model.ExportColumns
+	var fields []string
+	if job.Fields != "" {
+		fields = strings.Split(job.Fields, ",")
+		columns = fields
+	}
+	w, err := tabular.New(job.Format, file, columns, model.AuditLog{})
+	if err != nil {
+		return err
+	}
+
+	row := make([]any, len(columns))
+	var after *model.Keyset
+	for {
+		page, err := model.QueryShardPage(ctx, e.db, model.ShardQuery{
+			Tables:    tables,
+			Scope:     scope,
+			SortField: "created_at",
+			Limit:     e.conf.BatchSize,
+			Select:    fields,
+			After:     after,
+		})
+		if err != nil {
+			return err
+		}
+		// 已取完的分表之后的批次不再查询
+		tables = slices.DeleteFunc(tables, func(table string) bool { return slices.Contains(page.Exhausted, table) })
+		logs := page.Logs
+		for _, log := range logs {
+			for i, col := range columns {
+				row[i], _ = plugin.FieldValue(log, col)
+			}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+		// 统计总数后写入的新数据同样会被导出，以MaxRows兜底
+		if progress.Add(int64(len(logs))) > e.conf.MaxRows {
+			return fmt.Errorf("exported rows exceed the limit of %d", e.conf.MaxRows)
+		}
+		if len(logs) < e.conf.BatchSize {
+			break
+		}
+		last := logs[len(logs)-1]
+		after = &model.Keyset{Value: last.CreatedAt, LogId: last.LogId}
+	}
+	return w.Close()
+}
+
+// scope 由任务中保存的查询条件构造过滤条件，并从中提取用于裁剪分表的时间范围
+func (e *ExportTask) scope(job *model.ExportJob) (func(*gorm.DB) *gorm.DB, time.Time, time.Time, error) {
+	var node *querydsl.Node
+	if job.Filter != "" {
+		var err error
+		if node, err = querydsl.Parse([]byte(job.Filter)); err != nil {
+			return nil, time.Time{}, time.Time{}, err
+		}
+	}
+	query, err := querydsl.Compile(querydsl.Schema(constant.QueryFields), node)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	cond, args := query.SQL()
+	start, end := query.TimeRange("created_at")
+	return func(db *gorm.DB) *gorm.DB {
+		if cond != "" {
+			db = db.Where(cond, args...)
+		}
+		if job.Keyword != "" {
+			db = db.Where(model.Keyword(e.searchMode, job.Keyword))
+		}
+		return db
+	}, start, end, nil
+}
+
+// upload 将导出文件上传到存储，按租户分目录
+func (e *ExportTask) upload(job *model.ExportJob, file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return err
+	}
+	tenant := job.TenantID
+	if tenant == "" {
+		tenant = "_all"
+	}
+	job.FileKey = fmt.Sprintf("%s/%s.%s", tenant, job.TaskID, tabular.Ext(job.Format))
+	job.FileSize = info.Size()
+	return e.store.Put(context.Background(), job.FileKey, file, info.Size())
+}
+
+// finish 保存任务终态并回调，任务已不归本实例所有时返回false
+// 无论成功与否，任务都在保留期后由清理任务删除
+func (e *ExportTask) finish(job *model.ExportJob) bool {
+	expires := time.Now().Add(time.Duration(e.conf.Retention) * time.Second)
+	job.ExpiresAt = &expires
+	if err := model.FinishExportJob(e.db, job); err != nil {
+		if !errors.Is(err, model.ErrExportLeaseLost) {
+			logx.Errorf("save export job %s failed: %v", job.TaskID, err)
+		}
+		return false
+	}
+	logx.Infof("export job %s %s, rows: %d, size: %d", job.TaskID, job.Status, job.Progress, job.FileSize)
+	if job.CallbackURL != "" {
+		e.callback(job)
+	}
+	return true
+}
+
+// exportCallback 回调内容，请求体以 X-Audit-Signature: sha256=<hex(hmac(CallbackSecret, body))> 签名
+type exportCallback struct {
+	TaskID      string `json:"task_id"`
+	Status      string `json:"status"`
+	Rows        int64  `json:"rows"`
+	Size        int64  `json:"size,omitempty"`
+	DownloadURL string `json:"download_url,omitempty"` // 带签名的下载链接，过期前无需登录即可下载
+	ExpiresAt   int64  `json:"expires_at,omitempty"`   // 下载链接过期时间戳（毫秒）
+	Error       string `json:"error,omitempty"`
+}
+
+func (e *ExportTask) callback(job *model.ExportJob) {
+	// 任务提交后配置可能已变更，发送前按当前配置重新校验
+	if e.conf.CallbackSecret == "" {
+		logx.Errorf("skip callback of export job %s, Export.CallbackSecret is not configured", job.TaskID)
+		return
+	}
+	if u, err := url.Parse(job.CallbackURL); err != nil || (len(e.conf.CallbackHosts) > 0 && !e.conf.CallbackHostAllowed(u.Hostname())) {
+		logx.Errorf("skip callback of export job %s, callback host is not allowed", job.TaskID)
+		return
+	}
+	payload := exportCallback{TaskID: job.TaskID, Status: job.Status, Rows: job.Progress, Size: job.FileSize, Error: job.Error}
+	if job.Status == model.ExportSucceeded {
+		expires := time.Now().Add(time.Duration(e.conf.LinkTTL) * time.Second)
+		if job.ExpiresAt != nil && job.ExpiresAt.Before(expires) {
+			expires = *job.ExpiresAt
+		}
+		link, err := DownloadURL(e.key, e.conf.PublicURL, job.TaskID, expires)
+		if err != nil {
+			logx.Errorf("sign download url of export job %s failed: %v", job.TaskID, err)
+			return
+		}
+		payload.DownloadURL, payload.ExpiresAt = link, expires.UnixMilli()
+	}
+	body, _ := json.Marshal(payload)
+	mac := hmac.New(sha256.New, []byte(e.conf.CallbackSecret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err := e.post(job.CallbackURL, body, signature)
+		if err == nil {
+			return
+		}
+		if attempt == callbackRetries {
+			logx.Errorf("callback of export job %s failed after %d attempts: %v", job.TaskID, attempt, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (e *ExportTask) post(target string, body []byte, signature string) error {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Audit-Signature", signature)
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// DownloadURL 生成导出文件的签名下载链接，base为空时返回相对路径
+func DownloadURL(key []byte, base, taskID string, expires time.Time) (string, error) {
+	token, err := model.DownloadToken(key, taskID, expires)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(base, "/") + "/v1/audit/export/" + url.PathEscape(taskID) + "/download?token=" + url.QueryEscape(token), nil
+}
+
+func (e *ExportTask) NextRunTime() time.Time {
+	return e.nextRunTime
+}
+
+func (e *ExportTask) SetNextRunTime(t time.Time) {
+	e.nextRunTime = t
+}
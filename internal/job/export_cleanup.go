@@ -0,0 +1,79 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/blobstore"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+const exportCleanBatch = 100
+
+// ExportCleanTask 删除超过保留期的导出文件及任务记录
+type ExportCleanTask struct {
+	nextRunTime time.Time
+	db          *gorm.DB
+	store       blobstore.Store
+}
+
+func NewExportCleanJob(db *gorm.DB, store blobstore.Store) *ExportCleanTask {
+	return &ExportCleanTask{
+		db:    db,
+		store: store,
+	}
+}
+
+func (c *ExportCleanTask) Name() string {
+	return "ExportCleanTask"
+}
+
+func (c *ExportCleanTask) Priority() int {
+	return 3
+}
+
+func (c *ExportCleanTask) ExeInterval() int64 {
+	return 600 // 每10分钟清理一次
+}
+
+// Run 先删除文件再删除任务记录，文件删除失败的任务保留到下次重试
+func (c *ExportCleanTask) Run() error {
+	now := time.Now()
+	for {
+		jobs, err := model.ExpiredExportJobs(c.db, now, exportCleanBatch)
+		if err != nil {
+			return fmt.Errorf("failed to get expired export jobs: %w", err)
+		}
+		ids := make([]int64, 0, len(jobs))
+		for _, job := range jobs {
+			if job.FileKey != "" {
+				if err := c.store.Delete(context.Background(), job.FileKey); err != nil {
+					logx.Errorf("delete export file %s failed: %v", job.FileKey, err)
+					continue
+				}
+			}
+			ids = append(ids, job.Id)
+		}
+		if len(ids) > 0 {
+			if err := c.db.Delete(&model.ExportJob{}, ids).Error; err != nil {
+				return fmt.Errorf("failed to delete expired export jobs: %w", err)
+			}
+			logx.Infof("cleaned %d expired export jobs", len(ids))
+		}
+		// 本批全部删除失败时停止，避免重复查询同一批任务
+		if len(jobs) < exportCleanBatch || len(ids) == 0 {
+			return nil
+		}
+	}
+}
+
+func (c *ExportCleanTask) NextRunTime() time.Time {
+	return c.nextRunTime
+}
+
+func (c *ExportCleanTask) SetNextRunTime(t time.Time) {
+	c.nextRunTime = t
+}
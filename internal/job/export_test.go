@@ -0,0 +1,383 @@
+package job
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/internal/config"
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/blobstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const (
+	testCallbackSecret = "callback-secret"
+	testPublicURL      = "https://audit.example.com"
+)
+
+var testURLKey = []byte("url-secret")
+
+func exportConf() config.ExportConf {
+	return config.ExportConf{
+		PublicURL:       testPublicURL,
+		LinkTTL:         3600,
+		Retention:       86400,
+		MaxRows:         100,
+		BatchSize:       2,
+		MaxConcurrent:   1,
+		MaxAttempts:     2,
+		LeaseDuration:   60,
+		CallbackTimeout: 5,
+		CallbackSecret:  testCallbackSecret,
+		CallbackHosts:   []string{"127.0.0.1"},
+	}
+}
+
+// newExportTask 使用本地目录存储，不依赖本机IP生成持有者标识
+func newExportTask(t *testing.T, db *gorm.DB, store blobstore.Store, conf config.ExportConf, holder string) *ExportTask {
+	t.Helper()
+	if store == nil {
+		var err error
+		store, err = blobstore.NewLocal(t.TempDir())
+		require.NoError(t, err)
+	}
+	return &ExportTask{
+		db:         db,
+		store:      store,
+		conf:       conf,
+		searchMode: model.SearchLike,
+		key:        testURLKey,
+		holder:     holder,
+		client:     newCallbackClient(conf),
+	}
+}
+
+func submitJob(t *testing.T, db *gorm.DB, callbackURL string, attempts int) *model.ExportJob {
+	t.Helper()
+	job := &model.ExportJob{
+		TaskID:      "task-1",
+		TenantID:    "t1",
+		Format:      "csv",
+		CallbackURL: callbackURL,
+		Status:      model.ExportPending,
+		Attempts:    attempts,
+	}
+	require.NoError(t, db.Create(job).Error)
+	return job
+}
+
+func claim(t *testing.T, db *gorm.DB, holder string, lease time.Duration) *model.ExportJob {
+	t.Helper()
+	jobs, err := model.ClaimExportJobs(db, holder, lease, 1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	return jobs[0]
+}
+
+func findJob(t *testing.T, db *gorm.DB, id int64) *model.ExportJob {
+	t.Helper()
+	job := &model.ExportJob{}
+	require.NoError(t, db.First(job, id).Error)
+	return job
+}
+
+// callbackServer 按CallbackSecret校验回调签名，记录签名正确的回调
+type callbackServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	received []exportCallback
+}
+
+func newCallbackServer(t *testing.T, secret string) *callbackServer {
+	s := &callbackServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(r.Header.Get("X-Audit-Signature")), []byte(want)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload exportCallback
+		if !assert.NoError(t, json.Unmarshal(body, &payload)) {
+			return
+		}
+		s.mu.Lock()
+		s.received = append(s.received, payload)
+		s.mu.Unlock()
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *callbackServer) callbacks() []exportCallback {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]exportCallback(nil), s.received...)
+}
+
+func TestClaimExportJobs_Lease(t *testing.T) {
+	tests := []struct {
+		name      string
+		lease     time.Duration // 第一个实例领取时的租约
+		cancel    bool
+		reclaimed bool
+	}{
+		{name: "live lease is kept", lease: time.Minute},
+		{name: "expired lease is reclaimed", lease: -time.Second, reclaimed: true},
+		{name: "canceled job is not reclaimed", lease: -time.Second, cancel: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := sqliteDB(t)
+			submitJob(t, db, "", 0)
+			first := claim(t, db, "holder-a", tt.lease)
+			assert.Equal(t, 1, first.Attempts)
+			if tt.cancel {
+				ok, err := model.CancelExportJob(db, first.Id, time.Hour)
+				require.NoError(t, err)
+				require.True(t, ok)
+			}
+
+			jobs, err := model.ClaimExportJobs(db, "holder-b", time.Minute, 1)
+			require.NoError(t, err)
+			if !tt.reclaimed {
+				assert.Empty(t, jobs)
+				return
+			}
+			require.Len(t, jobs, 1)
+			assert.Equal(t, 2, jobs[0].Attempts)
+			assert.Equal(t, "holder-b", findJob(t, db, first.Id).LeaseHolder)
+
+			// 原持有者不能再续约或结束任务，新持有者不受影响
+			assert.ErrorIs(t, model.RenewExportLease(db, first, 1, 1, time.Minute), model.ErrExportLeaseLost)
+			first.Status = model.ExportSucceeded
+			assert.ErrorIs(t, model.FinishExportJob(db, first), model.ErrExportLeaseLost)
+			assert.NoError(t, model.RenewExportLease(db, jobs[0], 1, 1, time.Minute))
+			assert.Equal(t, model.ExportRunning, findJob(t, db, first.Id).Status)
+		})
+	}
+}
+
+func TestExportTask_Execute(t *testing.T) {
+	tests := []struct {
+		name     string
+		records  int
+		attempts int // 提交时已领取的次数
+		maxRows  int64
+		status   string
+		err      string
+	}{
+		{name: "succeeded", records: 5, status: model.ExportSucceeded},
+		{name: "no rows", status: model.ExportSucceeded},
+		{name: "too many attempts", records: 5, attempts: 2, status: model.ExportFailed, err: "interrupted 2 times"},
+		{name: "too many rows", records: 5, maxRows: 4, status: model.ExportFailed, err: "exceeding the limit of 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := sqliteDB(t)
+			now := time.Now()
+			for i := 0; i < tt.records; i++ {
+				insertLog(t, db, fmt.Sprintf("log-%d", i), "t1", now.Add(time.Duration(i)*time.Second))
+			}
+			server := newCallbackServer(t, testCallbackSecret)
+			conf := exportConf()
+			if tt.maxRows > 0 {
+				conf.MaxRows = tt.maxRows
+			}
+			store, err := blobstore.NewLocal(t.TempDir())
+			require.NoError(t, err)
+			task := newExportTask(t, db, store, conf, "holder-a")
+
+			submitJob(t, db, server.URL+"/hook", tt.attempts)
+			task.execute(claim(t, db, task.holder, task.lease()))
+
+			job := findJob(t, db, 1)
+			assert.Equal(t, tt.status, job.Status)
+			assert.Contains(t, job.Error, tt.err)
+			assert.Empty(t, job.LeaseHolder)
+			require.NotNil(t, job.ExpiresAt)
+
+			callbacks := server.callbacks()
+			require.Len(t, callbacks, 1, "callback must be signed with CallbackSecret")
+			cb := callbacks[0]
+			assert.Equal(t, job.TaskID, cb.TaskID)
+			assert.Equal(t, tt.status, cb.Status)
+			if tt.status != model.ExportSucceeded {
+				assert.Empty(t, cb.DownloadURL)
+				return
+			}
+
+			assert.Equal(t, int64(tt.records), cb.Rows)
+			assert.Equal(t, job.FileSize, cb.Size)
+			link, err := url.Parse(cb.DownloadURL)
+			require.NoError(t, err)
+			assert.Equal(t, testPublicURL+"/v1/audit/export/task-1/download", link.Scheme+"://"+link.Host+link.Path)
+			taskID, err := model.ParseDownloadToken(testURLKey, link.Query().Get("token"))
+			require.NoError(t, err)
+			assert.Equal(t, job.TaskID, taskID)
+
+			r, err := store.Open(context.Background(), job.FileKey, 0)
+			require.NoError(t, err)
+			defer r.Close()
+			content, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, tt.records+1, strings.Count(string(content), "\n"), "header and one line per record")
+		})
+	}
+}
+
+func TestExportTask_CallbackSignature(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string // 接收方校验使用的密钥
+		hosts  []string
+		want   bool
+	}{
+		{name: "signed with callback secret", secret: testCallbackSecret, hosts: []string{"127.0.0.1"}, want: true},
+		{name: "receiver with another secret rejects", secret: "other", hosts: []string{"127.0.0.1"}},
+		{name: "private host without allowlist is not dialed", secret: testCallbackSecret},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newCallbackServer(t, tt.secret)
+			conf := exportConf()
+			conf.CallbackHosts = tt.hosts
+			task := newExportTask(t, nil, nil, conf, "holder-a")
+
+			task.callback(&model.ExportJob{TaskID: "task-1", Status: model.ExportFailed, Error: "boom", CallbackURL: server.URL})
+			if !tt.want {
+				assert.Empty(t, server.callbacks())
+				return
+			}
+			require.Len(t, server.callbacks(), 1)
+			assert.Equal(t, exportCallback{TaskID: "task-1", Status: model.ExportFailed, Error: "boom"}, server.callbacks()[0])
+		})
+	}
+}
+
+func TestExportTask_LeaseTakenOver(t *testing.T) {
+	db := sqliteDB(t)
+	insertLog(t, db, "log-1", "t1", time.Now())
+	server := newCallbackServer(t, testCallbackSecret)
+	store, err := blobstore.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	stale := newExportTask(t, db, store, exportConf(), "holder-a")
+
+	submitJob(t, db, server.URL, 0)
+	job := claim(t, db, stale.holder, -time.Second)
+	// 租约过期后被其他实例接管，原实例执行完成后不能覆盖任务状态，也不回调
+	claim(t, db, "holder-b", time.Minute)
+	stale.execute(job)
+
+	current := findJob(t, db, job.Id)
+	assert.Equal(t, model.ExportRunning, current.Status)
+	assert.Equal(t, "holder-b", current.LeaseHolder)
+	assert.Empty(t, server.callbacks())
+	_, err = store.Stat(context.Background(), job.FileKey)
+	assert.ErrorIs(t, err, blobstore.ErrNotFound)
+}
+
+// blockingStore 上传开始时通知测试，等待放行后再写入
+type blockingStore struct {
+	blobstore.Store
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	close(s.started)
+	<-s.release
+	return s.Store.Put(ctx, key, r, size)
+}
+
+func TestExportTask_CanceledWhileRunning(t *testing.T) {
+	tests := []struct {
+		name   string
+		upload bool // 在上传阶段取消，否则在写出第二批时取消
+	}{
+		{name: "during write"},
+		{name: "during upload", upload: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := sqliteDB(t)
+			now := time.Now()
+			for i := 0; i < 5; i++ {
+				insertLog(t, db, fmt.Sprintf("log-%d", i), "t1", now.Add(time.Duration(i)*time.Second))
+			}
+			server := newCallbackServer(t, testCallbackSecret)
+			local, err := blobstore.NewLocal(t.TempDir())
+			require.NoError(t, err)
+			conf := exportConf()
+			conf.LeaseDuration = 1
+
+			started := make(chan struct{})
+			var store blobstore.Store = local
+			release := make(chan struct{})
+			if tt.upload {
+				store = &blockingStore{Store: local, started: started, release: release}
+			} else {
+				// 统计总数、第一批之后，第二批查询阻塞到续约发现任务已取消、写出被中止为止
+				var queries atomic.Int32
+				require.NoError(t, db.Callback().Query().Before("gorm:query").Register("test:block", func(tx *gorm.DB) {
+					if tx.Statement.Table != "audit_log_1" || queries.Add(1) != 3 {
+						return
+					}
+					close(started)
+					select {
+					case <-tx.Statement.Context.Done():
+					case <-time.After(5 * time.Second):
+						t.Error("write was not aborted after the job was canceled")
+					}
+				}))
+			}
+			task := newExportTask(t, db, store, conf, "holder-a")
+
+			submitJob(t, db, server.URL, 0)
+			job := claim(t, db, task.holder, task.lease())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				task.execute(job)
+			}()
+
+			<-started
+			ok, err := model.CancelExportJob(db, job.Id, time.Hour)
+			require.NoError(t, err)
+			require.True(t, ok)
+			close(release)
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("export job did not stop after it was canceled")
+			}
+
+			current := findJob(t, db, job.Id)
+			assert.Equal(t, model.ExportCanceled, current.Status)
+			assert.Empty(t, current.FileKey)
+			assert.Empty(t, server.callbacks())
+			_, err = local.Stat(context.Background(), "t1/task-1.csv")
+			assert.ErrorIs(t, err, blobstore.ErrNotFound)
+		})
+	}
+}
@@ -1,25 +1,31 @@
 package job
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"codexie.com/auditlog/internal/constant"
 	"codexie.com/auditlog/internal/model"
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
 	"gorm.io/gorm"
 )
 
-const (
-	TableRecordThreshold = 30000000 // 单表最大记录数阈值
-)
-
+// SchedulePosition 按实体的分表策略轮转分表：按行数分表时检查当前表行数，按时间分表时推进到当前时间所在分表并预建后续分表
+// 同时刷新分表目录中的统计信息，并封存不再作为写入位置超过宽限期的分表
 type SchedulePosition struct {
 	nextRunTime time.Time
 	db          *gorm.DB
+	redis       *redis.Client
+	strategies  map[string]model.ShardStrategy
 }
 
-func NewScheduleJob(db *gorm.DB) *SchedulePosition {
+func NewScheduleJob(db *gorm.DB, redis *redis.Client, strategies map[string]model.ShardStrategy) *SchedulePosition {
 	return &SchedulePosition{
-		db: db,
+		db:         db,
+		redis:      redis,
+		strategies: strategies,
 	}
 }
 
@@ -42,31 +48,37 @@ func (s *SchedulePosition) Run() error {
 		return fmt.Errorf("failed to get schedule_pos list: %w", err)
 	}
 	for _, pos := range posList {
-		tableName := fmt.Sprintf("%s_%d", pos.Name, pos.ScheduleEndPos)
-		var count int64
-		err := s.db.Table(tableName).Count(&count).Error
+		strategy, err := s.strategy(pos)
 		if err != nil {
-			return fmt.Errorf("failed to count records in %s: %w", tableName, err)
+			return err
+		}
+		changed, err := model.RotateShard(s.db, pos, strategy, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := model.RefreshShardCatalog(s.db, pos); err != nil {
+			return fmt.Errorf("failed to refresh shard catalog of %s: %w", pos.Name, err)
 		}
-		if count >= TableRecordThreshold {
-			pos.ScheduleEndPos++
-			newTableName := fmt.Sprintf("%s_%d", pos.Name, pos.ScheduleEndPos)
-			// 自动迁移新表结构
-			entity := model.GetModel(pos.Name)
-			err = s.db.Table(newTableName).AutoMigrate(entity)
-			if err != nil {
-				return fmt.Errorf("failed to migrate new table %s: %w", newTableName, err)
-			}
-			// 更新schedule_pos记录
-			err = posModel.Save(s.db, pos)
-			if err != nil {
-				return fmt.Errorf("failed to update schedule_pos: %w", err)
-			}
+		if !changed {
+			continue
+		}
+		logx.Infof("schedule_pos of %s rotated to %d", pos.Name, pos.ScheduleEndPos)
+		// 通知各实例刷新缓存的分表位置，通知丢失时由实例定时对齐
+		if err := s.redis.Publish(context.Background(), constant.SchedulePosChannel, pos.Name).Err(); err != nil {
+			logx.Errorf("failed to publish schedule_pos change of %s: %v", pos.Name, err)
 		}
 	}
 	return nil
 }
 
+// strategy 优先使用配置的策略，配置与记录不一致时以记录为准，避免分表序号混乱
+func (s *SchedulePosition) strategy(pos *model.SchedulePos) (model.ShardStrategy, error) {
+	if strategy, ok := s.strategies[pos.Name]; ok && strategy.Name() == pos.Strategy {
+		return strategy, nil
+	}
+	return pos.ShardStrategy()
+}
+
 func (s *SchedulePosition) NextRunTime() time.Time {
 	return s.nextRunTime
 }
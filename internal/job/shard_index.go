@@ -0,0 +1,65 @@
+package job
+
+import (
+	"fmt"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+// ShardIndexTask 为索引尚未建好的分表补建实体声明的索引(含全文索引)
+// 由调度器加锁执行，同一时刻只有一个实例在建索引，不阻塞服务启动
+type ShardIndexTask struct {
+	nextRunTime time.Time
+	db          *gorm.DB
+}
+
+func NewShardIndexJob(db *gorm.DB) *ShardIndexTask {
+	return &ShardIndexTask{db: db}
+}
+
+func (s *ShardIndexTask) Name() string {
+	return "ShardIndexTask"
+}
+
+func (s *ShardIndexTask) Priority() int {
+	return 3
+}
+
+func (s *ShardIndexTask) ExeInterval() int64 {
+	return 60
+}
+
+// Run 逐张分表建索引，单张分表失败时记录日志后继续，下次调度重试
+func (s *ShardIndexTask) Run() error {
+	shards, err := model.PendingIndexShards(s.db)
+	if err != nil {
+		return fmt.Errorf("failed to get pending index shards: %w", err)
+	}
+	for _, shard := range shards {
+		entity := model.GetModel(shard.Entity)
+		if entity == nil {
+			continue
+		}
+		start := time.Now()
+		if err := s.db.Table(shard.Table).AutoMigrate(entity); err != nil {
+			logx.Errorf("build index on shard %s failed: %v", shard.Table, err)
+			continue
+		}
+		if err := model.MarkShardIndexed(s.db, shard.Entity, shard.Shard); err != nil {
+			return fmt.Errorf("failed to mark shard %s indexed: %w", shard.Table, err)
+		}
+		logx.Infof("built index on shard %s in %s", shard.Table, time.Since(start))
+	}
+	return nil
+}
+
+func (s *ShardIndexTask) NextRunTime() time.Time {
+	return s.nextRunTime
+}
+
+func (s *ShardIndexTask) SetNextRunTime(t time.Time) {
+	s.nextRunTime = t
+}
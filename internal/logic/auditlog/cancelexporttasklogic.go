@@ -0,0 +1,57 @@
+package auditlog
+
+import (
+	"context"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"codexie.com/auditlog/pkg/apierr"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type CancelExportTaskLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewCancelExportTaskLogic(ctx context.Context, svcCtx *svc.ServiceContext) *CancelExportTaskLogic {
+	return &CancelExportTaskLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// CancelExportTask 取消等待或执行中的任务，执行中的任务最迟在一次续约间隔(租期的1/3)后停止
+func (l *CancelExportTaskLogic) CancelExportTask(req *types.ExportTaskRequest) (resp *types.ExportTaskResponse, err error) {
+	exportJob, err := loadExportJob(l.ctx, l.svcCtx.DB, req.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	retention := time.Duration(l.svcCtx.Config.Export.Retention) * time.Second
+	canceled, err := model.CancelExportJob(l.svcCtx.DB.WithContext(l.ctx), exportJob.Id, retention)
+	if err != nil {
+		l.Logger.Errorf("cancel export job %s failed: %v", req.TaskID, err)
+		return nil, err
+	}
+	if !canceled {
+		return nil, apierr.ErrExportFinished
+	}
+	l.Logger.Infof("export job %s canceled", req.TaskID)
+
+	if exportJob, err = loadExportJob(l.ctx, l.svcCtx.DB, req.TaskID); err != nil {
+		return nil, err
+	}
+	task, err := toExportTask(exportJob, l.svcCtx)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ExportTaskResponse{
+		BaseResponse: types.BaseResponse{Code: "200", Message: "success"},
+		Task:         task,
+	}, nil
+}
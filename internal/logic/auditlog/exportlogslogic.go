@@ -2,9 +2,17 @@ package auditlog
 
 import (
 	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
 
+	"codexie.com/auditlog/internal/constant"
+	"codexie.com/auditlog/internal/model"
 	"codexie.com/auditlog/internal/svc"
 	"codexie.com/auditlog/internal/types"
+	"codexie.com/auditlog/pkg/apierr"
+	"codexie.com/auditlog/pkg/idgen"
+	"codexie.com/auditlog/pkg/querydsl"
 
 	"github.com/zeromicro/go-zero/core/logx"
 )
@@ -23,8 +31,107 @@ func NewExportLogsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *Export
 	}
 }
 
+// ExportLogs 保存导出任务后立即返回任务ID，任务由调度器领取执行
 func (l *ExportLogsLogic) ExportLogs(req *types.ExportRequest) (resp *types.ExportResponse, err error) {
-	// todo: add your logic here and delete this line
+	q := &req.Query
+	// 非管理员只能导出自己租户的日志
+	if q.TenantID, err = scopeTenant(l.ctx, q.TenantID); err != nil {
+		return nil, err
+	}
+
+	if err := l.checkCallback(req.CallbackURL); err != nil {
+		return nil, err
+	}
 
-	return
+	filter, err := exportFilter(q)
+	if err != nil {
+		return nil, apierr.WithErrf(l.Logger, "E00001", "%v", err)
+	}
+	taskID, err := idgen.NewULID().Next()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &model.ExportJob{
+		TaskID:      taskID,
+		TenantID:    q.TenantID,
+		Filter:      filter,
+		Keyword:     q.Keyword,
+		Fields:      strings.Join(req.Fields, ","),
+		Format:      req.Format,
+		CallbackURL: req.CallbackURL,
+		Status:      model.ExportPending,
+	}
+	if user, ok := l.ctx.Value(constant.USER).(*types.User); ok {
+		job.UserID = user.ID
+	}
+	if err := l.svcCtx.DB.WithContext(l.ctx).Create(job).Error; err != nil {
+		l.Logger.Errorf("create export job failed: %v", err)
+		return nil, err
+	}
+
+	return &types.ExportResponse{
+		BaseResponse: types.BaseResponse{Code: "200", Message: "success"},
+		TaskID:       taskID,
+	}, nil
+}
+
+// exportFilter 将导出条件合并为一个查询DSL并校验，任务执行时按它重建查询
+func exportFilter(q *types.ExportQuery) (string, error) {
+	var conds []*querydsl.Node
+	for _, eq := range [][2]string{
+		{"tenant_id", q.TenantID},
+		{"user_id", q.UserID},
+		{"username", q.Username},
+		{"action", q.Action},
+		{"resource_type", q.ResourceType},
+		{"resource_id", q.ResourceID},
+		{"result", q.Result},
+	} {
+		if eq[1] != "" {
+			conds = append(conds, &querydsl.Node{Field: eq[0], Op: querydsl.OpEq, Value: eq[1]})
+		}
+	}
+	if q.StartTime != 0 {
+		conds = append(conds, &querydsl.Node{Field: "created_at", Op: querydsl.OpGte, Value: q.StartTime})
+	}
+	if q.EndTime != 0 {
+		conds = append(conds, &querydsl.Node{Field: "created_at", Op: querydsl.OpLte, Value: q.EndTime})
+	}
+	if len(q.Filter) > 0 {
+		node, err := querydsl.FromMap(q.Filter)
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, node)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+
+	root := &querydsl.Node{And: conds}
+	if _, err := querydsl.Compile(querySchema, root); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(root)
+	return string(data), err
+}
+
+// checkCallback 未配置回调签名密钥时不接受回调地址；配置了主机白名单时只能回调白名单中的主机
+func (l *ExportLogsLogic) checkCallback(callbackURL string) error {
+	if callbackURL == "" {
+		return nil
+	}
+	conf := l.svcCtx.Config.Export
+	if conf.CallbackSecret == "" {
+		return apierr.WithErrf(l.Logger, "E00001", "callback is disabled, Export.CallbackSecret is not configured")
+	}
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return apierr.WithErrf(l.Logger, "E00001", "invalid callback_url: %v", err)
+	}
+	if len(conf.CallbackHosts) > 0 && !conf.CallbackHostAllowed(u.Hostname()) {
+		return apierr.WithErrf(l.Logger, "E00001", "callback host %s is not allowed", u.Hostname())
+	}
+	return nil
 }
@@ -0,0 +1,48 @@
+package auditlog
+
+import (
+	"context"
+	"testing"
+
+	"codexie.com/auditlog/internal/config"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportCallback(t *testing.T) {
+	secret := config.ExportConf{CallbackSecret: "s"}
+	allowlist := config.ExportConf{CallbackSecret: "s", CallbackHosts: []string{"localhost", "hooks.internal"}}
+
+	tests := []struct {
+		name string
+		conf config.ExportConf
+		url  string
+		ok   bool
+	}{
+		{name: "no callback", conf: config.ExportConf{}, ok: true},
+		{name: "secret not configured", conf: config.ExportConf{}, url: "https://example.com/hook"},
+		{name: "not http", conf: secret, url: "ftp://example.com/hook"},
+		{name: "public host", conf: secret, url: "https://example.com/hook", ok: true},
+		// 白名单外的内网地址由回调客户端在连接时拒绝
+		{name: "private ip without allowlist", conf: secret, url: "http://10.0.0.1/hook", ok: true},
+		{name: "allowlisted localhost", conf: allowlist, url: "http://localhost:8080/hook", ok: true},
+		{name: "allowlisted internal host", conf: allowlist, url: "http://HOOKS.internal/hook", ok: true},
+		{name: "host outside allowlist", conf: allowlist, url: "https://example.com/hook"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &types.ExportRequest{CallbackURL: tt.url}
+			err := req.Validate(context.Background())
+			if err == nil {
+				l := NewExportLogsLogic(context.Background(), &svc.ServiceContext{Config: config.Config{Export: tt.conf}})
+				err = l.checkCallback(tt.url)
+			}
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"codexie.com/auditlog/internal/job"
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"codexie.com/auditlog/pkg/apierr"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+type GetExportTaskLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetExportTaskLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetExportTaskLogic {
+	return &GetExportTaskLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *GetExportTaskLogic) GetExportTask(req *types.ExportTaskRequest) (resp *types.ExportTaskResponse, err error) {
+	exportJob, err := loadExportJob(l.ctx, l.svcCtx.DB, req.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	task, err := toExportTask(exportJob, l.svcCtx)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ExportTaskResponse{
+		BaseResponse: types.BaseResponse{Code: "200", Message: "success"},
+		Task:         task,
+	}, nil
+}
+
+// loadExportJob 查询导出任务，非管理员只能访问自己租户的任务，其他租户的任务视为不存在
+func loadExportJob(ctx context.Context, db *gorm.DB, taskID string) (*model.ExportJob, error) {
+	if _, err := currentUser(ctx); err != nil {
+		return nil, err
+	}
+	var exportJob model.ExportJob
+	err := db.WithContext(ctx).Where("task_id = ?", taskID).Take(&exportJob).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apierr.ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := canAccessTenant(ctx, exportJob.TenantID); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, apierr.ErrExportNotFound
+	}
+	return &exportJob, nil
+}
+
+// toExportTask 转换为接口返回的任务详情，文件可下载时附带签名下载链接
+func toExportTask(exportJob *model.ExportJob, svcCtx *svc.ServiceContext) (types.ExportTask, error) {
+	task := types.ExportTask{
+		TaskID:     exportJob.TaskID,
+		TenantID:   exportJob.TenantID,
+		UserID:     exportJob.UserID,
+		Format:     exportJob.Format,
+		Status:     exportJob.Status,
+		Progress:   exportJob.Progress,
+		Total:      exportJob.Total,
+		FileSize:   exportJob.FileSize,
+		Error:      exportJob.Error,
+		CreatedAt:  exportJob.CreatedAt.UnixMilli(),
+		StartedAt:  unixMilliPtr(exportJob.StartedAt),
+		FinishedAt: unixMilliPtr(exportJob.FinishedAt),
+		ExpiresAt:  unixMilliPtr(exportJob.ExpiresAt),
+	}
+	if exportJob.Status != model.ExportSucceeded || exportJob.FileKey == "" {
+		return task, nil
+	}
+
+	conf := svcCtx.Config.Export
+	expires := time.Now().Add(time.Duration(conf.LinkTTL) * time.Second)
+	if exportJob.ExpiresAt != nil && exportJob.ExpiresAt.Before(expires) {
+		expires = *exportJob.ExpiresAt
+	}
+	link, err := job.DownloadURL(svcCtx.ExportKey, conf.PublicURL, exportJob.TaskID, expires)
+	if err != nil {
+		return task, err
+	}
+	task.DownloadURL, task.LinkExpires = link, expires.UnixMilli()
+	return task, nil
+}
+
+func unixMilliPtr(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixMilli()
+}
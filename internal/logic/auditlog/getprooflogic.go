@@ -0,0 +1,116 @@
+package auditlog
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"codexie.com/auditlog/pkg/apierr"
+	"codexie.com/auditlog/pkg/merkle"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type GetProofLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetProofLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetProofLogic {
+	return &GetProofLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// GetProof 返回日志在所属检查点中的包含证明
+// 证明由封存时保存的树节点生成，当前记录的哈希与封存的叶子不一致说明记录被修改
+func (l *GetProofLogic) GetProof(req *types.ProofRequest) (resp *types.ProofResponse, err error) {
+	if req.LogId == "" {
+		return nil, apierr.WithErrf(l.Logger, "E00001", "log_id is required")
+	}
+	// 先确认身份再查询，避免未认证的调用方通过返回码判断日志是否存在
+	if _, err := currentUser(l.ctx); err != nil {
+		return nil, err
+	}
+
+	db := l.svcCtx.DB.WithContext(l.ctx)
+	record := &model.AuditLog{LogId: req.LogId}
+	var logs []*model.AuditLog
+	if err := db.Table(record.TableName()).Where("log_id = ?", req.LogId).Limit(1).Find(&logs).Error; err != nil {
+		l.Logger.Errorf("query log %s failed: %v", req.LogId, err)
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, apierr.ErrLogNotFound
+	}
+	// 非管理员只能查询自己租户的日志，其他租户的日志视为不存在
+	if ok, err := canAccessTenant(l.ctx, logs[0].TenantID); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, apierr.ErrLogNotFound
+	}
+	record = logs[0]
+
+	leaf, err := model.FindCheckpointLeaf(db, record.LogId)
+	if err != nil {
+		l.Logger.Errorf("query checkpoint leaf of log %s failed: %v", req.LogId, err)
+		return nil, err
+	}
+	if leaf == nil {
+		return nil, apierr.ErrNotCheckpointed
+	}
+	var cps []*model.Checkpoint
+	if err := db.Where("id = ?", leaf.CheckpointID).Limit(1).Find(&cps).Error; err != nil {
+		l.Logger.Errorf("query checkpoint %d failed: %v", leaf.CheckpointID, err)
+		return nil, err
+	}
+	if len(cps) == 0 {
+		l.Logger.Errorf("checkpoint %d of log %s is missing", leaf.CheckpointID, req.LogId)
+		return nil, apierr.ErrCheckpointMismatch
+	}
+	cp := cps[0]
+
+	// 封存时的叶子哈希必须与当前记录一致，且能通过路径得到已签名的根
+	leafHash := merkle.LeafHash(record.Canonical())
+	if cp.TenantID != record.TenantID || hex.EncodeToString(leafHash) != leaf.Hash {
+		l.Logger.Errorf("log %s does not match leaf %d of checkpoint %d", req.LogId, leaf.Idx, cp.Id)
+		return nil, apierr.ErrCheckpointMismatch
+	}
+	path, err := model.CheckpointProof(db, cp, leaf.Idx)
+	if err != nil {
+		l.Logger.Errorf("read proof of checkpoint %d failed: %v", cp.Id, err)
+		return nil, apierr.ErrCheckpointMismatch
+	}
+	root, err := hex.DecodeString(cp.Root)
+	if err != nil || !merkle.Verify(leafHash, path, root) {
+		l.Logger.Errorf("proof of log %s does not reach root of checkpoint %d", req.LogId, cp.Id)
+		return nil, apierr.ErrCheckpointMismatch
+	}
+
+	resp = &types.ProofResponse{
+		LogId:       record.LogId,
+		TenantID:    cp.TenantID,
+		LeafHash:    leaf.Hash,
+		LeafIndex:   int(leaf.Idx),
+		Proof:       make([]types.ProofNode, 0, len(path)),
+		Root:        cp.Root,
+		LeafCount:   cp.LeafCount,
+		WindowStart: cp.WindowStart.UnixMilli(),
+		WindowEnd:   cp.WindowEnd.UnixMilli(),
+		Signature:   cp.Signature,
+		KeyID:       cp.KeyID,
+	}
+	for _, node := range path {
+		resp.Proof = append(resp.Proof, types.ProofNode{Hash: hex.EncodeToString(node.Hash), Left: node.Left})
+	}
+	if l.svcCtx.CheckpointKey != nil {
+		resp.PublicKey = base64.StdEncoding.EncodeToString(l.svcCtx.CheckpointKey.Public().(ed25519.PublicKey))
+	}
+	return resp, nil
+}
@@ -0,0 +1,182 @@
+package auditlog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"codexie.com/auditlog/internal/constant"
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"codexie.com/auditlog/pkg/apierr"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	statsCachePrefix = "audit:stats:"
+	defaultStatsSpan = 24 * time.Hour // 未指定起始时间时的统计跨度
+)
+
+type GetStatsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewGetStatsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetStatsLogic {
+	return &GetStatsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *GetStatsLogic) GetStats(req *types.StatsRequest) (resp *types.StatsResponse, err error) {
+	// 非管理员只能统计自己租户的日志
+	if req.TenantID, err = scopeTenant(l.ctx, req.TenantID); err != nil {
+		return nil, err
+	}
+
+	query, err := compileFilter(req.Filter)
+	if err != nil {
+		return nil, apierr.WithErrf(l.Logger, "E00001", "%v", err)
+	}
+
+	ttl := time.Duration(l.svcCtx.Config.Query.StatsCacheTTL) * time.Second
+	l.defaultRange(req, ttl)
+	interval := constant.StatsIntervals[req.Interval]
+	if interval > 0 {
+		buckets := (req.EndTime - req.StartTime) / interval.Milliseconds()
+		if buckets > constant.MAX_STATS_BUCKETS {
+			return nil, apierr.WithErrf(l.Logger, "E00001", "time range exceeds %d buckets of %s", constant.MAX_STATS_BUCKETS, req.Interval)
+		}
+		// 取前N组时每张分表最多返回N×桶数行
+		if req.Top > 0 && buckets*int64(req.Top) > model.MaxStatsGroups {
+			return nil, apierr.WithErrf(l.Logger, "E00001", "top %d over %d buckets exceeds %d groups, use a larger interval or a smaller top", req.Top, buckets, model.MaxStatsGroups)
+		}
+	}
+
+	// 短时间内相同的统计请求直接返回缓存结果
+	key := statsCacheKey(req)
+	if ttl > 0 {
+		if resp = l.cached(key); resp != nil {
+			return resp, nil
+		}
+	}
+
+	start, end := time.UnixMilli(req.StartTime), time.UnixMilli(req.EndTime)
+	queryMap := map[string]any{
+		"created_at >= ?": start,
+		"created_at < ?":  end,
+	}
+	if req.TenantID != "" {
+		queryMap["tenant_id"] = req.TenantID
+	}
+	if cond, args := query.SQL(); cond != "" {
+		queryMap[cond] = args
+	}
+
+	// 顶层AND中的创建时间条件可进一步缩小需要访问的分表
+	s, e := query.TimeRange("created_at")
+	if s.After(start) {
+		start = s
+	}
+	if !e.IsZero() && e.Before(end) {
+		end = e
+	}
+	tables, err := model.ShardTablesInRange(l.svcCtx.DB, model.AuditLogName, start, end)
+	if err != nil {
+		l.Logger.Errorf("read shard catalog failed: %v", err)
+		return nil, err
+	}
+
+	rows, total, err := model.QueryStats(l.ctx, l.svcCtx.DB, model.StatsQuery{
+		Tables:   tables,
+		Scope:    scopeOf(queryMap),
+		GroupBy:  req.GroupBy,
+		Interval: interval,
+		Top:      req.Top,
+	})
+	if errors.Is(err, model.ErrTooManyGroups) {
+		return nil, apierr.WithErrf(l.Logger, "E00001", "%v", err)
+	}
+	if err != nil {
+		l.Logger.Errorf("query audit log stats failed: %v", err)
+		return nil, err
+	}
+
+	resp = &types.StatsResponse{
+		Total:     total,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Buckets:   make([]types.StatsBucket, 0, len(rows)),
+	}
+	for _, row := range rows {
+		bucket := types.StatsBucket{Time: row.Bucket, Count: row.Count}
+		if len(req.GroupBy) > 0 {
+			bucket.Keys = make(map[string]string, len(req.GroupBy))
+			for i, dim := range req.GroupBy {
+				bucket.Keys[dim] = row.Keys[i]
+			}
+		}
+		resp.Buckets = append(resp.Buckets, bucket)
+	}
+
+	if ttl > 0 {
+		l.cache(key, resp, ttl)
+	}
+	return resp, nil
+}
+
+// defaultRange 补全统计时间范围，未指定结束时间时按缓存时间对齐，使缓存期内的重复请求命中同一个键
+func (l *GetStatsLogic) defaultRange(req *types.StatsRequest, ttl time.Duration) {
+	if req.EndTime == 0 {
+		end := time.Now()
+		if ttl > 0 {
+			end = end.Truncate(ttl).Add(ttl)
+		}
+		req.EndTime = end.UnixMilli()
+	}
+	if req.StartTime == 0 {
+		req.StartTime = req.EndTime - defaultStatsSpan.Milliseconds()
+	}
+}
+
+func (l *GetStatsLogic) cached(key string) *types.StatsResponse {
+	data, err := l.svcCtx.Redis.Get(l.ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			l.Logger.Errorf("read stats cache failed: %v", err)
+		}
+		return nil
+	}
+	var resp types.StatsResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		l.Logger.Errorf("decode stats cache failed: %v", err)
+		return nil
+	}
+	return &resp
+}
+
+func (l *GetStatsLogic) cache(key string, resp *types.StatsResponse, ttl time.Duration) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	if err := l.svcCtx.Redis.Set(l.ctx, key, data, ttl).Err(); err != nil {
+		l.Logger.Errorf("write stats cache failed: %v", err)
+	}
+}
+
+// statsCacheKey 以补全后的请求计算缓存键，租户已按调用方身份固定
+func statsCacheKey(req *types.StatsRequest) string {
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return statsCachePrefix + hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,61 @@
+package auditlog
+
+import (
+	"context"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ListExportTasksLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewListExportTasksLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ListExportTasksLogic {
+	return &ListExportTasksLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// ListExportTasks 按提交时间倒序分页列出租户的导出任务
+func (l *ListExportTasksLogic) ListExportTasks(req *types.ExportListRequest) (resp *types.ExportListResponse, err error) {
+	// 非管理员只能查看自己租户的任务
+	if req.TenantID, err = scopeTenant(l.ctx, req.TenantID); err != nil {
+		return nil, err
+	}
+
+	db := l.svcCtx.DB.WithContext(l.ctx).Model(&model.ExportJob{})
+	if req.TenantID != "" {
+		db = db.Where("tenant_id = ?", req.TenantID)
+	}
+	if req.Status != "" {
+		db = db.Where("status = ?", req.Status)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		l.Logger.Errorf("count export jobs failed: %v", err)
+		return nil, err
+	}
+	var jobs []*model.ExportJob
+	if err := db.Order("created_at DESC, id DESC").
+		Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize).Find(&jobs).Error; err != nil {
+		l.Logger.Errorf("list export jobs failed: %v", err)
+		return nil, err
+	}
+
+	list := make([]types.ExportTask, 0, len(jobs))
+	for _, exportJob := range jobs {
+		task, err := toExportTask(exportJob, l.svcCtx)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, task)
+	}
+	return &types.ExportListResponse{Total: total, List: list}, nil
+}
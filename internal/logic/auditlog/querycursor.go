@@ -0,0 +1,90 @@
+package auditlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/apierr"
+	"codexie.com/auditlog/pkg/cursor"
+)
+
+// queryCursor 游标内容，签名后以不透明字符串返回给调用方
+type queryCursor struct {
+	Field  string `json:"f"` // 排序字段
+	Desc   bool   `json:"d"` // 是否降序
+	Value  string `json:"v"` // 上一页最后一条记录的排序值
+	LogId  string `json:"i"` // 上一页最后一条记录的log_id，排序值相同时的次序
+	Filter string `json:"h"` // 查询条件摘要，游标只能用于生成它的查询
+	Total  int64  `json:"t"` // 首页统计的总数，后续页沿用而不再统计
+	Done   []int  `json:"s"` // 已取完的分表序号，后续页不再查询
+}
+
+// encodeCursor 以上一页最后一条记录生成下一页游标，c中需已填充查询条件和翻页状态
+func (l *QueryLogsLogic) encodeCursor(last *model.AuditLog, c queryCursor) (string, error) {
+	c.Value = last.SortValue(c.Field)
+	c.LogId = last.LogId
+	return cursor.Encode(l.svcCtx.CursorKey, c)
+}
+
+// decodeCursor 校验游标签名及其与当前查询是否匹配，返回游标内容和起始位置
+func (l *QueryLogsLogic) decodeCursor(token, field string, desc bool, filter string) (*queryCursor, *model.Keyset, error) {
+	var c queryCursor
+	if err := cursor.Decode(l.svcCtx.CursorKey, token, &c); err != nil {
+		return nil, nil, apierr.WithErrf(l.Logger, "E00001", "invalid cursor")
+	}
+	if c.Field != field || c.Desc != desc || c.Filter != filter {
+		return nil, nil, apierr.WithErrf(l.Logger, "E00001", "cursor does not match the query")
+	}
+	value, err := model.ParseSortValue(c.Field, c.Value)
+	if err != nil {
+		return nil, nil, apierr.WithErrf(l.Logger, "E00001", "invalid cursor")
+	}
+	return &c, &model.Keyset{Value: value, LogId: c.LogId}, nil
+}
+
+// skipDone 去掉游标中已取完且仍处于关闭状态的分表
+// 游标生成后分表可能重新有写入(如磁盘恢复)，以当前目录为准，closed为ClosedShards的结果
+func (c *queryCursor) skipDone(tables []string, closed map[string]bool) []string {
+	if len(c.Done) == 0 {
+		return tables
+	}
+	res := make([]string, 0, len(tables))
+	for _, table := range tables {
+		if shard, ok := model.TableShard(table); !ok || !slices.Contains(c.Done, shard) || !closed[table] {
+			res = append(res, table)
+		}
+	}
+	return res
+}
+
+// markDone 记录本页之后已取完的分表
+func (c *queryCursor) markDone(tables []string) {
+	for _, table := range tables {
+		if shard, ok := model.TableShard(table); ok && !slices.Contains(c.Done, shard) {
+			c.Done = append(c.Done, shard)
+		}
+	}
+}
+
+// filterDigest 计算查询条件摘要，分页参数不参与计算
+func filterDigest(req map[string]any) string {
+	keys := make([]string, 0, len(req))
+	for k := range req {
+		if k != "page" && k != "page_size" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%v;", k, req[k])
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
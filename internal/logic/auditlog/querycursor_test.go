@@ -0,0 +1,70 @@
+package auditlog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/internal/svc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCursorLogic() *QueryLogsLogic {
+	return NewQueryLogsLogic(context.Background(), &svc.ServiceContext{CursorKey: []byte("0123456789abcdef0123456789abcdef")})
+}
+
+func TestQueryCursor_Keyset(t *testing.T) {
+	l := newCursorLogic()
+	created := time.UnixMicro(1735689600123456)
+	last := &model.AuditLog{LogId: "01JGZ_202501", Action: "LOGIN", CreatedAt: created}
+	filter := filterDigest(map[string]any{"tenant_id": "t1", "page": 1, "page_size": 10})
+
+	tests := []struct {
+		field string
+		value any
+	}{
+		{field: "created_at", value: created},
+		{field: "action", value: "LOGIN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			token, err := l.encodeCursor(last, queryCursor{Field: tt.field, Desc: true, Filter: filter, Total: 42, Done: []int{202412}})
+			require.NoError(t, err)
+
+			c, after, err := l.decodeCursor(token, tt.field, true, filter)
+			require.NoError(t, err)
+			assert.Equal(t, &model.Keyset{Value: tt.value, LogId: last.LogId}, after)
+			assert.Equal(t, int64(42), c.Total)
+			assert.Equal(t, []int{202412}, c.Done)
+
+			// 游标只能用于生成它的查询
+			_, _, err = l.decodeCursor(token, tt.field, false, filter)
+			assert.Error(t, err)
+			_, _, err = l.decodeCursor(token, tt.field, true, filterDigest(map[string]any{"tenant_id": "t2"}))
+			assert.Error(t, err)
+			_, _, err = l.decodeCursor(token+"x", tt.field, true, filter)
+			assert.Error(t, err)
+		})
+	}
+
+	// 页码和每页条数不影响查询条件摘要
+	assert.Equal(t, filter, filterDigest(map[string]any{"tenant_id": "t1", "page": 3, "page_size": 50}))
+}
+
+func TestQueryCursor_Done(t *testing.T) {
+	c := &queryCursor{}
+	tables := []string{"audit_log_202411", "audit_log_202412", "audit_log_202501"}
+	closed := map[string]bool{"audit_log_202411": true, "audit_log_202412": true}
+	assert.Equal(t, tables, c.skipDone(tables, closed))
+
+	c.markDone([]string{"audit_log_202411"})
+	c.markDone([]string{"audit_log_202411", "audit_log_202412"})
+	assert.Equal(t, []int{202411, 202412}, c.Done)
+	assert.Equal(t, []string{"audit_log_202501"}, c.skipDone(tables, closed))
+
+	// 游标生成后重新有写入的分表不再跳过
+	delete(closed, "audit_log_202412")
+	assert.Equal(t, []string{"audit_log_202412", "audit_log_202501"}, c.skipDone(tables, closed))
+}
@@ -2,15 +2,24 @@ package auditlog
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"time"
 
 	"codexie.com/auditlog/internal/model"
 	"codexie.com/auditlog/internal/svc"
 	"codexie.com/auditlog/internal/types"
-	"codexie.com/auditlog/pkg/util"
+	"codexie.com/auditlog/pkg/highlight"
 
 	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 总数统计方式
+const (
+	totalExact    = "exact"
+	totalEstimate = "estimate"
 )
 
 type QueryLogsLogic struct {
@@ -28,11 +37,9 @@ func NewQueryLogsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *QueryLo
 }
 
 func (l *QueryLogsLogic) QueryLogs(req *types.QueryRequest) (resp *types.QueryResponse, err error) {
-	// 从ctx中拿到user结构体
-	user := l.ctx.Value("user").(*types.User)
-	// 如果用户不是admin，则租户id只能为自己
-	if util.ArrayContains[string](user.Roles, "admin") {
-		req.TenantID = user.TenantID
+	// 非管理员只能查询自己租户的日志
+	if req.TenantID, err = scopeTenant(l.ctx, req.TenantID); err != nil {
+		return nil, err
 	}
 
 	// 将req中的字段封装成map(零值则忽略)
@@ -52,61 +59,239 @@ func (l *QueryLogsLogic) QueryLogs(req *types.QueryRequest) (resp *types.QueryRe
 	if req.ResourceID != "" {
 		queryMap["resource_id"] = req.ResourceID
 	}
+	if req.Username != "" {
+		queryMap["username"] = req.Username
+	}
+	if req.Result != "" {
+		queryMap["result"] = req.Result
+	}
+	if req.Keyword != "" {
+		queryMap["keyword"] = model.Keyword(l.svcCtx.Config.Query.Search, req.Keyword)
+	}
+	var start, end time.Time
 	if req.StartTime != 0 {
-		queryMap["created_at >= ?"] = time.UnixMilli(req.StartTime)
+		start = time.UnixMilli(req.StartTime)
+		queryMap["created_at >= ?"] = start
 	}
 	if req.EndTime != 0 {
-		queryMap["created_at <= ?"] = time.UnixMilli(req.EndTime)
+		end = time.UnixMilli(req.EndTime)
+		queryMap["created_at <= ?"] = end
 	}
 
 	queryMap["page"] = req.Page
 	queryMap["page_size"] = req.PageSize
 	queryMap["sort_field"] = req.SortField
 	queryMap["sort_order"] = req.SortOrder
+	queryMap["fields"] = req.FieldList()
+	queryMap["total_mode"] = req.TotalMode
 
-	logs := l.queryLogsByMap(queryMap)
-	return &types.QueryResponse{List: logs, Total: len(logs)}, nil
+	resp, err = l.queryPage(queryMap, start, end, req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if req.Keyword != "" {
+		l.highlight(resp.List, req.Keyword)
+	}
+	return resp, nil
 }
 
-func (l *QueryLogsLogic) queryLogsByMap(req map[string]any) []types.AuditLog {
-	var logs []*model.AuditLog
+// queryPage 按查询条件分页查询，start/end为创建时间范围，用于裁剪分表
+// token不为空时按游标分页，本页已满时返回下一页游标
+// 游标分页只在首页统计总数，后续页沿用游标中的总数，并跳过游标中记录的已取完分表
+func (l *QueryLogsLogic) queryPage(queryMap map[string]any, start, end time.Time, token string) (*types.QueryResponse, error) {
+	sortField := queryMap["sort_field"].(string)
+	desc := strings.EqualFold(queryMap["sort_order"].(string), "desc")
+	state := &queryCursor{Field: sortField, Desc: desc, Filter: filterDigest(queryMap)}
+
+	// 游标分页：从上一页最后一条记录之后继续，按排序时间收紧范围以跳过已翻过的分表
+	var after *model.Keyset
+	if token != "" {
+		var err error
+		if state, after, err = l.decodeCursor(token, sortField, desc, state.Filter); err != nil {
+			return nil, err
+		}
+		if t, ok := after.Value.(time.Time); ok && sortField == "created_at" {
+			if desc && (end.IsZero() || t.Before(end)) {
+				end = t
+			}
+			if !desc && t.After(start) {
+				start = t
+			}
+		}
+	}
+
+	// 按分表目录裁剪与时间范围无交集的分表
+	tables, err := model.ShardTablesInRange(l.svcCtx.DB, model.AuditLogName, start, end)
+	if err != nil {
+		l.Logger.Errorf("read shard catalog failed: %v", err)
+		return nil, err
+	}
 
-	query := l.svcCtx.DB.Model(&model.AuditLog{})
-	for k, v := range req {
-		if strings.Contains(k, "?") {
-			query = query.Where(k, v)
-		} else {
-			query = query.Where(k+" = ?", v)
+	closed := map[string]bool{}
+	if len(state.Done) > 0 {
+		if closed, err = model.ClosedShards(l.svcCtx.DB.WithContext(l.ctx), tables); err != nil {
+			l.Logger.Errorf("read shard catalog failed: %v", err)
+			return nil, err
 		}
 	}
+	page, err := l.queryLogsByMap(state.skipDone(tables, closed), queryMap, after)
+	if err != nil {
+		return nil, err
+	}
+	if after == nil {
+		state.Total = page.Total
+	}
 
-	// 分页、排序
-	query = query.Offset((req["page"].(int) - 1) * req["page_size"].(int)).Limit(req["page_size"].(int))
-	sortField := req["sort_field"].(string)
-	query = query.Order(sortField + " " + req["sort_order"].(string))
+	fields, _ := queryMap["fields"].([]string)
+	resp := &types.QueryResponse{
+		List:      toLogRecords(page.Logs, fields),
+		Total:     int(state.Total),
+		Estimated: queryMap["total_mode"] == totalEstimate,
+	}
+	// 本页已满时返回下一页游标，适用于偏移分页和游标分页
+	if len(page.Logs) == queryMap["page_size"].(int) {
+		state.markDone(page.Exhausted)
+		if resp.NextCursor, err = l.encodeCursor(page.Logs[len(page.Logs)-1], *state); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
 
-	if err := query.Find(&logs).Error; err != nil {
+// queryLogsByMap 在各分表上并行查询，归并排序后返回全局分页结果
+// after不为空时按游标位置查询，忽略页码且不统计总数
+func (l *QueryLogsLogic) queryLogsByMap(tables []string, req map[string]any, after *model.Keyset) (*model.ShardPage, error) {
+	pageSize := req["page_size"].(int)
+	offset := (req["page"].(int) - 1) * pageSize
+	if after != nil {
+		offset = 0
+	}
+	fields, _ := req["fields"].([]string)
+	page, err := model.QueryShardPage(l.ctx, l.svcCtx.DB, model.ShardQuery{
+		Tables:    tables,
+		Scope:     scopeOf(req),
+		SortField: req["sort_field"].(string),
+		Desc:      strings.EqualFold(req["sort_order"].(string), "desc"),
+		Offset:    offset,
+		Limit:     pageSize,
+		Count:     after == nil,
+		Estimate:  req["total_mode"] == totalEstimate,
+		Select:    fields,
+		After:     after,
+	})
+	if err != nil {
 		l.Logger.Errorf("query audit logs failed: %v", err)
-		return nil
+		return nil, err
+	}
+	return page, nil
+}
+
+// scopeOf 将查询条件转换为作用于每张分表的过滤条件，分页、排序和投影参数不参与过滤
+// 键中含占位符的视为原始条件，多个参数的条件以[]any传入
+func scopeOf(req map[string]any) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for k, v := range req {
+			switch {
+			case k == "page" || k == "page_size" || k == "sort_field" || k == "sort_order" || k == "fields" || k == "total_mode":
+			case isExpr(v):
+				db = db.Where(v)
+			case strings.Contains(k, "?") || strings.Contains(k, "@"):
+				if args, ok := v.([]any); ok {
+					db = db.Where(k, args...)
+				} else {
+					db = db.Where(k, v)
+				}
+			default:
+				db = db.Where(k+" = ?", v)
+			}
+		}
+		return db
+	}
+}
+
+func isExpr(v any) bool {
+	_, ok := v.(clause.Expression)
+	return ok
+}
+
+// highlight 为关键字命中的字段生成高亮片段
+func (l *QueryLogsLogic) highlight(logs []types.LogRecord, keyword string) {
+	h := highlight.New(l.svcCtx.Config.Query.HighlightRadius)
+	for i := range logs {
+		log := &logs[i]
+		for field, text := range map[string]string{
+			"message":       log.Message,
+			"resource_name": log.ResourceName,
+			"username":      log.Username,
+		} {
+			if snippet, ok := h.Snippet(text, keyword); ok {
+				if log.Highlights == nil {
+					log.Highlights = make(map[string]string)
+				}
+				log.Highlights[field] = snippet
+			}
+		}
+	}
+}
+
+// toLogRecords 转换为响应模型，指定了投影字段时只保留这些字段和log_id
+func toLogRecords(logs []*model.AuditLog, fields []string) []types.LogRecord {
+	var selected map[string]bool
+	if len(fields) > 0 {
+		selected = make(map[string]bool, len(fields))
+		for _, field := range fields {
+			selected[field] = true
+		}
 	}
 
-	auditLogs := make([]types.AuditLog, 0, len(logs))
+	records := make([]types.LogRecord, 0, len(logs))
 	for _, log := range logs {
-		auditLogs = append(auditLogs, types.AuditLog{
-			TenantID:     log.TenantID,
-			UserID:       log.UserID,
-			Username:     log.Username,
-			Action:       log.Action,
-			ResourceType: log.ResourceType,
-			ResourceID:   log.ResourceID,
-			ResourceName: log.ResourceName,
-			Result:       log.Result,
-			Message:      log.Message,
-			ClientIP:     log.ClientIP,
-			Module:       log.Module,
-			TraceID:      log.TraceID,
-		})
-	}
-
-	return auditLogs
+		record := types.LogRecord{
+			LogId:          log.LogId,
+			TenantID:       log.TenantID,
+			UserID:         log.UserID,
+			Username:       log.Username,
+			Action:         log.Action,
+			ResourceType:   log.ResourceType,
+			ResourceID:     log.ResourceID,
+			ResourceName:   log.ResourceName,
+			Result:         log.Result,
+			Message:        log.Message,
+			Timestamp:      log.TimeStamp,
+			ClientIP:       log.ClientIP,
+			Module:         log.Module,
+			TraceID:        log.TraceID,
+			IdempotencyKey: log.IdempotencyKey,
+			Seq:            log.Seq,
+			PrevHash:       log.PrevHash,
+			Hash:           log.Hash,
+			CreatedAt:      unixMilli(log.CreatedAt),
+			UpdatedAt:      unixMilli(log.UpdatedAt),
+		}
+		if selected != nil {
+			project(&record, selected)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// project 清空未选择的字段并记录投影，序列化时省略这些字段；排序字段虽被查询但不在投影中时同样清空
+func project(record *types.LogRecord, selected map[string]bool) {
+	v := reflect.ValueOf(record).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if name != "log_id" && name != "highlights" && name != "-" && !selected[name] {
+			v.Field(i).SetZero()
+		}
+	}
+	record.Selected = selected
+}
+
+// unixMilli 时间为零值时返回0
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
 }
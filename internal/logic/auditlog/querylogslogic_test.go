@@ -0,0 +1,60 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToLogRecords(t *testing.T) {
+	created := time.UnixMilli(1735689600123)
+	logs := []*model.AuditLog{{
+		LogId:     "01JGZ_1",
+		TenantID:  "t1",
+		Action:    "LOGIN",
+		Message:   "login failed",
+		Seq:       0,
+		CreatedAt: created,
+	}}
+
+	// 未投影时零值字段也会返回
+	records := toLogRecords(logs, nil)
+	require.Len(t, records, 1)
+	assert.Equal(t, "t1", records[0].TenantID)
+	assert.Equal(t, created.UnixMilli(), records[0].CreatedAt)
+	assert.Zero(t, records[0].UpdatedAt)
+	assert.Nil(t, records[0].Selected)
+
+	var full map[string]any
+	data, err := json.Marshal(records[0])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &full))
+	for _, field := range []string{"seq", "result", "updated_at", "prev_hash"} {
+		assert.Contains(t, full, field)
+	}
+	assert.Equal(t, float64(0), full["seq"])
+	assert.NotContains(t, full, "highlights")
+
+	// 投影时只返回指定字段和log_id，未选择的字段被清空
+	records = toLogRecords(logs, []string{"action", "seq"})
+	assert.Equal(t, "LOGIN", records[0].Action)
+	assert.Empty(t, records[0].TenantID)
+	assert.Empty(t, records[0].Message)
+	assert.Zero(t, records[0].CreatedAt)
+
+	data, err = json.Marshal(records[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"log_id":"01JGZ_1","action":"LOGIN","seq":0}`, string(data))
+
+	// 高亮片段不受投影影响
+	records[0].Highlights = map[string]string{"action": "<em>LOGIN</em>"}
+	data, err = json.Marshal(records[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"log_id":"01JGZ_1","action":"LOGIN","seq":0,"highlights":{"action":"<em>LOGIN</em>"}}`, string(data))
+
+	assert.Empty(t, toLogRecords(nil, nil))
+}
@@ -7,6 +7,8 @@ import (
 
 	"codexie.com/auditlog/internal/svc"
 	"codexie.com/auditlog/internal/types"
+	"codexie.com/auditlog/pkg/apierr"
+	"codexie.com/auditlog/pkg/ratelimit"
 
 	"github.com/zeromicro/go-zero/core/logx"
 )
@@ -26,6 +28,13 @@ func NewReportLogLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ReportL
 }
 
 func (l *ReportLogLogic) ReportLog(req *types.AuditLog) (resp *types.BaseResponse, err error) {
+	switch l.svcCtx.TenantLimiter.Allow(l.ctx, req.TenantID) {
+	case ratelimit.RateLimited:
+		return nil, apierr.ErrRateLimited
+	case ratelimit.QuotaExceeded:
+		return nil, apierr.ErrQuotaExceeded
+	}
+
 	auditLog := req.ToAuditLog()
 
 	for _, p := range l.svcCtx.Piplines {
@@ -0,0 +1,72 @@
+package auditlog
+
+import (
+	"context"
+
+	"codexie.com/auditlog/internal/constant"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"codexie.com/auditlog/pkg/apierr"
+	"codexie.com/auditlog/pkg/querydsl"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// querySchema 查询DSL字段白名单
+var querySchema = querydsl.Schema(constant.QueryFields)
+
+type SearchLogsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSearchLogsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SearchLogsLogic {
+	return &SearchLogsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *SearchLogsLogic) SearchLogs(req *types.SearchRequest) (resp *types.QueryResponse, err error) {
+	// 非管理员只能查询自己租户的日志
+	if req.TenantID, err = scopeTenant(l.ctx, req.TenantID); err != nil {
+		return nil, err
+	}
+
+	query, err := compileFilter(req.Filter)
+	if err != nil {
+		return nil, apierr.WithErrf(l.Logger, "E00001", "%v", err)
+	}
+
+	queryMap := make(map[string]any)
+	if req.TenantID != "" {
+		queryMap["tenant_id"] = req.TenantID
+	}
+	if cond, args := query.SQL(); cond != "" {
+		queryMap[cond] = args
+	}
+	queryMap["page"] = req.Page
+	queryMap["page_size"] = req.PageSize
+	queryMap["sort_field"] = req.SortField
+	queryMap["sort_order"] = req.SortOrder
+	queryMap["fields"] = req.Fields
+	queryMap["total_mode"] = req.TotalMode
+
+	// 顶层AND中的创建时间条件用于裁剪分表
+	start, end := query.TimeRange("created_at")
+	return NewQueryLogsLogic(l.ctx, l.svcCtx).queryPage(queryMap, start, end, req.Cursor)
+}
+
+// compileFilter 按字段白名单编译查询条件，filter为空时匹配全部数据
+func compileFilter(filter map[string]any) (*querydsl.Query, error) {
+	if len(filter) == 0 {
+		return querydsl.Compile(querySchema, nil)
+	}
+	node, err := querydsl.FromMap(filter)
+	if err != nil {
+		return nil, err
+	}
+	return querydsl.Compile(querySchema, node)
+}
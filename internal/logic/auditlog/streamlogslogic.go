@@ -0,0 +1,162 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// 流式查询的结束状态
+const (
+	StreamComplete  = "complete"   // 已返回全部满足条件的记录
+	StreamRowLimit  = "row_limit"  // 达到行数限制，仍有未返回的记录
+	StreamTimeLimit = "time_limit" // 达到时间限制
+)
+
+// StreamResult 流式查询结束时的统计
+type StreamResult struct {
+	Rows   int64  `json:"rows"`
+	Status string `json:"status"`
+}
+
+type StreamLogsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewStreamLogsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *StreamLogsLogic {
+	return &StreamLogsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// StreamLogs 按创建时间逐张分表做键集扫描，每批结果交给send输出，内存中只保留一批记录
+// 分表按序号顺序读取，同一分表内严格有序；客户端断开时ctx被取消，查询随之中止
+func (l *StreamLogsLogic) StreamLogs(req *types.StreamRequest, send func([]types.LogRecord) error) (*StreamResult, error) {
+	// 非管理员只能查询自己租户的日志
+	var err error
+	if req.TenantID, err = scopeTenant(l.ctx, req.TenantID); err != nil {
+		return nil, err
+	}
+	conf := l.svcCtx.Config.Stream
+	maxRows, maxDuration := conf.Limit(userRoles(l.ctx))
+	if req.Limit > 0 && req.Limit < maxRows {
+		maxRows = req.Limit
+	}
+
+	queryMap, start, end := l.streamQuery(req)
+	tables, err := model.ShardTablesInRange(l.svcCtx.DB, model.AuditLogName, start, end)
+	if err != nil {
+		l.Logger.Errorf("read shard catalog failed: %v", err)
+		return nil, err
+	}
+	desc := req.SortOrder == "desc"
+	if desc {
+		slices.Reverse(tables)
+	}
+
+	fields := req.FieldList()
+	fetch := func(ctx context.Context, table string, after *model.Keyset, limit int) ([]*model.AuditLog, error) {
+		logs, _, err := model.QueryShards(ctx, l.svcCtx.DB, model.ShardQuery{
+			Tables:    []string{table},
+			Scope:     scopeOf(queryMap),
+			SortField: "created_at",
+			Desc:      desc,
+			Limit:     limit,
+			Select:    fields,
+			After:     after,
+		})
+		return logs, err
+	}
+	return streamShards(l.ctx, tables, conf.BatchSize, maxRows, maxDuration, fetch, func(logs []*model.AuditLog) error {
+		return send(toLogRecords(logs, fields))
+	})
+}
+
+// shardFetcher 读取一张分表中排在after之后的至多limit条记录，after为空时从头读取
+type shardFetcher func(ctx context.Context, table string, after *model.Keyset, limit int) ([]*model.AuditLog, error)
+
+// streamShards 逐张分表分批读取并输出，达到行数或时间限制时停止
+// 时间限制到期时返回已输出的统计，调用方的ctx被取消(客户端断开)时返回错误
+func streamShards(parent context.Context, tables []string, batchSize int, maxRows int64, maxDuration time.Duration,
+	fetch shardFetcher, send func([]*model.AuditLog) error) (*StreamResult, error) {
+	ctx, cancel := context.WithTimeout(parent, maxDuration)
+	defer cancel()
+	result := &StreamResult{Status: StreamComplete}
+	for _, table := range tables {
+		var after *model.Keyset
+		for {
+			// 多取一条用于判断达到行数限制时是否还有剩余记录
+			remaining := maxRows - result.Rows
+			limit := int(min(int64(batchSize), remaining+1))
+			logs, err := fetch(ctx, table, after, limit)
+			if err != nil {
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) && parent.Err() == nil {
+					result.Status = StreamTimeLimit
+					return result, nil
+				}
+				return result, err
+			}
+			truncated := int64(len(logs)) > remaining
+			if truncated {
+				logs = logs[:remaining]
+			}
+			if len(logs) > 0 {
+				if err := send(logs); err != nil {
+					return result, err
+				}
+				result.Rows += int64(len(logs))
+			}
+			if truncated {
+				result.Status = StreamRowLimit
+				return result, nil
+			}
+			if len(logs) < limit {
+				break
+			}
+			last := logs[len(logs)-1]
+			after = &model.Keyset{Value: last.CreatedAt, LogId: last.LogId}
+		}
+	}
+	return result, nil
+}
+
+// streamQuery 将请求中的过滤条件转换为查询条件，并返回用于裁剪分表的时间范围
+func (l *StreamLogsLogic) streamQuery(req *types.StreamRequest) (map[string]any, time.Time, time.Time) {
+	queryMap := make(map[string]any)
+	for field, value := range map[string]string{
+		"tenant_id":     req.TenantID,
+		"user_id":       req.UserID,
+		"username":      req.Username,
+		"action":        req.Action,
+		"resource_type": req.ResourceType,
+		"resource_id":   req.ResourceID,
+		"result":        req.Result,
+	} {
+		if value != "" {
+			queryMap[field] = value
+		}
+	}
+	if req.Keyword != "" {
+		queryMap["keyword"] = model.Keyword(l.svcCtx.Config.Query.Search, req.Keyword)
+	}
+	var start, end time.Time
+	if req.StartTime != 0 {
+		start = time.UnixMilli(req.StartTime)
+		queryMap["created_at >= ?"] = start
+	}
+	if req.EndTime != 0 {
+		end = time.UnixMilli(req.EndTime)
+		queryMap["created_at <= ?"] = end
+	}
+	return queryMap, start, end
+}
@@ -0,0 +1,146 @@
+package auditlog
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memShards 内存中的分表，记录按created_at升序排列
+type memShards struct {
+	tables map[string][]*model.AuditLog
+	limits []int // 每次读取的条数上限
+}
+
+func newMemShards(sizes ...int) (*memShards, []string) {
+	m := &memShards{tables: make(map[string][]*model.AuditLog)}
+	tables := make([]string, 0, len(sizes))
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, size := range sizes {
+		table := fmt.Sprintf("audit_log_%d", i+1)
+		for j := 0; j < size; j++ {
+			m.tables[table] = append(m.tables[table], &model.AuditLog{
+				LogId:     fmt.Sprintf("%03d_%d", j, i+1),
+				CreatedAt: base.Add(time.Duration(i*1000+j) * time.Second),
+			})
+		}
+		tables = append(tables, table)
+	}
+	return m, tables
+}
+
+func (m *memShards) fetch(ctx context.Context, table string, after *model.Keyset, limit int) ([]*model.AuditLog, error) {
+	m.limits = append(m.limits, limit)
+	var out []*model.AuditLog
+	for _, log := range m.tables[table] {
+		if after != nil && !log.CreatedAt.After(after.Value.(time.Time)) {
+			continue
+		}
+		if len(out) == limit {
+			break
+		}
+		out = append(out, log)
+	}
+	return out, nil
+}
+
+func streamAll(t *testing.T, m *memShards, tables []string, batchSize int, maxRows int64) (*StreamResult, []string) {
+	t.Helper()
+	var ids []string
+	result, err := streamShards(context.Background(), tables, batchSize, maxRows, time.Minute, m.fetch, func(logs []*model.AuditLog) error {
+		assert.LessOrEqual(t, len(logs), batchSize)
+		ids = append(ids, logIds(logs)...)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(ids)), result.Rows)
+	return result, ids
+}
+
+func logIds(logs []*model.AuditLog) []string {
+	ids := make([]string, 0, len(logs))
+	for _, log := range logs {
+		ids = append(ids, log.LogId)
+	}
+	return ids
+}
+
+func TestStreamShards_RowLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		sizes   []int
+		maxRows int64
+		rows    int64
+		status  string
+	}{
+		{name: "all rows", sizes: []int{5, 0, 7}, maxRows: 100, rows: 12, status: StreamComplete},
+		{name: "exactly the limit", sizes: []int{5, 7}, maxRows: 12, rows: 12, status: StreamComplete},
+		{name: "limit reached in a shard", sizes: []int{5, 7}, maxRows: 8, rows: 8, status: StreamRowLimit},
+		{name: "limit reached at a shard boundary", sizes: []int{5, 7}, maxRows: 5, rows: 5, status: StreamRowLimit},
+		{name: "limit at a boundary followed by empty shards", sizes: []int{5, 0, 0}, maxRows: 5, rows: 5, status: StreamComplete},
+		{name: "no shards", maxRows: 5, status: StreamComplete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, tables := newMemShards(tt.sizes...)
+			result, ids := streamAll(t, m, tables, 3, tt.maxRows)
+			assert.Equal(t, tt.status, result.Status)
+			assert.Equal(t, tt.rows, result.Rows)
+			// 同一分表内按时间顺序、不重复地输出
+			assert.Len(t, ids, int(tt.rows))
+			seen := make(map[string]bool)
+			for _, id := range ids {
+				assert.False(t, seen[id], id)
+				seen[id] = true
+			}
+		})
+	}
+
+	// 接近行数限制时只多取一条用于判断是否还有剩余
+	m, tables := newMemShards(10)
+	streamAll(t, m, tables, 3, 4)
+	assert.Equal(t, []int{3, 2}, m.limits)
+}
+
+func TestStreamShards_TimeLimit(t *testing.T) {
+	m, tables := newMemShards(3, 3)
+	slow := func(ctx context.Context, table string, after *model.Keyset, limit int) ([]*model.AuditLog, error) {
+		if table == "audit_log_2" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return m.fetch(ctx, table, after, limit)
+	}
+	var rows int
+	result, err := streamShards(context.Background(), tables, 10, 100, 20*time.Millisecond, slow, func(logs []*model.AuditLog) error {
+		rows += len(logs)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StreamTimeLimit, result.Status)
+	assert.Equal(t, int64(3), result.Rows)
+	assert.Equal(t, 3, rows)
+
+	// 客户端断开不属于时间限制，返回错误
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = streamShards(ctx, tables, 10, 100, time.Minute, func(ctx context.Context, table string, after *model.Keyset, limit int) ([]*model.AuditLog, error) {
+		return nil, ctx.Err()
+	}, func([]*model.AuditLog) error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestStreamShards_SendError(t *testing.T) {
+	m, tables := newMemShards(5)
+	sendErr := fmt.Errorf("broken pipe")
+	result, err := streamShards(context.Background(), tables, 2, 100, time.Minute, m.fetch, func([]*model.AuditLog) error {
+		return sendErr
+	})
+	assert.ErrorIs(t, err, sendErr)
+	assert.Equal(t, int64(0), result.Rows)
+}
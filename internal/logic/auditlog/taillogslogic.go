@@ -0,0 +1,118 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"codexie.com/auditlog/pkg/apierr"
+	"codexie.com/auditlog/pkg/plugin"
+	"codexie.com/auditlog/pkg/querydsl"
+	"codexie.com/auditlog/pkg/tail"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	tailBatchSize        = 100 // 单次推送的最大记录数
+	defaultTailHeartbeat = 15 * time.Second
+)
+
+// TailEvent 推送给订阅者的一批记录，Skipped为上次推送以来因消费过慢被丢弃的记录数
+// 两者都为空时表示心跳
+type TailEvent struct {
+	Records []types.LogRecord
+	Skipped int64
+}
+
+type TailLogsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewTailLogsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *TailLogsLogic {
+	return &TailLogsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// Subscribe 按租户和查询条件订阅新写入的日志，调用方负责关闭返回的订阅
+func (l *TailLogsLogic) Subscribe(req *types.TailRequest) (*tail.Subscription, error) {
+	// 非管理员只能订阅自己租户的日志
+	var err error
+	if req.TenantID, err = scopeTenant(l.ctx, req.TenantID); err != nil {
+		return nil, err
+	}
+
+	var node *querydsl.Node
+	if req.Filter != "" {
+		var err error
+		if node, err = querydsl.Parse([]byte(req.Filter)); err != nil {
+			return nil, apierr.WithErrf(l.Logger, "E00001", "%v", err)
+		}
+	}
+	query, err := querydsl.Compile(querySchema, node)
+	if err != nil {
+		return nil, apierr.WithErrf(l.Logger, "E00001", "%v", err)
+	}
+
+	// 租户按原值精确匹配，不使用DSL中忽略大小写的比较
+	tenant := req.TenantID
+	sub, err := l.svcCtx.Tail.Subscribe(func(record any) bool {
+		log, ok := record.(*model.AuditLog)
+		if !ok || (tenant != "" && log.TenantID != tenant) {
+			return false
+		}
+		return query.Match(func(field string) (any, bool) {
+			return plugin.FieldValue(log, field)
+		})
+	})
+	if errors.Is(err, tail.ErrTooManySubscribers) {
+		return nil, apierr.ErrTooManySubscribers
+	}
+	return sub, err
+}
+
+// Forward 将订阅收到的记录分批交给send，空闲时定期发送心跳
+// ctx结束时返回nil，订阅因消费过慢被断开时返回tail.ErrSlowConsumer
+func (l *TailLogsLogic) Forward(ctx context.Context, sub *tail.Subscription, fields []string, send func(*TailEvent) error) error {
+	interval := time.Duration(l.svcCtx.Config.Tail.Heartbeat) * time.Second
+	if interval <= 0 {
+		interval = defaultTailHeartbeat
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.Done():
+			return sub.Err()
+		case <-heartbeat.C:
+			if err := send(&TailEvent{Skipped: sub.Skipped()}); err != nil {
+				return err
+			}
+		case record := <-sub.C:
+			logs := []*model.AuditLog{record.(*model.AuditLog)}
+		drain:
+			for len(logs) < tailBatchSize {
+				select {
+				case record := <-sub.C:
+					logs = append(logs, record.(*model.AuditLog))
+				default:
+					break drain
+				}
+			}
+			if err := send(&TailEvent{Records: toLogRecords(logs, fields), Skipped: sub.Skipped()}); err != nil {
+				return err
+			}
+			heartbeat.Reset(interval)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package auditlog
+
+import (
+	"context"
+
+	"codexie.com/auditlog/internal/constant"
+	"codexie.com/auditlog/internal/types"
+	"codexie.com/auditlog/pkg/apierr"
+	"codexie.com/auditlog/pkg/util"
+)
+
+// currentUser 返回上下文中的调用方，无法确认身份时返回ErrUnauthenticated
+func currentUser(ctx context.Context) (*types.User, error) {
+	user, ok := ctx.Value(constant.USER).(*types.User)
+	if !ok || user == nil {
+		return nil, apierr.ErrUnauthenticated
+	}
+	return user, nil
+}
+
+// scopeTenant 返回请求可访问的租户，为空表示不限租户，仅管理员可以跨租户访问
+// 非管理员固定为本租户；无法确认调用方身份时拒绝访问
+func scopeTenant(ctx context.Context, tenantID string) (string, error) {
+	user, err := currentUser(ctx)
+	switch {
+	case err != nil:
+		return "", err
+	case util.ArrayContains(user.Roles, constant.ADMIN):
+		return tenantID, nil
+	case user.TenantID == "":
+		return "", apierr.ErrTenantRequired
+	default:
+		return user.TenantID, nil
+	}
+}
+
+// canAccessTenant 当前用户能否访问指定租户的资源，与scopeTenant一致，无法确认调用方身份时返回ErrUnauthenticated
+func canAccessTenant(ctx context.Context, tenantID string) (bool, error) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		return false, err
+	}
+	return util.ArrayContains(user.Roles, constant.ADMIN) || (user.TenantID != "" && user.TenantID == tenantID), nil
+}
+
+// userRoles 返回当前用户的角色，上下文中没有用户时为空
+func userRoles(ctx context.Context) []string {
+	if user, err := currentUser(ctx); err == nil {
+		return user.Roles
+	}
+	return nil
+}
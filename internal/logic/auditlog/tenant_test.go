@@ -0,0 +1,84 @@
+package auditlog
+
+import (
+	"context"
+	"testing"
+
+	"codexie.com/auditlog/internal/constant"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"codexie.com/auditlog/pkg/apierr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeTenant(t *testing.T) {
+	admin := &types.User{ID: "u1", Roles: []string{constant.ADMIN}}
+	member := &types.User{ID: "u2", TenantID: "t1", Roles: []string{"auditor"}}
+	noTenant := &types.User{ID: "u3", Roles: []string{"auditor"}}
+
+	tests := []struct {
+		name    string
+		user    *types.User
+		tenant  string
+		want    string
+		wantErr error
+	}{
+		{name: "admin across tenants", user: admin, want: ""},
+		{name: "admin picks tenant", user: admin, tenant: "t2", want: "t2"},
+		{name: "member fixed to own tenant", user: member, tenant: "t2", want: "t1"},
+		{name: "member without tenant", user: noTenant, tenant: "t2", wantErr: apierr.ErrTenantRequired},
+		{name: "anonymous with tenant", tenant: "t2", wantErr: apierr.ErrUnauthenticated},
+		{name: "anonymous across tenants", wantErr: apierr.ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.user != nil {
+				ctx = context.WithValue(ctx, constant.USER, tt.user)
+			}
+			got, err := scopeTenant(ctx, tt.tenant)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	tenantTests := []struct {
+		name    string
+		user    *types.User
+		tenant  string
+		want    bool
+		wantErr error
+	}{
+		{name: "admin", user: admin, tenant: "t2", want: true},
+		{name: "own tenant", user: member, tenant: "t1", want: true},
+		{name: "other tenant", user: member, tenant: "t2"},
+		{name: "member without tenant", user: noTenant, tenant: ""},
+		{name: "anonymous", tenant: "t1", wantErr: apierr.ErrUnauthenticated},
+	}
+	for _, tt := range tenantTests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.user != nil {
+				ctx = context.WithValue(ctx, constant.USER, tt.user)
+			}
+			got, err := canAccessTenant(ctx, tt.tenant)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestAnonymousDenied 无法确认身份的调用方在访问数据库之前被拒绝
+func TestAnonymousDenied(t *testing.T) {
+	svcCtx := &svc.ServiceContext{}
+	ctx := context.Background()
+
+	_, err := loadExportJob(ctx, nil, "task")
+	assert.Equal(t, apierr.ErrUnauthenticated, err)
+	_, err = NewGetProofLogic(ctx, svcCtx).GetProof(&types.ProofRequest{LogId: "01J"})
+	assert.Equal(t, apierr.ErrUnauthenticated, err)
+	_, err = NewQueryLogsLogic(ctx, svcCtx).QueryLogs(&types.QueryRequest{TenantID: "t1"})
+	assert.Equal(t, apierr.ErrUnauthenticated, err)
+	_, err = NewGetStatsLogic(ctx, svcCtx).GetStats(&types.StatsRequest{TenantID: "t1"})
+	assert.Equal(t, apierr.ErrUnauthenticated, err)
+}
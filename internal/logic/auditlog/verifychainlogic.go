@@ -0,0 +1,53 @@
+package auditlog
+
+import (
+	"context"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/internal/svc"
+	"codexie.com/auditlog/internal/types"
+	"codexie.com/auditlog/pkg/apierr"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type VerifyChainLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewVerifyChainLogic(ctx context.Context, svcCtx *svc.ServiceContext) *VerifyChainLogic {
+	return &VerifyChainLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *VerifyChainLogic) VerifyChain(req *types.VerifyRequest) (resp *types.VerifyResponse, err error) {
+	// 非管理员只能校验自己租户的哈希链
+	if req.TenantID, err = scopeTenant(l.ctx, req.TenantID); err != nil {
+		return nil, err
+	}
+	if req.TenantID == "" {
+		return nil, apierr.WithErrf(l.Logger, "E00001", "tenant_id is required")
+	}
+
+	res, err := model.VerifyChain(l.ctx, l.svcCtx.DB, req.TenantID)
+	if err != nil {
+		l.Logger.Errorf("verify hash chain of tenant %s failed: %v", req.TenantID, err)
+		return nil, err
+	}
+
+	return &types.VerifyResponse{
+		TenantID:    res.TenantID,
+		Valid:       res.Valid,
+		Checked:     res.Checked,
+		LastSeq:     res.LastSeq,
+		HeadSeq:     res.HeadSeq,
+		BrokenSeq:   res.BrokenSeq,
+		BrokenLogId: res.BrokenLogId,
+		BrokenTable: res.BrokenTable,
+		Reason:      res.Reason,
+	}, nil
+}
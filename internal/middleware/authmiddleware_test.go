@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/internal/config"
+	"codexie.com/auditlog/internal/constant"
+	"codexie.com/auditlog/internal/types"
+	"codexie.com/auditlog/pkg/apierr"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+const secret = "0123456789abcdef"
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, expires time.Time) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, userClaims{
+		Username: "alice",
+		Roles:    []string{"auditor"},
+		TenantID: "t1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "u1",
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}).SignedString(key)
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	httpx.SetErrorHandlerCtx(func(ctx context.Context, err error) (int, any) {
+		if err == apierr.ErrUnauthenticated {
+			return http.StatusUnauthorized, nil
+		}
+		return http.StatusBadRequest, nil
+	})
+	defer httpx.SetErrorHandlerCtx(nil)
+
+	var got *types.User
+	next := func(w http.ResponseWriter, r *http.Request) {
+		got, _ = r.Context().Value(constant.USER).(*types.User)
+	}
+	valid := sign(t, jwt.SigningMethodHS256, []byte(secret), time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name   string
+		conf   config.AuthConf
+		header string
+		query  string
+		status int
+		user   bool
+	}{
+		{name: "no token", conf: config.AuthConf{AccessSecret: secret}, status: http.StatusOK},
+		{name: "bearer token", conf: config.AuthConf{AccessSecret: secret}, header: "Bearer " + valid, status: http.StatusOK, user: true},
+		{name: "query token", conf: config.AuthConf{AccessSecret: secret}, query: valid, status: http.StatusOK, user: true},
+		{name: "not bearer", conf: config.AuthConf{AccessSecret: secret}, header: "Basic dTpw", status: http.StatusUnauthorized},
+		{name: "wrong secret", conf: config.AuthConf{AccessSecret: "other"}, header: "Bearer " + valid, status: http.StatusUnauthorized},
+		{name: "secret not configured", header: "Bearer " + valid, status: http.StatusUnauthorized},
+		{name: "expired", conf: config.AuthConf{AccessSecret: secret}, header: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte(secret), time.Now().Add(-time.Minute)), status: http.StatusUnauthorized},
+		{name: "unsigned", conf: config.AuthConf{AccessSecret: secret}, header: "Bearer " + sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, time.Now().Add(time.Hour)), status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			r := httptest.NewRequest(http.MethodGet, "/v1/audit/query", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.query != "" {
+				r.URL.RawQuery = "access_token=" + tt.query
+			}
+			w := httptest.NewRecorder()
+			NewAuthMiddleware(tt.conf).Handle(next)(w, r)
+			assert.Equal(t, tt.status, w.Code)
+			if tt.user {
+				assert.Equal(t, &types.User{ID: "u1", Username: "alice", Roles: []string{"auditor"}, TenantID: "t1"}, got)
+			} else {
+				assert.Nil(t, got)
+			}
+		})
+	}
+}
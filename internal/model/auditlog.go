@@ -3,45 +3,49 @@ package model
 import (
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
+	"codexie.com/auditlog/pkg/idgen"
 	"github.com/zeromicro/go-zero/core/logx"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
-// 分表配置（每月一张表）
+// 分表配置，分表策略见 ShardStrategy
 const (
 	AuditLogName = "audit_log"
 )
 
-// 审计日志实体（对应分表结构 audit_log_YYYYMM）
+// 审计日志实体（对应分表结构 audit_log_<shard>，按行数分表时shard为1,2,3...，按时间分表时为YYYYMMDD/YYYYWW/YYYYMM）
 type AuditLog struct {
-	Id           int       `gorm:"primaryKey,autoIncrement" json:"id"`                       // 自增主键
-	LogId        string    `gorm:"column:log_id;index;" json:"log_id"`                       // 日志ID
-	TenantID     string    `gorm:"column:tenant_id;index;" json:"tenant_id"`                 // 租户ID
-	UserID       string    `gorm:"column:user_id;index;" json:"user_id"`                     // 用户ID
-	Username     string    `gorm:"column:username;" json:"username"`                         // 用户名
-	Action       string    `gorm:"column:action;index;" json:"action"`                       // 操作类型
-	ResourceType string    `gorm:"column:resource_type;" json:"resource_type"`               // 资源类型
-	ResourceID   string    `gorm:"column:resource_id;" json:"resource_id"`                   // 资源ID
-	ResourceName string    `gorm:"column:resource_name;" json:"resource_name"`               // 资源名称
-	Result       string    `gorm:"column:result;index;" json:"result"`                       // 操作结果
-	Message      string    `gorm:"column:message;type:text" json:"message"`                  // 详细信息
-	TimeStamp    int64     `gorm:"column:timestamp;index" json:"timestamp"`                  // 日志时间戳
-	ClientIP     string    `gorm:"column:client_ip;" json:"client_ip"`                       // 客户端IP
-	Module       string    `gorm:"column:module;" json:"module"`                             // 模块
-	TraceID      string    `gorm:"column:trace_id;" json:"trace_id"`                         // 链路追踪ID
-	CreatedAt    time.Time `gorm:"column:created_at;index;autoCreateTime" json:"created_at"` // 创建时间
-	UpdatedAt    time.Time `gorm:"column:updated_at;index;autoUpdateTime" json:"updated_at"` // 更新时间
+	Id             int       `gorm:"primaryKey,autoIncrement" json:"id"`                                                                      // 自增主键
+	LogId          string    `gorm:"column:log_id;index;" json:"log_id"`                                                                      // 日志ID
+	TenantID       string    `gorm:"column:tenant_id;index;index:idx_tenant_seq,priority:1" json:"tenant_id"`                                 // 租户ID
+	UserID         string    `gorm:"column:user_id;index;" json:"user_id"`                                                                    // 用户ID
+	Username       string    `gorm:"column:username;index:idx_ft_keyword,class:FULLTEXT,option:WITH PARSER ngram,priority:3" json:"username"` // 用户名
+	Action         string    `gorm:"column:action;index;" json:"action"`                                                                      // 操作类型
+	ResourceType   string    `gorm:"column:resource_type;" json:"resource_type"`                                                              // 资源类型
+	ResourceID     string    `gorm:"column:resource_id;" json:"resource_id"`                                                                  // 资源ID
+	ResourceName   string    `gorm:"column:resource_name;index:idx_ft_keyword,priority:2" json:"resource_name"`                               // 资源名称
+	Result         string    `gorm:"column:result;index;" json:"result"`                                                                      // 操作结果
+	Message        string    `gorm:"column:message;type:text;index:idx_ft_keyword,priority:1" json:"message"`                                 // 详细信息
+	TimeStamp      int64     `gorm:"column:timestamp;index" json:"timestamp"`                                                                 // 日志时间戳
+	ClientIP       string    `gorm:"column:client_ip;" json:"client_ip"`                                                                      // 客户端IP
+	Module         string    `gorm:"column:module;" json:"module"`                                                                            // 模块
+	TraceID        string    `gorm:"column:trace_id;" json:"trace_id"`                                                                        // 链路追踪ID
+	IdempotencyKey string    `gorm:"column:idempotency_key;type:varchar(128);index" json:"idempotency_key"`                                   // 客户端幂等键
+	Seq            int64     `gorm:"column:seq;index:idx_tenant_seq,priority:2" json:"seq"`                                                   // 租户内哈希链序号，0表示未入链
+	PrevHash       string    `gorm:"column:prev_hash;type:char(64)" json:"prev_hash"`                                                         // 前一条记录的哈希
+	Hash           string    `gorm:"column:hash;type:char(64)" json:"hash"`                                                                   // 本条记录的哈希
+	CreatedAt      time.Time `gorm:"column:created_at;index;autoCreateTime" json:"created_at"`                                                // 创建时间
+	UpdatedAt      time.Time `gorm:"column:updated_at;index;autoUpdateTime" json:"updated_at"`                                                // 更新时间
 }
 
 // TableName 设置表名（实际表名会根据分表规则动态生成）
+// 日志ID格式为 <id>_<shard>，兼容历史的 uuid_<shard>
 func (log *AuditLog) TableName() string {
-	tableSuffix := strings.Split(log.LogId, "_")
-	if len(tableSuffix) > 1 {
-		return fmt.Sprintf("%s_%s", AuditLogName, tableSuffix[1])
+	if _, shard, ok := idgen.Parse(log.LogId); ok {
+		return fmt.Sprintf("%s_%d", AuditLogName, shard)
 	}
 	return AuditLogName
 }
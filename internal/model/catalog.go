@@ -0,0 +1,212 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 分表状态
+const (
+	ShardActive   = "active"   // 正在写入(含预建的后续分表)
+	ShardSealed   = "sealed"   // 不再作为写入位置，仍可能有迟到的写入
+	ShardArchived = "archived" // 数据已归档，表仍存在
+	ShardDropped  = "dropped"  // 表已删除
+)
+
+// ShardCatalog 分表目录，记录每张分表的时间范围、行数和状态，查询据此裁剪分表
+type ShardCatalog struct {
+	Id        int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Entity    string     `gorm:"column:entity;type:varchar(155);uniqueIndex:idx_entity_shard,priority:1" json:"entity"`
+	Shard     int        `gorm:"column:shard;uniqueIndex:idx_entity_shard,priority:2" json:"shard"`
+	Table     string     `gorm:"column:table_name;type:varchar(191)" json:"table_name"`
+	Status    string     `gorm:"column:status;type:varchar(16);index" json:"status"`
+	MinTime   *time.Time `gorm:"column:min_time" json:"min_time"`                        // 最早入库时间，空表为空
+	MaxTime   *time.Time `gorm:"column:max_time" json:"max_time"`                        // 最晚入库时间，空表为空
+	RowCount  int64      `gorm:"column:row_count" json:"row_count"`                      // 近似行数，取自information_schema
+	FullText  bool       `gorm:"column:ft_index;not null;default:false" json:"ft_index"` // 实体声明的索引(含全文索引)是否已建好
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (c *ShardCatalog) TableName() string {
+	return "shard_catalog"
+}
+
+// RegisterShard 登记新分表，已登记的分表保持不变
+func RegisterShard(db *gorm.DB, entity string, shard int) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&ShardCatalog{
+		Entity: entity,
+		Shard:  shard,
+		Table:  fmt.Sprintf("%s_%d", entity, shard),
+		Status: ShardActive,
+	}).Error
+}
+
+// SyncShardCatalog 将分表位置范围内已存在但未登记的分表补登到目录，兼容目录上线前创建的分表
+func SyncShardCatalog(db *gorm.DB, pos *SchedulePos) error {
+	strategy, err := pos.ShardStrategy()
+	if err != nil {
+		return err
+	}
+	for _, shard := range strategy.Shards(pos, time.Now()) {
+		if !db.Migrator().HasTable(fmt.Sprintf("%s_%d", pos.Name, shard)) {
+			continue
+		}
+		if err := RegisterShard(db, pos.Name, shard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShardSealGrace 分表不再作为当前写入位置后延迟封存的时间
+// 需长于实例缓存分表位置的刷新间隔和磁盘恢复的重试间隔，期间迟到的写入仍计入活跃分表
+const ShardSealGrace = time.Hour
+
+// RefreshShardCatalog 刷新活跃和已封存分表的统计信息，当前写入位置之前的分表在超过ShardSealGrace后封存
+// 封存后仍可能有迟到的写入(磁盘恢复、按客户端时间路由到历史分表)，因此已封存分表继续刷新时间范围，
+// 避免查询按max_time裁剪时漏掉这些记录
+func RefreshShardCatalog(db *gorm.DB, pos *SchedulePos) error {
+	var shards []*ShardCatalog
+	err := db.Where("entity = ? AND status IN ?", pos.Name, []string{ShardActive, ShardSealed}).Find(&shards).Error
+	if err != nil {
+		return err
+	}
+	sealable := pos.StartTime != nil && time.Since(*pos.StartTime) > ShardSealGrace
+	for _, shard := range shards {
+		if !db.Migrator().HasTable(shard.Table) {
+			shard.Status = ShardDropped
+		} else {
+			if err := shard.refreshStats(db); err != nil {
+				return err
+			}
+			if shard.Status == ShardActive && shard.Shard < pos.ScheduleEndPos && sealable {
+				shard.Status = ShardSealed
+			}
+		}
+		err := db.Model(shard).Select("status", "min_time", "max_time", "row_count").Updates(shard).Error
+		if err != nil {
+			return fmt.Errorf("failed to update catalog of %s: %w", shard.Table, err)
+		}
+	}
+	return nil
+}
+
+// refreshStats 刷新分表的时间范围和行数，时间范围通过created_at索引查询，已有统计时只查找范围之外的记录
+// 磁盘恢复的记录保留原入库时间，可能早于已统计的min_time，两端都需要刷新
+func (c *ShardCatalog) refreshStats(db *gorm.DB) error {
+	var err error
+	if c.MinTime, err = c.boundTime(db, "created_at <= ?", c.MinTime, "created_at"); err != nil {
+		return err
+	}
+	if c.MaxTime, err = c.boundTime(db, "created_at >= ?", c.MaxTime, "created_at DESC"); err != nil {
+		return err
+	}
+
+	var rows []int64
+	err = db.Raw("SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", c.Table).
+		Scan(&rows).Error
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", c.Table, err)
+	}
+	if len(rows) > 0 {
+		c.RowCount = rows[0]
+	}
+	return nil
+}
+
+// boundTime 按order取分表第一条记录的入库时间，last不为空时只在cond限定的范围内查找，没有记录时返回last
+func (c *ShardCatalog) boundTime(db *gorm.DB, cond string, last *time.Time, order string) (*time.Time, error) {
+	query := db.Table(c.Table)
+	if last != nil {
+		query = query.Where(cond, *last)
+	}
+	var times []time.Time
+	if err := query.Order(order).Limit(1).Pluck("created_at", &times).Error; err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", c.Table, err)
+	}
+	if len(times) == 0 {
+		return last, nil
+	}
+	return &times[0], nil
+}
+
+// SetShardStatus 修改分表状态，供归档、清理流程使用
+func SetShardStatus(db *gorm.DB, entity string, shard int, status string) error {
+	return db.Model(&ShardCatalog{}).
+		Where("entity = ? AND shard = ?", entity, shard).
+		Update("status", status).Error
+}
+
+// ShardTablesInRange 返回入库时间与[start, end]有交集的可查询分表，按分表序号升序
+// 零值表示不限制；活跃分表的最晚时间仍在变化，不按max_time裁剪，已封存分表的max_time随目录刷新更新
+func ShardTablesInRange(db *gorm.DB, entity string, start, end time.Time) ([]string, error) {
+	query := db.Model(&ShardCatalog{}).
+		Where("entity = ? AND status IN ?", entity, []string{ShardActive, ShardSealed})
+	if !end.IsZero() {
+		query = query.Where("min_time IS NULL OR min_time <= ?", end)
+	}
+	if !start.IsZero() {
+		query = query.Where("status = ? OR max_time >= ?", ShardActive, start)
+	}
+
+	var shards []*ShardCatalog
+	if err := query.Select("table_name", "ft_index").Order("shard").Find(&shards).Error; err != nil {
+		return nil, err
+	}
+	tables := make([]string, 0, len(shards))
+	for _, shard := range shards {
+		setFullTextReady(shard.Table, shard.FullText)
+		tables = append(tables, shard.Table)
+	}
+	return tables, nil
+}
+
+// ClosedShards 返回tables中已不再写入的分表：已封存且最晚入库时间早于ShardSealGrace之前
+// 活跃分表、尚未统计到时间范围的分表以及近期仍有迟到写入的分表都视为未关闭，
+// 游标翻页不能因其在某一页取完而在后续页跳过
+func ClosedShards(db *gorm.DB, tables []string) (map[string]bool, error) {
+	closed := make(map[string]bool)
+	if len(tables) == 0 {
+		return closed, nil
+	}
+	var names []string
+	err := db.Model(&ShardCatalog{}).
+		Where("table_name IN ? AND status = ? AND max_time IS NOT NULL AND max_time < ?",
+			tables, ShardSealed, time.Now().Add(-ShardSealGrace)).
+		Pluck("table_name", &names).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		closed[name] = true
+	}
+	return closed, nil
+}
+
+// PendingIndexShards 返回索引尚未建好的分表
+func PendingIndexShards(db *gorm.DB) ([]*ShardCatalog, error) {
+	var shards []*ShardCatalog
+	err := db.Where("ft_index = ? AND status IN ?", false, []string{ShardActive, ShardSealed}).Order("shard").Find(&shards).Error
+	return shards, err
+}
+
+// MarkShardIndexed 记录分表的索引已建好，此后关键字搜索可使用全文索引
+func MarkShardIndexed(db *gorm.DB, entity string, shard int) error {
+	return db.Model(&ShardCatalog{}).Where("entity = ? AND shard = ?", entity, shard).Update("ft_index", true).Error
+}
+
+// TableShard 从分表名<entity>_<shard>中解析分表序号
+func TableShard(table string) (int, bool) {
+	i := strings.LastIndexByte(table, '_')
+	if i < 0 {
+		return 0, false
+	}
+	shard, err := strconv.Atoi(table[i+1:])
+	return shard, err == nil
+}
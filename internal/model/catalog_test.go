@@ -0,0 +1,45 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClosedShards(t *testing.T) {
+	db := sqliteDB(t)
+	old := time.Now().Add(-2 * ShardSealGrace)
+	recent := time.Now().Add(-time.Minute)
+	shards := []*ShardCatalog{
+		{Entity: AuditLogName, Shard: 2, Table: "audit_log_2", Status: ShardSealed, MaxTime: &old},
+		{Entity: AuditLogName, Shard: 3, Table: "audit_log_3", Status: ShardSealed, MaxTime: &recent},
+		{Entity: AuditLogName, Shard: 4, Table: "audit_log_4", Status: ShardSealed},
+		{Entity: AuditLogName, Shard: 5, Table: "audit_log_5", Status: ShardActive, MaxTime: &old},
+	}
+	require.NoError(t, db.Create(shards).Error)
+
+	closed, err := ClosedShards(db, []string{"audit_log_1", "audit_log_2", "audit_log_3", "audit_log_4", "audit_log_5"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"audit_log_2": true}, closed)
+}
+
+func TestQueryShardPage_ExhaustedOnlyClosed(t *testing.T) {
+	db := sqliteDB(t)
+	old := time.Now().Add(-2 * ShardSealGrace)
+	require.NoError(t, db.Create(&ShardCatalog{Entity: AuditLogName, Shard: 2, Table: "audit_log_2", Status: ShardSealed, MaxTime: &old}).Error)
+	require.NoError(t, db.Exec("CREATE TABLE audit_log_2 AS SELECT * FROM audit_log_1").Error)
+	insertLog(t, db, "a_1", "t", old)
+
+	// 两张分表都已取完，只有已封存且不再写入的audit_log_2可以跳过，活跃的audit_log_1之后仍可能有新记录
+	page, err := QueryShardPage(context.Background(), db, ShardQuery{
+		Tables:    []string{"audit_log_1", "audit_log_2"},
+		SortField: "created_at",
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a_1"}, logIds(page.Logs))
+	assert.Equal(t, []string{"audit_log_2"}, page.Exhausted)
+}
@@ -0,0 +1,230 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 哈希链校验失败原因
+const (
+	ChainMissing          = "missing"            // 序号缺失，记录被删除或未落库
+	ChainHashMismatch     = "hash_mismatch"      // 记录内容被修改
+	ChainPrevHashMismatch = "prev_hash_mismatch" // 前驱哈希不一致，链被篡改或重排
+)
+
+const chainVerifyBatch = 1000
+
+// ChainHead 租户哈希链头，记录已分配的最大序号及其哈希
+type ChainHead struct {
+	TenantID  string    `gorm:"column:tenant_id;primaryKey;type:varchar(128)" json:"tenant_id"`
+	Seq       int64     `gorm:"column:seq;not null" json:"seq"`
+	Hash      string    `gorm:"column:hash;type:char(64)" json:"hash"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (h *ChainHead) TableName() string {
+	return "audit_chain_head"
+}
+
+// LockChainHeads 在事务中锁定租户链头，不存在则先创建
+// 按租户ID排序加锁，避免多副本并发时死锁
+func LockChainHeads(tx *gorm.DB, tenants []string) (map[string]*ChainHead, error) {
+	sort.Strings(tenants)
+	heads := make([]*ChainHead, 0, len(tenants))
+	for _, tenant := range tenants {
+		heads = append(heads, &ChainHead{TenantID: tenant})
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&heads).Error; err != nil {
+		return nil, err
+	}
+
+	var locked []*ChainHead
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tenant_id IN ?", tenants).
+		Order("tenant_id").
+		Find(&locked).Error
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]*ChainHead, len(locked))
+	for _, head := range locked {
+		res[head.TenantID] = head
+	}
+	return res, nil
+}
+
+// SaveChainHeads 保存链头
+func SaveChainHeads(tx *gorm.DB, heads map[string]*ChainHead) error {
+	for _, head := range heads {
+		err := tx.Model(head).
+			Where("tenant_id = ?", head.TenantID).
+			Updates(map[string]any{"seq": head.Seq, "hash": head.Hash}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Canonical 记录的规范化序列化，参与哈希计算的字段按固定顺序编码
+// 不包含自增ID和入库时间等由数据库生成的字段
+func (log *AuditLog) Canonical() []byte {
+	data, _ := json.Marshal([]any{
+		log.LogId, log.TenantID, log.UserID, log.Username, log.Action,
+		log.ResourceType, log.ResourceID, log.ResourceName, log.Result, log.Message,
+		log.TimeStamp, log.ClientIP, log.Module, log.TraceID, log.IdempotencyKey, log.Seq,
+	})
+	return data
+}
+
+// ComputeHash 计算 H(prev_hash || canonical(record))
+func (log *AuditLog) ComputeHash() string {
+	h := sha256.New()
+	h.Write([]byte(log.PrevHash))
+	h.Write(log.Canonical())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChainVerifyResult 哈希链校验结果
+type ChainVerifyResult struct {
+	TenantID    string `json:"tenant_id"`
+	Valid       bool   `json:"valid"`
+	Checked     int64  `json:"checked"`                 // 已校验的记录数
+	LastSeq     int64  `json:"last_seq"`                // 最后一条连续有效记录的序号
+	HeadSeq     int64  `json:"head_seq"`                // 链头已分配的序号
+	BrokenSeq   int64  `json:"broken_seq,omitempty"`    // 首个断裂位置
+	BrokenLogId string `json:"broken_log_id,omitempty"` // 首个断裂位置的日志ID
+	BrokenTable string `json:"broken_table,omitempty"`  // 首个断裂位置所在分表
+	Reason      string `json:"reason,omitempty"`
+}
+
+// VerifyChain 按序号遍历租户在所有分表中的记录，报告第一个缺失或被篡改的位置
+// 尚在管道中未落库的记录也会被报告为缺失，校验应在写入静默后进行
+func VerifyChain(ctx context.Context, db *gorm.DB, tenantID string) (*ChainVerifyResult, error) {
+	tables, err := ShardTables(db, AuditLogName)
+	if err != nil {
+		return nil, err
+	}
+
+	iters := make([]*chainIterator, 0, len(tables))
+	for _, table := range tables {
+		if !db.Migrator().HasTable(table) || !db.Migrator().HasColumn(table, "seq") {
+			continue
+		}
+		iters = append(iters, &chainIterator{db: db.WithContext(ctx), table: table, tenantID: tenantID})
+	}
+
+	res := &ChainVerifyResult{TenantID: tenantID, Valid: true}
+	head := &ChainHead{}
+	if err := db.WithContext(ctx).Where("tenant_id = ?", tenantID).Limit(1).Find(head).Error; err != nil {
+		return nil, err
+	}
+	res.HeadSeq = head.Seq
+
+	prevHash := ""
+	for {
+		// 多路归并：取各分表中序号最小的记录
+		var next *chainIterator
+		for _, it := range iters {
+			log, err := it.peek()
+			if err != nil {
+				return nil, err
+			}
+			if log != nil && (next == nil || log.Seq < next.current().Seq) {
+				next = it
+			}
+		}
+		if next == nil {
+			break
+		}
+		log, table := next.current(), next.table
+		next.advance()
+
+		// 同一条记录重复入库(如失败重试)时跳过
+		if log.Seq == res.LastSeq && log.Hash == prevHash {
+			continue
+		}
+		res.Checked++
+
+		switch {
+		case log.Seq != res.LastSeq+1:
+			res.fail(res.LastSeq+1, "", table, ChainMissing)
+		case log.PrevHash != prevHash:
+			res.fail(log.Seq, log.LogId, table, ChainPrevHashMismatch)
+		case log.ComputeHash() != log.Hash:
+			res.fail(log.Seq, log.LogId, table, ChainHashMismatch)
+		}
+		if !res.Valid {
+			return res, nil
+		}
+		res.LastSeq = log.Seq
+		prevHash = log.Hash
+	}
+
+	// 链尾缺失：链头序号大于已落库的最大序号
+	if res.HeadSeq > res.LastSeq {
+		res.fail(res.LastSeq+1, "", "", ChainMissing)
+	}
+	return res, nil
+}
+
+func (r *ChainVerifyResult) fail(seq int64, logId, table, reason string) {
+	r.Valid = false
+	r.BrokenSeq = seq
+	r.BrokenLogId = logId
+	r.BrokenTable = table
+	r.Reason = reason
+}
+
+// chainIterator 按序号分批读取单个分表中租户的记录
+type chainIterator struct {
+	db       *gorm.DB
+	table    string
+	tenantID string
+	buf      []*AuditLog
+	lastSeq  int64
+	done     bool
+}
+
+func (it *chainIterator) peek() (*AuditLog, error) {
+	if len(it.buf) > 0 {
+		return it.buf[0], nil
+	}
+	if it.done {
+		return nil, nil
+	}
+
+	err := it.db.Table(it.table).
+		Where("tenant_id = ? AND seq > ?", it.tenantID, it.lastSeq).
+		Order("seq").
+		Limit(chainVerifyBatch).
+		Find(&it.buf).Error
+	if err != nil {
+		return nil, fmt.Errorf("read chain from %s: %w", it.table, err)
+	}
+	if len(it.buf) < chainVerifyBatch {
+		it.done = true
+	}
+	if len(it.buf) == 0 {
+		return nil, nil
+	}
+	it.lastSeq = it.buf[len(it.buf)-1].Seq
+	return it.buf[0], nil
+}
+
+func (it *chainIterator) current() *AuditLog {
+	return it.buf[0]
+}
+
+func (it *chainIterator) advance() {
+	it.buf = it.buf[1:]
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// linkLogs 按哈希链钩子的方式为租户t的记录入链，返回未落库的记录
+func linkLogs(t *testing.T, db *gorm.DB, ids ...string) []*AuditLog {
+	t.Helper()
+	logs := make([]*AuditLog, 0, len(ids))
+	err := db.Transaction(func(tx *gorm.DB) error {
+		heads, err := LockChainHeads(tx, []string{"t"})
+		if err != nil {
+			return err
+		}
+		head := heads["t"]
+		for _, id := range ids {
+			head.Seq++
+			log := &AuditLog{LogId: id, TenantID: "t", Action: "login", Seq: head.Seq, PrevHash: head.Hash, CreatedAt: time.Now()}
+			log.Hash = log.ComputeHash()
+			head.Hash = log.Hash
+			logs = append(logs, log)
+		}
+		return SaveChainHeads(tx, heads)
+	})
+	require.NoError(t, err)
+	return logs
+}
+
+func saveLogs(t *testing.T, db *gorm.DB, logs []*AuditLog) {
+	t.Helper()
+	require.NoError(t, db.Table("audit_log_1").Create(logs).Error)
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(t *testing.T, db *gorm.DB)
+		valid  bool
+		broken int64
+		reason string
+	}{
+		{
+			name: "intact",
+			setup: func(t *testing.T, db *gorm.DB) {
+				saveLogs(t, db, linkLogs(t, db, "a", "b", "c"))
+			},
+			valid: true,
+		},
+		{
+			name: "gap",
+			setup: func(t *testing.T, db *gorm.DB) {
+				logs := linkLogs(t, db, "a", "b", "c")
+				saveLogs(t, db, []*AuditLog{logs[0], logs[2]})
+			},
+			broken: 2,
+			reason: ChainMissing,
+		},
+		{
+			name: "tampered row",
+			setup: func(t *testing.T, db *gorm.DB) {
+				saveLogs(t, db, linkLogs(t, db, "a", "b", "c"))
+				require.NoError(t, db.Table("audit_log_1").Where("log_id = ?", "b").Update("action", "delete").Error)
+			},
+			broken: 2,
+			reason: ChainHashMismatch,
+		},
+		{
+			name: "missing tail",
+			setup: func(t *testing.T, db *gorm.DB) {
+				logs := linkLogs(t, db, "a", "b")
+				saveLogs(t, db, logs[:1])
+			},
+			broken: 2,
+			reason: ChainMissing,
+		},
+		{
+			// 被保留的批次在之后的批次导出后才落库，序号不变，落库后链完整
+			name: "held batch exported later",
+			setup: func(t *testing.T, db *gorm.DB) {
+				held := linkLogs(t, db, "a", "b")
+				saveLogs(t, db, linkLogs(t, db, "c"))
+				res, err := VerifyChain(context.Background(), db, "t")
+				require.NoError(t, err)
+				require.False(t, res.Valid)
+				require.Equal(t, int64(1), res.BrokenSeq)
+				saveLogs(t, db, held)
+			},
+			valid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := sqliteDB(t)
+			require.NoError(t, db.AutoMigrate(&ChainHead{}))
+			tt.setup(t, db)
+
+			res, err := VerifyChain(context.Background(), db, "t")
+			require.NoError(t, err)
+			assert.Equal(t, tt.valid, res.Valid, fmt.Sprintf("%+v", res))
+			assert.Equal(t, tt.broken, res.BrokenSeq)
+			assert.Equal(t, tt.reason, res.Reason)
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package model
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"codexie.com/auditlog/pkg/merkle"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	checkpointLeafBatch = 5000
+	checkpointNodeBatch = 1000
+)
+
+// Checkpoint 租户在一个时间窗口内封存的记录的默克尔根及其签名
+// 窗口按入库时间(created_at)划分，客户端上报的时间戳不可信；窗口封存后才入库的迟到记录由后续窗口封存
+// 封存时保存整棵树(CheckpointNode)，证明只读取路径上的节点，不随窗口数据变化
+type Checkpoint struct {
+	Id          int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	TenantID    string    `gorm:"column:tenant_id;type:varchar(128);uniqueIndex:idx_tenant_window,priority:1" json:"tenant_id"`
+	WindowStart time.Time `gorm:"column:window_start;uniqueIndex:idx_tenant_window,priority:2" json:"window_start"`
+	WindowEnd   time.Time `gorm:"column:window_end;index" json:"window_end"`
+	LeafCount   int64     `gorm:"column:leaf_count;not null" json:"leaf_count"`
+	Root        string    `gorm:"column:root;type:char(64)" json:"root"`
+	Signature   string    `gorm:"column:signature;type:varchar(128)" json:"signature"` // base64编码的Ed25519签名
+	KeyID       string    `gorm:"column:key_id;type:varchar(16)" json:"key_id"`        // 签名公钥指纹，便于轮换密钥
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+
+	Nodes []*CheckpointNode `gorm:"-" json:"-"` // 待保存的树节点，由NewCheckpoint生成
+}
+
+func (c *Checkpoint) TableName() string {
+	return "audit_checkpoint"
+}
+
+// CheckpointNode 检查点默克尔树的节点，level为0时是叶子，按log_id排序并记录对应的日志
+// 日志一经封存即有叶子，后续窗口不再封存同一日志
+type CheckpointNode struct {
+	CheckpointID int64  `gorm:"column:checkpoint_id;primaryKey;autoIncrement:false" json:"checkpoint_id"`
+	Level        int    `gorm:"column:level;primaryKey;autoIncrement:false" json:"level"`
+	Idx          int64  `gorm:"column:idx;primaryKey;autoIncrement:false" json:"idx"`
+	Hash         string `gorm:"column:hash;type:char(64)" json:"hash"`
+	LogId        string `gorm:"column:log_id;type:varchar(64);index" json:"log_id,omitempty"` // 仅叶子有值
+}
+
+func (n *CheckpointNode) TableName() string {
+	return "audit_checkpoint_node"
+}
+
+// NewCheckpoint 由叶子构建默克尔树，生成未签名的检查点及其全部节点
+func NewCheckpoint(tenantID string, start, end time.Time, leaves []CheckpointLeaf) *Checkpoint {
+	tree := CheckpointTree(leaves)
+	cp := &Checkpoint{
+		TenantID:    tenantID,
+		WindowStart: start,
+		WindowEnd:   end,
+		LeafCount:   int64(len(leaves)),
+		Root:        hex.EncodeToString(tree.Root()),
+	}
+	for level, hashes := range tree.Levels() {
+		for i, hash := range hashes {
+			node := &CheckpointNode{Level: level, Idx: int64(i), Hash: hex.EncodeToString(hash)}
+			if level == 0 {
+				node.LogId = leaves[i].LogId
+			}
+			cp.Nodes = append(cp.Nodes, node)
+		}
+	}
+	return cp
+}
+
+// SignedMessage 参与签名的内容，窗口边界使用毫秒时间戳
+func (c *Checkpoint) SignedMessage() []byte {
+	return []byte(fmt.Sprintf("auditlog-checkpoint:v1|%s|%d|%d|%d|%s",
+		c.TenantID, c.WindowStart.UnixMilli(), c.WindowEnd.UnixMilli(), c.LeafCount, c.Root))
+}
+
+// Sign 使用私钥对检查点签名
+func (c *Checkpoint) Sign(key ed25519.PrivateKey) {
+	c.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, c.SignedMessage()))
+	c.KeyID = KeyID(key.Public().(ed25519.PublicKey))
+}
+
+// KeyID 公钥指纹，取SHA-256前8字节
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// CheckpointProgress 检查点生成进度，只有一行，记录已处理的最大窗口结束时间
+// 没有数据的窗口不生成检查点，通过进度记录避免重复扫描
+type CheckpointProgress struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement:false" json:"id"`
+	WindowEnd time.Time `gorm:"column:window_end" json:"window_end"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (c *CheckpointProgress) TableName() string {
+	return "audit_checkpoint_progress"
+}
+
+// LatestCheckpointEnd 返回已处理的最大窗口结束时间，取进度与已生成检查点中较大的值，都没有时返回零值
+func LatestCheckpointEnd(db *gorm.DB) (time.Time, error) {
+	progress := &CheckpointProgress{}
+	if err := db.Limit(1).Find(progress).Error; err != nil {
+		return time.Time{}, err
+	}
+	cp := &Checkpoint{}
+	if err := db.Order("window_end DESC").Limit(1).Find(cp).Error; err != nil {
+		return time.Time{}, err
+	}
+	if cp.WindowEnd.After(progress.WindowEnd) {
+		return cp.WindowEnd, nil
+	}
+	return progress.WindowEnd, nil
+}
+
+// FindCheckpointLeaf 查找日志在检查点中的叶子，日志尚未封存时返回nil
+func FindCheckpointLeaf(db *gorm.DB, logId string) (*CheckpointNode, error) {
+	var nodes []*CheckpointNode
+	err := db.Where("log_id = ? AND level = 0", logId).Limit(1).Find(&nodes).Error
+	if err != nil || len(nodes) == 0 {
+		return nil, err
+	}
+	return nodes[0], nil
+}
+
+// CheckpointProof 读取检查点中第index个叶子的证明路径，只访问路径上的节点
+func CheckpointProof(db *gorm.DB, cp *Checkpoint, index int64) ([]merkle.ProofNode, error) {
+	positions, err := merkle.ProofPositions(int(cp.LeafCount), int(index))
+	if err != nil {
+		return nil, err
+	}
+	if len(positions) == 0 {
+		return []merkle.ProofNode{}, nil
+	}
+	keys := make([][]any, 0, len(positions))
+	for _, pos := range positions {
+		keys = append(keys, []any{pos.Level, pos.Index})
+	}
+	var nodes []*CheckpointNode
+	if err := db.Where("checkpoint_id = ? AND (level, idx) IN ?", cp.Id, keys).Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	hashes := make(map[[2]int64]string, len(nodes))
+	for _, node := range nodes {
+		hashes[[2]int64{int64(node.Level), node.Idx}] = node.Hash
+	}
+
+	proof := make([]merkle.ProofNode, 0, len(positions))
+	for _, pos := range positions {
+		hash, err := hex.DecodeString(hashes[[2]int64{int64(pos.Level), int64(pos.Index)}])
+		if err != nil || len(hash) == 0 {
+			return nil, fmt.Errorf("checkpoint %d: node (%d, %d) is missing or invalid", cp.Id, pos.Level, pos.Index)
+		}
+		proof = append(proof, merkle.ProofNode{Hash: hash, Left: pos.Left})
+	}
+	return proof, nil
+}
+
+// SaveCheckpoints 在一个事务中保存同一窗口的所有检查点及其树节点并推进进度，已存在的检查点不覆盖
+// cps为空时只推进进度，表示该窗口没有需要封存的数据
+func SaveCheckpoints(db *gorm.DB, cps []*Checkpoint, windowEnd time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, cp := range cps {
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(cp)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				continue
+			}
+			for _, node := range cp.Nodes {
+				node.CheckpointID = cp.Id
+			}
+			if err := tx.CreateInBatches(cp.Nodes, checkpointNodeBatch).Error; err != nil {
+				return err
+			}
+		}
+
+		res := tx.Model(&CheckpointProgress{}).Where("id = ? AND window_end < ?", 1, windowEnd).Update("window_end", windowEnd)
+		if res.Error != nil || res.RowsAffected > 0 {
+			return res.Error
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&CheckpointProgress{Id: 1, WindowEnd: windowEnd}).Error
+	})
+}
+
+// unsealed 只保留尚未被任何检查点封存的记录
+func unsealed(table string) string {
+	return fmt.Sprintf("NOT EXISTS (SELECT 1 FROM audit_checkpoint_node n WHERE n.log_id = %s.log_id AND n.level = 0)", table)
+}
+
+// CheckpointTenants 返回入库时间在[start, end)内、有尚未封存记录的租户
+func CheckpointTenants(ctx context.Context, db *gorm.DB, start, end time.Time) ([]string, error) {
+	tables, err := ShardTablesInRange(db, AuditLogName, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, table := range tables {
+		if !db.Migrator().HasTable(table) {
+			continue
+		}
+		var tenants []string
+		err := db.WithContext(ctx).Table(table).
+			Where("created_at >= ? AND created_at < ?", start, end).
+			Where(unsealed(table)).
+			Distinct("tenant_id").
+			Pluck("tenant_id", &tenants).Error
+		if err != nil {
+			return nil, fmt.Errorf("read tenants from %s: %w", table, err)
+		}
+		for _, tenant := range tenants {
+			seen[tenant] = true
+		}
+	}
+
+	res := make([]string, 0, len(seen))
+	for tenant := range seen {
+		res = append(res, tenant)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// CheckpointLeaf 默克尔树叶子
+type CheckpointLeaf struct {
+	LogId string
+	Hash  []byte
+}
+
+// CheckpointLeaves 读取租户入库时间在[start, end)内、尚未封存的记录，按log_id排序生成叶子
+// 叶子哈希为 H(0x00 || canonical(record))，重复入库的同一日志只保留一条
+func CheckpointLeaves(ctx context.Context, db *gorm.DB, tenantID string, start, end time.Time) ([]CheckpointLeaf, error) {
+	tables, err := ShardTablesInRange(db, AuditLogName, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	leaves := make([]CheckpointLeaf, 0)
+	seen := make(map[string]bool)
+	for _, table := range tables {
+		if !db.Migrator().HasTable(table) {
+			continue
+		}
+		lastId := 0
+		for {
+			var logs []*AuditLog
+			err := db.WithContext(ctx).Table(table).
+				Where("tenant_id = ? AND created_at >= ? AND created_at < ? AND id > ?", tenantID, start, end, lastId).
+				Where(unsealed(table)).
+				Order("id").
+				Limit(checkpointLeafBatch).
+				Find(&logs).Error
+			if err != nil {
+				return nil, fmt.Errorf("read leaves from %s: %w", table, err)
+			}
+			for _, log := range logs {
+				if seen[log.LogId] {
+					continue
+				}
+				seen[log.LogId] = true
+				leaves = append(leaves, CheckpointLeaf{LogId: log.LogId, Hash: merkle.LeafHash(log.Canonical())})
+			}
+			if len(logs) < checkpointLeafBatch {
+				break
+			}
+			lastId = logs[len(logs)-1].Id
+		}
+	}
+
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].LogId < leaves[j].LogId })
+	return leaves, nil
+}
+
+// CheckpointTree 由叶子构建默克尔树
+func CheckpointTree(leaves []CheckpointLeaf) *merkle.Tree {
+	hashes := make([][]byte, 0, len(leaves))
+	for _, leaf := range leaves {
+		hashes = append(hashes, leaf.Hash)
+	}
+	return merkle.New(hashes)
+}
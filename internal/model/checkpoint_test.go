@@ -0,0 +1,163 @@
+package model
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/pkg/merkle"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// auditLogDDL 测试用分表结构，sqlite不支持AuditLog声明的全文索引，因此手写建表语句
+const auditLogDDL = `CREATE TABLE %s (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	log_id TEXT, tenant_id TEXT, user_id TEXT, username TEXT, action TEXT,
+	resource_type TEXT, resource_id TEXT, resource_name TEXT, result TEXT, message TEXT,
+	timestamp INTEGER, client_ip TEXT, module TEXT, trace_id TEXT, idempotency_key TEXT,
+	seq INTEGER, prev_hash TEXT, hash TEXT, created_at DATETIME, updated_at DATETIME)`
+
+// sqliteDB 创建内存数据库，包含检查点相关表和一张已登记的活跃分表audit_log_1
+func sqliteDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	require.NoError(t, db.AutoMigrate(&ShardCatalog{}, &Checkpoint{}, &CheckpointNode{}, &CheckpointProgress{}))
+	require.NoError(t, db.Exec(fmt.Sprintf(auditLogDDL, "audit_log_1")).Error)
+	require.NoError(t, RegisterShard(db, AuditLogName, 1))
+	return db
+}
+
+func insertLog(t *testing.T, db *gorm.DB, logId, tenant string, createdAt time.Time) *AuditLog {
+	t.Helper()
+	log := &AuditLog{LogId: logId, TenantID: tenant, Action: "login", Result: "success", CreatedAt: createdAt}
+	require.NoError(t, db.Table("audit_log_1").Create(log).Error)
+	return log
+}
+
+// seal 按检查点任务的方式封存[start, end)及其前lateness内尚未封存的记录
+func seal(t *testing.T, db *gorm.DB, start, end time.Time, lateness time.Duration) []*Checkpoint {
+	t.Helper()
+	ctx := context.Background()
+	tenants, err := CheckpointTenants(ctx, db, start.Add(-lateness), end)
+	require.NoError(t, err)
+	var cps []*Checkpoint
+	for _, tenant := range tenants {
+		leaves, err := CheckpointLeaves(ctx, db, tenant, start.Add(-lateness), end)
+		require.NoError(t, err)
+		cps = append(cps, NewCheckpoint(tenant, start, end, leaves))
+	}
+	require.NoError(t, SaveCheckpoints(db, cps, end))
+	return cps
+}
+
+// prove 读取存储的证明并校验到检查点的根
+func prove(t *testing.T, db *gorm.DB, log *AuditLog) (*Checkpoint, bool) {
+	t.Helper()
+	leaf, err := FindCheckpointLeaf(db, log.LogId)
+	require.NoError(t, err)
+	require.NotNil(t, leaf, "log %s not sealed", log.LogId)
+	cp := &Checkpoint{}
+	require.NoError(t, db.First(cp, leaf.CheckpointID).Error)
+
+	path, err := CheckpointProof(db, cp, leaf.Idx)
+	require.NoError(t, err)
+	root, err := hex.DecodeString(cp.Root)
+	require.NoError(t, err)
+	hash := merkle.LeafHash(log.Canonical())
+	return cp, hex.EncodeToString(hash) == leaf.Hash && merkle.Verify(hash, path, root)
+}
+
+func TestCheckpoint_SealAndProve(t *testing.T) {
+	db := sqliteDB(t)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	var logs []*AuditLog
+	for i := 0; i < 5; i++ {
+		logs = append(logs, insertLog(t, db, fmt.Sprintf("log-a%d", i), "a", start.Add(time.Duration(i)*time.Minute)))
+	}
+	logs = append(logs, insertLog(t, db, "log-b0", "b", start.Add(time.Minute)))
+	outside := insertLog(t, db, "log-a9", "a", end)
+
+	cps := seal(t, db, start, end, 0)
+	require.Len(t, cps, 2)
+	assert.Equal(t, int64(5), cps[0].LeafCount)
+	assert.Equal(t, int64(1), cps[1].LeafCount)
+
+	for _, log := range logs {
+		cp, ok := prove(t, db, log)
+		assert.True(t, ok, log.LogId)
+		assert.Equal(t, log.TenantID, cp.TenantID)
+	}
+	leaf, err := FindCheckpointLeaf(db, outside.LogId)
+	require.NoError(t, err)
+	assert.Nil(t, leaf)
+
+	// 同一窗口重复保存不覆盖已有检查点，也不重复写入节点
+	require.NoError(t, SaveCheckpoints(db, []*Checkpoint{NewCheckpoint("a", start, end, nil)}, end))
+	var count int64
+	require.NoError(t, db.Model(&CheckpointNode{}).Where("level = 0").Count(&count).Error)
+	assert.Equal(t, int64(6), count)
+
+	latest, err := LatestCheckpointEnd(db)
+	require.NoError(t, err)
+	assert.True(t, latest.Equal(end))
+}
+
+func TestCheckpoint_LateRecord(t *testing.T) {
+	db := sqliteDB(t)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := insertLog(t, db, "log-1", "a", start.Add(time.Minute))
+	seal(t, db, start, start.Add(time.Hour), 0)
+
+	// 窗口封存后才落库、入库时间仍落在已封存窗口内的记录
+	late := insertLog(t, db, "log-0", "a", start.Add(2*time.Minute))
+	cp, ok := prove(t, db, first)
+	assert.True(t, ok, "late record must not invalidate the sealed window")
+	assert.Equal(t, int64(1), cp.LeafCount)
+
+	cps := seal(t, db, start.Add(time.Hour), start.Add(2*time.Hour), time.Hour)
+	require.Len(t, cps, 1)
+	assert.Equal(t, int64(1), cps[0].LeafCount)
+	cp, ok = prove(t, db, late)
+	assert.True(t, ok)
+	assert.True(t, cp.WindowStart.Equal(start.Add(time.Hour)))
+
+	// 超过迟到时间的记录不再封存
+	insertLog(t, db, "log-old", "a", start.Add(-time.Minute))
+	cps = seal(t, db, start.Add(2*time.Hour), start.Add(3*time.Hour), time.Hour)
+	assert.Empty(t, cps)
+}
+
+func TestCheckpoint_Tampered(t *testing.T) {
+	db := sqliteDB(t)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var logs []*AuditLog
+	for i := 0; i < 3; i++ {
+		logs = append(logs, insertLog(t, db, fmt.Sprintf("log-%d", i), "a", start.Add(time.Duration(i)*time.Minute)))
+	}
+	seal(t, db, start, start.Add(time.Hour), 0)
+
+	require.NoError(t, db.Table("audit_log_1").Where("log_id = ?", "log-1").Update("result", "fail").Error)
+	tampered := &AuditLog{}
+	require.NoError(t, db.Table("audit_log_1").Where("log_id = ?", "log-1").First(tampered).Error)
+	_, ok := prove(t, db, tampered)
+	assert.False(t, ok)
+
+	// 修改其他记录不影响未修改记录的证明
+	_, ok = prove(t, db, logs[0])
+	assert.True(t, ok)
+}
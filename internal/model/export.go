@@ -0,0 +1,181 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"codexie.com/auditlog/pkg/cursor"
+	"gorm.io/gorm"
+)
+
+// 导出任务状态
+const (
+	ExportPending   = "pending"   // 等待执行
+	ExportRunning   = "running"   // 执行中，由lease_holder持有
+	ExportSucceeded = "succeeded" // 已完成，文件可下载
+	ExportFailed    = "failed"    // 执行失败
+	ExportCanceled  = "canceled"  // 已取消
+)
+
+// ExportColumns 导出文件的默认列及顺序，与AuditLog的json字段名一致
+var ExportColumns = []string{
+	"log_id", "tenant_id", "user_id", "username", "action", "resource_type", "resource_id", "resource_name",
+	"result", "message", "timestamp", "client_ip", "module", "trace_id", "idempotency_key",
+	"seq", "prev_hash", "hash", "created_at", "updated_at",
+}
+
+var ErrExportLeaseLost = errors.New("export job lease lost")
+
+// ExportJob 异步导出任务，执行实例通过租约持有任务，租约过期后可被其他实例重新领取
+type ExportJob struct {
+	Id          int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TaskID      string     `gorm:"column:task_id;type:varchar(64);uniqueIndex" json:"task_id"` // 对外的任务ID
+	TenantID    string     `gorm:"column:tenant_id;type:varchar(64);index:idx_export_tenant,priority:1" json:"tenant_id"`
+	UserID      string     `gorm:"column:user_id;type:varchar(64)" json:"user_id"`             // 提交人
+	Filter      string     `gorm:"column:filter;type:text" json:"filter"`                      // 查询条件DSL(JSON)，已包含租户等条件
+	Keyword     string     `gorm:"column:keyword;type:varchar(255)" json:"keyword"`            // 关键字搜索
+	Fields      string     `gorm:"column:fields;type:varchar(512)" json:"fields"`              // 导出的列，逗号分隔，为空时导出全部列
+	Format      string     `gorm:"column:format;type:varchar(16)" json:"format"`               // csv/ndjson/xlsx/parquet
+	CallbackURL string     `gorm:"column:callback_url;type:varchar(1024)" json:"callback_url"` // 完成后的回调地址
+	Status      string     `gorm:"column:status;type:varchar(16);index:idx_export_status" json:"status"`
+	Progress    int64      `gorm:"column:progress" json:"progress"`                   // 已写出的行数
+	Total       int64      `gorm:"column:total" json:"total"`                         // 开始执行时统计的总行数
+	FileKey     string     `gorm:"column:file_key;type:varchar(255)" json:"file_key"` // 存储中的文件位置
+	FileSize    int64      `gorm:"column:file_size" json:"file_size"`
+	Error       string     `gorm:"column:error;type:text" json:"error"`
+	Attempts    int        `gorm:"column:attempts" json:"attempts"` // 已领取执行的次数
+	LeaseHolder string     `gorm:"column:lease_holder;type:varchar(128)" json:"lease_holder"`
+	LeaseUntil  *time.Time `gorm:"column:lease_until;index:idx_export_status" json:"lease_until"`
+	StartedAt   *time.Time `gorm:"column:started_at" json:"started_at"`
+	FinishedAt  *time.Time `gorm:"column:finished_at" json:"finished_at"`
+	ExpiresAt   *time.Time `gorm:"column:expires_at;index" json:"expires_at"` // 任务结束后的保留截止时间，过期后删除文件和任务
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime;index:idx_export_tenant,priority:2" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (*ExportJob) TableName() string {
+	return "export_job"
+}
+
+// ClaimExportJobs 领取待执行或租约已过期的任务，最多limit个
+// 先查询候选再逐个以条件更新抢占，多实例并发领取时每个任务只会被一个实例抢到
+func ClaimExportJobs(db *gorm.DB, holder string, lease time.Duration, limit int) ([]*ExportJob, error) {
+	now := time.Now()
+	var candidates []*ExportJob
+	if err := db.Where("status = ? OR (status = ? AND lease_until < ?)", ExportPending, ExportRunning, now).
+		Order("id").Limit(limit).Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	claimed := make([]*ExportJob, 0, len(candidates))
+	for _, job := range candidates {
+		until := now.Add(lease)
+		res := db.Model(&ExportJob{}).
+			Where("id = ? AND status = ? AND attempts = ?", job.Id, job.Status, job.Attempts).
+			Updates(map[string]any{
+				"status":       ExportRunning,
+				"lease_holder": holder,
+				"lease_until":  until,
+				"attempts":     gorm.Expr("attempts + 1"),
+				"progress":     0,
+			})
+		if res.Error != nil {
+			return claimed, res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		job.Status, job.LeaseHolder, job.LeaseUntil, job.Progress = ExportRunning, holder, &until, 0
+		job.Attempts++
+		claimed = append(claimed, job)
+	}
+	return claimed, nil
+}
+
+// RenewExportLease 续约并同步进度，任务已被取消或被其他实例领取时返回ErrExportLeaseLost
+func RenewExportLease(db *gorm.DB, job *ExportJob, progress, total int64, lease time.Duration) error {
+	res := db.Model(&ExportJob{}).
+		Where("id = ? AND status = ? AND lease_holder = ?", job.Id, ExportRunning, job.LeaseHolder).
+		Updates(map[string]any{"lease_until": time.Now().Add(lease), "progress": progress, "total": total})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrExportLeaseLost
+	}
+	return nil
+}
+
+// FinishExportJob 以终态结束任务，只有仍持有租约时才会生效
+func FinishExportJob(db *gorm.DB, job *ExportJob) error {
+	now := time.Now()
+	job.FinishedAt = &now
+	res := db.Model(&ExportJob{}).
+		Where("id = ? AND status = ? AND lease_holder = ?", job.Id, ExportRunning, job.LeaseHolder).
+		Updates(map[string]any{
+			"status":       job.Status,
+			"progress":     job.Progress,
+			"total":        job.Total,
+			"file_key":     job.FileKey,
+			"file_size":    job.FileSize,
+			"error":        job.Error,
+			"started_at":   job.StartedAt,
+			"finished_at":  job.FinishedAt,
+			"expires_at":   job.ExpiresAt,
+			"lease_holder": "",
+			"lease_until":  nil,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrExportLeaseLost
+	}
+	return nil
+}
+
+// CancelExportJob 取消等待或执行中的任务，执行实例在下次续约时发现租约丢失并停止写出
+// 任务已结束时返回false
+func CancelExportJob(db *gorm.DB, id int64, retention time.Duration) (bool, error) {
+	now := time.Now()
+	res := db.Model(&ExportJob{}).
+		Where("id = ? AND status IN ?", id, []string{ExportPending, ExportRunning}).
+		Updates(map[string]any{
+			"status":       ExportCanceled,
+			"finished_at":  now,
+			"expires_at":   now.Add(retention),
+			"lease_holder": "",
+			"lease_until":  nil,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+// ExpiredExportJobs 查询已过保留期的任务，执行中的任务没有过期时间
+func ExpiredExportJobs(db *gorm.DB, now time.Time, limit int) ([]*ExportJob, error) {
+	var jobs []*ExportJob
+	err := db.Where("expires_at < ?", now).Order("expires_at").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+// downloadToken 下载链接中的签名内容
+type downloadToken struct {
+	TaskID  string `json:"t"`
+	Expires int64  `json:"e"` // 过期时间戳(秒)
+}
+
+// DownloadToken 生成导出文件的下载令牌，持有令牌即可在过期前下载，无需登录
+func DownloadToken(key []byte, taskID string, expires time.Time) (string, error) {
+	return cursor.Encode(key, downloadToken{TaskID: taskID, Expires: expires.Unix()})
+}
+
+// ParseDownloadToken 校验下载令牌的签名和有效期，返回任务ID
+func ParseDownloadToken(key []byte, token string) (string, error) {
+	var t downloadToken
+	if err := cursor.Decode(key, token, &t); err != nil {
+		return "", err
+	}
+	if time.Now().Unix() > t.Expires {
+		return "", cursor.ErrInvalidCursor
+	}
+	return t.TaskID, nil
+}
@@ -14,12 +14,15 @@ var TaskPosNsp SchedulePos
 
 // SchedulePos taskPos
 type SchedulePos struct {
-	Id               uint64    `gorm:"column:id;primary_key;auto_increment"`
-	Name             string    `gorm:"column:name;uniqueIndex:idx_name;type:varchar(155)"`
-	ScheduleBeginPos int       `gorm:"column:schedule_begin_pos;not null"`
-	ScheduleEndPos   int       `gorm:"column:schedule_end_pos;not null"`
-	CreateTime       time.Time `gorm:"column:create_time;not null;autoCreateTime"`
-	ModifyTime       time.Time `gorm:"column:modify_time;not null;autoUpdateTime"`
+	Id               uint64     `gorm:"column:id;primary_key;auto_increment"`
+	Name             string     `gorm:"column:name;uniqueIndex:idx_name;type:varchar(155)"`
+	ScheduleBeginPos int        `gorm:"column:schedule_begin_pos;not null"`
+	ScheduleEndPos   int        `gorm:"column:schedule_end_pos;not null"`
+	Strategy         string     `gorm:"column:strategy;type:varchar(16);not null;default:rows"` // 分表策略
+	StartTime        *time.Time `gorm:"column:start_time"`                                      // 当前分表的起始时间
+	EndTime          *time.Time `gorm:"column:end_time"`                                        // 当前分表的结束时间，按行数分表时为空
+	CreateTime       time.Time  `gorm:"column:create_time;not null;autoCreateTime"`
+	ModifyTime       time.Time  `gorm:"column:modify_time;not null;autoUpdateTime"`
 }
 
 // TableName 表名
@@ -88,3 +91,13 @@ func (p *SchedulePos) GetPosList(db *gorm.DB) ([]*SchedulePos, error) {
 	}
 	return taskList, nil
 }
+
+// ShardStrategy 返回记录中的分表策略，用于路由和枚举分表，不依赖阈值等运行参数
+func (p *SchedulePos) ShardStrategy() (ShardStrategy, error) {
+	return NewShardStrategy(p.Strategy, 0, 0)
+}
+
+// ShardTables 返回分表目录中实体所有可查询分表的表名，按分表序号升序
+func ShardTables(db *gorm.DB, name string) ([]string, error) {
+	return ShardTablesInRange(db, name, time.Time{}, time.Time{})
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 关键字搜索方式
+const (
+	SearchFullText = "fulltext" // FULLTEXT ngram索引，需MySQL 5.7.6+
+	SearchLike     = "like"     // LIKE模糊匹配，无需索引但需全表扫描
+)
+
+// ngramTokenSize MySQL ngram_token_size的默认值，短于该长度的关键字无法通过全文索引命中
+const ngramTokenSize = 2
+
+// KeywordFields 参与关键字搜索的字段，与idx_ft_keyword索引的列一致
+var KeywordFields = []string{"message", "resource_name", "username"}
+
+// Keyword 返回关键字搜索条件，作用于单张分表
+// 全文搜索时按分表目录中记录的索引状态逐表决定，尚未建好全文索引的分表和过短的关键字退回LIKE
+func Keyword(mode, keyword string) clause.Expression {
+	return keywordExpr{mode: mode, keyword: keyword}
+}
+
+type keywordExpr struct {
+	mode    string
+	keyword string
+}
+
+func (k keywordExpr) Build(builder clause.Builder) {
+	if k.mode == SearchFullText && utf8.RuneCountInString(k.keyword) >= ngramTokenSize {
+		if stmt, ok := builder.(*gorm.Statement); ok && FullTextReady(stmt.Table) {
+			// 以短语方式匹配，去掉双引号避免破坏布尔模式语法
+			phrase := `"` + strings.ReplaceAll(k.keyword, `"`, " ") + `"`
+			clause.Expr{
+				SQL:  "MATCH(" + strings.Join(KeywordFields, ", ") + ") AGAINST (? IN BOOLEAN MODE)",
+				Vars: []any{phrase},
+			}.Build(builder)
+			return
+		}
+	}
+
+	conds := make([]string, 0, len(KeywordFields))
+	for _, field := range KeywordFields {
+		conds = append(conds, field+" LIKE @keyword")
+	}
+	clause.NamedExpr{
+		SQL:  "(" + strings.Join(conds, " OR ") + ")",
+		Vars: []any{sql.Named("keyword", "%"+EscapeLike(k.keyword)+"%")},
+	}.Build(builder)
+}
+
+// fullTextTables 已建好全文索引的分表，读取分表目录时更新
+var fullTextTables sync.Map
+
+// FullTextReady 分表是否已建好全文索引，未知的分表按未建好处理
+func FullTextReady(table string) bool {
+	ready, _ := fullTextTables.Load(table)
+	return ready == true
+}
+
+func setFullTextReady(table string, ready bool) {
+	fullTextTables.Store(table, ready)
+}
+
+// EscapeLike 转义LIKE中的通配符
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	return db
+}
+
+func TestKeyword_FullTextFallback(t *testing.T) {
+	db := dryRunDB(t)
+	setFullTextReady("audit_log_1", true)
+	setFullTextReady("audit_log_2", false)
+
+	tests := []struct {
+		name    string
+		table   string
+		mode    string
+		keyword string
+		match   bool
+	}{
+		{name: "indexed shard", table: "audit_log_1", mode: SearchFullText, keyword: "登录失败", match: true},
+		{name: "index not built", table: "audit_log_2", mode: SearchFullText, keyword: "登录失败"},
+		{name: "unknown shard", table: "audit_log_3", mode: SearchFullText, keyword: "登录失败"},
+		{name: "keyword shorter than ngram", table: "audit_log_1", mode: SearchFullText, keyword: "a"},
+		{name: "like mode", table: "audit_log_1", mode: SearchLike, keyword: "登录失败"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := db.Table(tt.table).Where(Keyword(tt.mode, tt.keyword)).Find(&[]*AuditLog{}).Statement
+			sql := stmt.SQL.String()
+			assert.Equal(t, tt.match, strings.Contains(sql, "MATCH("), sql)
+			assert.Equal(t, !tt.match, strings.Contains(sql, "LIKE"), sql)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 分表策略
+const (
+	ShardByRows    = "rows"    // 单表行数达到阈值后轮转，分表序号为1,2,3...
+	ShardByDay     = "daily"   // 按天分表，分表序号为YYYYMMDD
+	ShardByWeek    = "weekly"  // 按ISO周分表，分表序号为YYYYWW
+	ShardByMonth   = "monthly" // 按月分表，分表序号为YYYYMM
+	DefaultMaxRows = 30000000  // 按行数分表时单表最大记录数
+)
+
+// ShardStrategy 分表策略，决定数据写入哪张分表以及何时创建新分表
+// 分表序号始终为整数，日志ID以 <id>_<shard> 的形式携带分表序号用于路由
+type ShardStrategy interface {
+	Name() string
+	// First 返回实体首张分表的序号
+	First(now time.Time) int
+	// Route 返回时间t的数据应写入的分表序号，分表不一定已创建，写入前需调用EnsureShard
+	Route(pos *SchedulePos, t time.Time) int
+	// Rotate 推进分表位置，返回需要预先创建的分表序号，pos被修改时需由调用方保存
+	Rotate(db *gorm.DB, pos *SchedulePos, now time.Time) (create []int, changed bool, err error)
+	// Shards 返回已启用范围内的分表序号，按时间升序，其中的分表不一定已创建
+	Shards(pos *SchedulePos, now time.Time) []int
+}
+
+// NewShardStrategy 创建分表策略，maxRows仅对rows生效，preCreate仅对按时间分表生效
+func NewShardStrategy(name string, maxRows int64, preCreate int) (ShardStrategy, error) {
+	switch name {
+	case ShardByRows, "":
+		if maxRows <= 0 {
+			maxRows = DefaultMaxRows
+		}
+		return &rowsStrategy{maxRows: maxRows}, nil
+	case ShardByDay, ShardByWeek, ShardByMonth:
+		if preCreate <= 0 {
+			preCreate = 1
+		}
+		return &timeStrategy{unit: name, preCreate: preCreate}, nil
+	default:
+		return nil, fmt.Errorf("unknown shard strategy %q", name)
+	}
+}
+
+// rowsStrategy 按行数轮转，写入位置为ScheduleEndPos
+type rowsStrategy struct {
+	maxRows int64
+}
+
+func (s *rowsStrategy) Name() string { return ShardByRows }
+
+func (s *rowsStrategy) First(time.Time) int { return 1 }
+
+func (s *rowsStrategy) Route(pos *SchedulePos, _ time.Time) int {
+	return pos.ScheduleEndPos
+}
+
+func (s *rowsStrategy) Rotate(db *gorm.DB, pos *SchedulePos, now time.Time) ([]int, bool, error) {
+	tableName := fmt.Sprintf("%s_%d", pos.Name, pos.ScheduleEndPos)
+	var count int64
+	if err := db.Table(tableName).Count(&count).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to count records in %s: %w", tableName, err)
+	}
+	if count < s.maxRows {
+		return nil, false, nil
+	}
+	pos.ScheduleEndPos++
+	pos.StartTime = &now
+	return []int{pos.ScheduleEndPos}, true, nil
+}
+
+func (s *rowsStrategy) Shards(pos *SchedulePos, _ time.Time) []int {
+	shards := make([]int, 0, pos.ScheduleEndPos-pos.ScheduleBeginPos+1)
+	for i := pos.ScheduleBeginPos; i <= pos.ScheduleEndPos; i++ {
+		shards = append(shards, i)
+	}
+	return shards
+}
+
+// timeStrategy 按时间分桶，分桶使用本地时区
+type timeStrategy struct {
+	unit      string
+	preCreate int // 预先创建的后续分表数
+}
+
+func (s *timeStrategy) Name() string { return s.unit }
+
+func (s *timeStrategy) First(now time.Time) int { return s.shard(now) }
+
+// Route 按时间路由，超出已启用范围(如客户端时钟偏差)的数据写入当前分表
+func (s *timeStrategy) Route(pos *SchedulePos, t time.Time) int {
+	shard := s.shard(t)
+	if shard < pos.ScheduleBeginPos || shard > s.current(pos, time.Now()) {
+		return s.current(pos, time.Now())
+	}
+	return shard
+}
+
+func (s *timeStrategy) Rotate(_ *gorm.DB, pos *SchedulePos, now time.Time) ([]int, bool, error) {
+	cur := s.shard(now)
+	create := []int{cur}
+	for next, i := cur, 0; i < s.preCreate; i++ {
+		next = s.next(next)
+		create = append(create, next)
+	}
+	if cur == pos.ScheduleEndPos {
+		return create, false, nil
+	}
+
+	start, end := s.Bounds(cur)
+	pos.ScheduleEndPos = cur
+	pos.StartTime, pos.EndTime = &start, &end
+	return create, true, nil
+}
+
+func (s *timeStrategy) Shards(pos *SchedulePos, now time.Time) []int {
+	shards := make([]int, 0)
+	last := s.current(pos, now)
+	for shard := pos.ScheduleBeginPos; shard <= last; shard = s.next(shard) {
+		shards = append(shards, shard)
+	}
+	return shards
+}
+
+// current 当前写入的分表，位置尚未被轮转任务推进时以当前时间为准
+func (s *timeStrategy) current(pos *SchedulePos, now time.Time) int {
+	return max(pos.ScheduleEndPos, s.shard(now))
+}
+
+func (s *timeStrategy) shard(t time.Time) int {
+	t = t.In(time.Local)
+	switch s.unit {
+	case ShardByDay:
+		return t.Year()*10000 + int(t.Month())*100 + t.Day()
+	case ShardByWeek:
+		year, week := t.ISOWeek()
+		return year*100 + week
+	default:
+		return t.Year()*100 + int(t.Month())
+	}
+}
+
+func (s *timeStrategy) next(shard int) int {
+	_, end := s.Bounds(shard)
+	return s.shard(end)
+}
+
+// Bounds 返回分表的时间范围 [start, end)
+func (s *timeStrategy) Bounds(shard int) (start, end time.Time) {
+	switch s.unit {
+	case ShardByDay:
+		start = time.Date(shard/10000, time.Month(shard/100%100), shard%100, 0, 0, 0, 0, time.Local)
+		return start, start.AddDate(0, 0, 1)
+	case ShardByWeek:
+		// ISO周：1月4日所在的周为第1周，周一为一周的开始
+		jan4 := time.Date(shard/100, 1, 4, 0, 0, 0, 0, time.Local)
+		monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+		start = monday.AddDate(0, 0, (shard%100-1)*7)
+		return start, start.AddDate(0, 0, 7)
+	default:
+		start = time.Date(shard/100, time.Month(shard%100), 1, 0, 0, 0, 0, time.Local)
+		return start, start.AddDate(0, 1, 0)
+	}
+}
+
+// RotateShard 按策略推进实体的分表位置，创建所需的分表并登记到分表目录，位置变化时保存
+func RotateShard(db *gorm.DB, pos *SchedulePos, strategy ShardStrategy, now time.Time) (bool, error) {
+	create, changed, err := strategy.Rotate(db, pos, now)
+	if err != nil {
+		return false, err
+	}
+	for _, shard := range create {
+		if err := EnsureShard(db, pos.Name, shard); err != nil {
+			return false, err
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	if err := pos.Save(db, pos); err != nil {
+		return false, fmt.Errorf("failed to update schedule_pos: %w", err)
+	}
+	return true, nil
+}
+
+// EnsureShard 确保分表已创建并登记到分表目录
+// 按时间分表时写入路由可能指向轮转任务未创建的分表(停机期间的分表、按客户端时间路由到的历史分表)，写入前按需创建
+func EnsureShard(db *gorm.DB, name string, shard int) error {
+	tableName := fmt.Sprintf("%s_%d", name, shard)
+	created := false
+	if !db.Migrator().HasTable(tableName) {
+		if err := db.Table(tableName).AutoMigrate(GetModel(name)); err != nil {
+			return fmt.Errorf("failed to migrate new table %s: %w", tableName, err)
+		}
+		created = true
+	}
+	if err := RegisterShard(db, name, shard); err != nil {
+		return fmt.Errorf("failed to register shard %s: %w", tableName, err)
+	}
+	// 新建的空表随建表语句一起建好了索引，已存在的表由后台任务补建
+	if created {
+		if err := MarkShardIndexed(db, name, shard); err != nil {
+			return fmt.Errorf("failed to mark shard %s indexed: %w", tableName, err)
+		}
+		setFullTextReady(tableName, true)
+	}
+	return nil
+}
+
+// MigrateShardColumns 为已存在的分表补齐实体新增的列，不创建索引
+// 缺列会导致写入失败，需在启动时同步完成；耗时较长的索引由后台任务补建
+func MigrateShardColumns(db *gorm.DB, table string, entity Entity) error {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(entity); err != nil {
+		return err
+	}
+	migrator := db.Table(table).Migrator()
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || migrator.HasColumn(entity, field.DBName) {
+			continue
+		}
+		if err := migrator.AddColumn(entity, field.DBName); err != nil {
+			return fmt.Errorf("add column %s.%s: %w", table, field.DBName, err)
+		}
+	}
+	return nil
+}
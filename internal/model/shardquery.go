@@ -0,0 +1,305 @@
+package model
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+)
+
+const shardQueryConcurrency = 8 // 单次查询并发访问的分表数
+
+// MaxShardOffset 偏移分页时每张分表读取的最大行数(offset+limit)，更深的数据需按游标(After)翻页
+const MaxShardOffset = 10000
+
+var ErrOffsetTooDeep = fmt.Errorf("offset+limit exceeds %d, use a keyset cursor", MaxShardOffset)
+
+// ShardQuery 跨分表查询，各分表并行查询后按排序字段归并
+type ShardQuery struct {
+	Tables    []string
+	Scope     func(*gorm.DB) *gorm.DB // 过滤条件，作用于每张分表
+	SortField string                  // 排序字段，需为AuditLog的列名
+	Desc      bool
+	Offset    int
+	Limit     int
+	Count     bool     // 是否统计满足条件的总数
+	Estimate  bool     // 总数取自执行计划的估算行数，代价与数据量无关，但可能与实际值有偏差
+	Select    []string // 只查询指定的列，排序字段和log_id总会被查询；为空时查询全部列
+	After     *Keyset  // 游标分页时的起始位置，只返回排在其后的记录
+}
+
+// Keyset 游标分页位置，由上一页最后一条记录的排序值和log_id组成
+type Keyset struct {
+	Value any
+	LogId string
+}
+
+// ShardPage 跨分表查询的一页结果
+type ShardPage struct {
+	Logs      []*AuditLog
+	Total     int64
+	Exhausted []string // 本页之后没有更多记录且已不再写入的分表，按游标翻页时可跳过
+}
+
+// QueryShards 每张分表取前 offset+limit 条并统计总数，归并排序后截取全局分页
+// 排序相同的记录以log_id作为次序，保证分页稳定
+func QueryShards(ctx context.Context, db *gorm.DB, q ShardQuery) ([]*AuditLog, int64, error) {
+	page, err := QueryShardPage(ctx, db, q)
+	if err != nil {
+		return nil, 0, err
+	}
+	return page.Logs, page.Total, nil
+}
+
+// QueryShardPage 与QueryShards相同，同时返回已取完的分表
+// offset+limit超过MaxShardOffset时返回ErrOffsetTooDeep，避免每张分表读取并归并过多的行
+func QueryShardPage(ctx context.Context, db *gorm.DB, q ShardQuery) (*ShardPage, error) {
+	if q.Offset+q.Limit > MaxShardOffset {
+		return nil, ErrOffsetTooDeep
+	}
+	results := make([][]*AuditLog, len(q.Tables))
+	counts := make([]int64, len(q.Tables))
+	order := q.SortField + " ASC, log_id ASC"
+	if q.Desc {
+		order = q.SortField + " DESC, log_id DESC"
+	}
+
+	var columns []string
+	if len(q.Select) > 0 {
+		columns = append(columns, "log_id")
+		for _, col := range slices.Concat(q.Select, []string{q.SortField}) {
+			if !slices.Contains(columns, col) {
+				columns = append(columns, col)
+			}
+		}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(shardQueryConcurrency)
+	for i, table := range q.Tables {
+		g.Go(func() error {
+			query := func() *gorm.DB {
+				query := db.WithContext(gctx).Table(table)
+				if q.Scope != nil {
+					query = q.Scope(query)
+				}
+				return query
+			}
+			page := query()
+			if len(columns) > 0 {
+				page = page.Select(columns)
+			}
+			if q.After != nil {
+				op := ">"
+				if q.Desc {
+					op = "<"
+				}
+				page = page.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND log_id %[2]s ?))", q.SortField, op),
+					q.After.Value, q.After.Value, q.After.LogId)
+			}
+			err := page.Order(order).Limit(q.Offset + q.Limit).Find(&results[i]).Error
+			if err != nil {
+				return fmt.Errorf("query %s: %w", table, err)
+			}
+			if !q.Count {
+				return nil
+			}
+			if q.Estimate {
+				counts[i], err = estimateCount(query())
+			} else {
+				err = query().Count(&counts[i]).Error
+			}
+			if err != nil {
+				return fmt.Errorf("count %s: %w", table, err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	page := mergeShards(results, q)
+	for _, n := range counts {
+		page.Total += n
+	}
+	// 仍在写入的分表之后可能出现排在本页之后的新记录，不能视为已取完
+	closed, err := ClosedShards(db.WithContext(ctx), page.Exhausted)
+	if err != nil {
+		return nil, fmt.Errorf("read shard catalog: %w", err)
+	}
+	page.Exhausted = slices.DeleteFunc(page.Exhausted, func(table string) bool { return !closed[table] })
+	return page, nil
+}
+
+// mergeShards 将各分表已排序的结果归并后截取全局分页，排序相同的记录按log_id排序
+// 分表返回的记录不足offset+limit条且全部落在本页及之前时，该分表已取完
+func mergeShards(results [][]*AuditLog, q ShardQuery) *ShardPage {
+	type shardLog struct {
+		log   *AuditLog
+		shard int
+	}
+	merged := make([]shardLog, 0)
+	for i, logs := range results {
+		for _, log := range logs {
+			merged = append(merged, shardLog{log: log, shard: i})
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		c := CompareLogs(merged[i].log, merged[j].log, q.SortField)
+		if q.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	start := min(q.Offset, len(merged))
+	end := min(q.Offset+q.Limit, len(merged))
+	page := &ShardPage{Logs: make([]*AuditLog, 0, end-start)}
+	for _, item := range merged[start:end] {
+		page.Logs = append(page.Logs, item.log)
+	}
+
+	remaining := make(map[int]bool)
+	for _, item := range merged[end:] {
+		remaining[item.shard] = true
+	}
+	for i, logs := range results {
+		if len(logs) < q.Offset+q.Limit && !remaining[i] {
+			page.Exhausted = append(page.Exhausted, q.Tables[i])
+		}
+	}
+	return page
+}
+
+// CountShards 并行统计各分表中满足条件的记录数之和
+func CountShards(ctx context.Context, db *gorm.DB, tables []string, scope func(*gorm.DB) *gorm.DB) (int64, error) {
+	counts := make([]int64, len(tables))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(shardQueryConcurrency)
+	for i, table := range tables {
+		g.Go(func() error {
+			query := db.WithContext(gctx).Table(table)
+			if scope != nil {
+				query = scope(query)
+			}
+			if err := query.Count(&counts[i]).Error; err != nil {
+				return fmt.Errorf("count %s: %w", table, err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	return total, nil
+}
+
+// estimateCount 以EXPLAIN的估算行数乘以过滤比例作为总数，避免在大表上执行COUNT(*)
+func estimateCount(query *gorm.DB) (int64, error) {
+	stmt := query.Session(&gorm.Session{DryRun: true}).Select("*").Find(&[]*AuditLog{}).Statement
+	var plan []map[string]any
+	if err := query.Session(&gorm.Session{NewDB: true}).Raw("EXPLAIN "+stmt.SQL.String(), stmt.Vars...).Scan(&plan).Error; err != nil {
+		return 0, err
+	}
+	return planRows(plan), nil
+}
+
+// planRows 单表查询的执行计划只有一行，估算行数为rows乘以filtered百分比，缺少filtered时按100%计算
+func planRows(plan []map[string]any) int64 {
+	if len(plan) == 0 {
+		return 0
+	}
+	rows, _ := strconv.ParseFloat(fmt.Sprint(plan[0]["rows"]), 64)
+	filtered, err := strconv.ParseFloat(fmt.Sprint(plan[0]["filtered"]), 64)
+	if err != nil {
+		filtered = 100
+	}
+	return int64(rows * filtered / 100)
+}
+
+// CompareLogs 按排序字段比较两条记录，相同时比较log_id
+// 字符串忽略大小写比较，与分表使用的utf8_general_ci排序规则保持一致
+// constant.ValidFields新增排序字段时需同步修改CompareLogs、SortValue和ParseSortValue，由测试校验
+func CompareLogs(a, b *AuditLog, field string) int {
+	var c int
+	switch field {
+	case "created_at":
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case "updated_at":
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	case "timestamp":
+		c = cmp.Compare(a.TimeStamp, b.TimeStamp)
+	case "seq":
+		c = cmp.Compare(a.Seq, b.Seq)
+	case "action":
+		c = compareFold(a.Action, b.Action)
+	case "resource_type":
+		c = compareFold(a.ResourceType, b.ResourceType)
+	case "tenant_id":
+		c = compareFold(a.TenantID, b.TenantID)
+	case "user_id":
+		c = compareFold(a.UserID, b.UserID)
+	case "result":
+		c = compareFold(a.Result, b.Result)
+	}
+	if c != 0 {
+		return c
+	}
+	return compareFold(a.LogId, b.LogId)
+}
+
+func compareFold(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// SortValue 返回记录排序字段的值，用于编码游标；时间字段转换为微秒时间戳
+func (log *AuditLog) SortValue(field string) string {
+	switch field {
+	case "created_at":
+		return strconv.FormatInt(log.CreatedAt.UnixMicro(), 10)
+	case "updated_at":
+		return strconv.FormatInt(log.UpdatedAt.UnixMicro(), 10)
+	case "timestamp":
+		return strconv.FormatInt(log.TimeStamp, 10)
+	case "seq":
+		return strconv.FormatInt(log.Seq, 10)
+	case "action":
+		return log.Action
+	case "resource_type":
+		return log.ResourceType
+	case "tenant_id":
+		return log.TenantID
+	case "user_id":
+		return log.UserID
+	case "result":
+		return log.Result
+	}
+	return ""
+}
+
+// ParseSortValue 将SortValue的结果还原为查询参数
+func ParseSortValue(field, value string) (any, error) {
+	switch field {
+	case "created_at", "updated_at":
+		us, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return time.UnixMicro(us), nil
+	case "timestamp", "seq":
+		return strconv.ParseInt(value, 10, 64)
+	}
+	return value, nil
+}
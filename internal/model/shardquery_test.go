@@ -0,0 +1,293 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/internal/constant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func logAt(id string, minute int) *AuditLog {
+	return &AuditLog{LogId: id, CreatedAt: time.Date(2025, 1, 1, 0, minute, 0, 0, time.UTC)}
+}
+
+func logIds(logs []*AuditLog) []string {
+	ids := make([]string, 0, len(logs))
+	for _, log := range logs {
+		ids = append(ids, log.LogId)
+	}
+	return ids
+}
+
+func TestMergeShards(t *testing.T) {
+	// 各分表的结果已按排序字段和log_id排序
+	shards := func() [][]*AuditLog {
+		return [][]*AuditLog{
+			{logAt("a1_1", 1), logAt("a4_1", 4), logAt("a5_1", 5)},
+			{logAt("b2_2", 2), logAt("b4_2", 4)},
+			{},
+			{logAt("c3_3", 3), logAt("c6_3", 6)},
+		}
+	}
+	actions := func() [][]*AuditLog {
+		return [][]*AuditLog{
+			{{LogId: "2_1", Action: "login"}, {LogId: "3_1", Action: "LOGOUT"}},
+			{{LogId: "1_2", Action: "LOGIN"}, {LogId: "4_2", Action: "create"}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		results [][]*AuditLog
+		q       ShardQuery
+		want    []string
+	}{
+		{
+			name:    "asc",
+			results: shards(),
+			q:       ShardQuery{SortField: "created_at", Limit: 10},
+			want:    []string{"a1_1", "b2_2", "c3_3", "a4_1", "b4_2", "a5_1", "c6_3"},
+		},
+		{
+			name:    "desc ties broken by log_id",
+			results: shards(),
+			q:       ShardQuery{SortField: "created_at", Desc: true, Limit: 10},
+			want:    []string{"c6_3", "a5_1", "b4_2", "a4_1", "c3_3", "b2_2", "a1_1"},
+		},
+		{
+			name:    "offset spans shards",
+			results: shards(),
+			q:       ShardQuery{SortField: "created_at", Offset: 2, Limit: 3},
+			want:    []string{"c3_3", "a4_1", "b4_2"},
+		},
+		{
+			name:    "last page shorter than limit",
+			results: shards(),
+			q:       ShardQuery{SortField: "created_at", Offset: 5, Limit: 3},
+			want:    []string{"a5_1", "c6_3"},
+		},
+		{
+			name:    "offset beyond results",
+			results: shards(),
+			q:       ShardQuery{SortField: "created_at", Offset: 7, Limit: 3},
+			want:    []string{},
+		},
+		{
+			name:    "strings compare case-insensitively",
+			results: actions(),
+			q:       ShardQuery{SortField: "action", Limit: 10},
+			want:    []string{"4_2", "1_2", "2_1", "3_1"},
+		},
+		{
+			name:    "no shards",
+			results: [][]*AuditLog{},
+			q:       ShardQuery{SortField: "created_at", Limit: 10},
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.q.Tables = tableNames(len(tt.results))
+			assert.Equal(t, tt.want, logIds(mergeShards(tt.results, tt.q).Logs))
+		})
+	}
+}
+
+func tableNames(n int) []string {
+	tables := make([]string, n)
+	for i := range tables {
+		tables[i] = fmt.Sprintf("audit_log_%d", i+1)
+	}
+	return tables
+}
+
+func TestMergeShards_Exhausted(t *testing.T) {
+	// 每张分表最多取limit条，不足limit条且全部返回的分表已取完
+	results := [][]*AuditLog{
+		{logAt("a1_1", 1), logAt("a5_1", 5)},
+		{logAt("b2_2", 2), logAt("b3_2", 3), logAt("b4_2", 4)},
+		{},
+		{logAt("d6_4", 6)},
+	}
+	q := ShardQuery{Tables: tableNames(4), SortField: "created_at", Limit: 3}
+	page := mergeShards(results, q)
+	assert.Equal(t, []string{"a1_1", "b2_2", "b3_2"}, logIds(page.Logs))
+	// audit_log_1还有记录在本页之后，audit_log_2取满了limit条，可能还有更多
+	assert.Equal(t, []string{"audit_log_3"}, page.Exhausted)
+
+	page = mergeShards(results, ShardQuery{Tables: tableNames(4), SortField: "created_at", Limit: 4})
+	assert.Equal(t, []string{"b4_2"}, logIds(page.Logs[3:]))
+	assert.Equal(t, []string{"audit_log_2", "audit_log_3"}, page.Exhausted, "audit_log_1 and audit_log_4 have records after this page")
+
+	page = mergeShards(results, ShardQuery{Tables: tableNames(4), SortField: "created_at", Limit: 10})
+	assert.Equal(t, tableNames(4), page.Exhausted)
+}
+
+func TestQueryShardPage_OffsetTooDeep(t *testing.T) {
+	db := dryRunDB(t)
+	q := ShardQuery{Tables: tableNames(2), SortField: "created_at", Offset: MaxShardOffset - 10, Limit: 20}
+	_, err := QueryShardPage(context.Background(), db, q)
+	assert.ErrorIs(t, err, ErrOffsetTooDeep)
+
+	q.Offset = MaxShardOffset - 20
+	_, err = QueryShardPage(context.Background(), db, q)
+	assert.NoError(t, err)
+}
+
+// TestCompareLogs_SortFields 所有可排序字段都需要在CompareLogs和SortValue中处理，
+// 否则归并时只按log_id排序，与各分表中MySQL的排序不一致
+func TestCompareLogs_SortFields(t *testing.T) {
+	fields := make(map[string]bool)
+	for field := range constant.ValidFields {
+		fields[field] = true
+	}
+	for field, kind := range constant.QueryFields {
+		if kind == "int" || kind == "time" {
+			fields[field] = true
+		}
+	}
+
+	for field := range fields {
+		t.Run(field, func(t *testing.T) {
+			a, b := &AuditLog{LogId: "2"}, &AuditLog{LogId: "1"}
+			setColumn(t, a, field, 1)
+			setColumn(t, b, field, 2)
+			assert.Negative(t, CompareLogs(a, b, field), "the sort field takes precedence over log_id")
+			assert.Positive(t, CompareLogs(b, a, field))
+
+			// 游标中的排序值可以还原
+			value, err := ParseSortValue(field, b.SortValue(field))
+			require.NoError(t, err)
+			switch want := getColumn(t, b, field).(type) {
+			case time.Time:
+				assert.True(t, want.Equal(value.(time.Time)))
+			case int:
+				assert.Equal(t, int64(want), value)
+			default:
+				assert.Equal(t, want, value)
+			}
+		})
+	}
+
+	// 排序值相同时按log_id比较，与MySQL默认排序规则一样忽略大小写
+	a, b := &AuditLog{LogId: "01ab_1", Action: "Login"}, &AuditLog{LogId: "01AC_1", Action: "login"}
+	assert.Negative(t, CompareLogs(a, b, "action"))
+	assert.Zero(t, CompareLogs(a, a, "action"))
+}
+
+// setColumn 按gorm列名为记录字段赋值，n越大值越大
+func setColumn(t *testing.T, log *AuditLog, column string, n int) {
+	t.Helper()
+	field := columnField(t, log, column)
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(strings.Repeat("x", n))
+	case reflect.Int, reflect.Int64:
+		field.SetInt(int64(n))
+	case reflect.Struct:
+		field.Set(reflect.ValueOf(time.Date(2025, 1, 1, 0, n, 0, 0, time.Local)))
+	default:
+		t.Fatalf("unsupported column %s of kind %s", column, field.Kind())
+	}
+}
+
+func getColumn(t *testing.T, log *AuditLog, column string) any {
+	t.Helper()
+	return columnField(t, log, column).Interface()
+}
+
+func columnField(t *testing.T, log *AuditLog, column string) reflect.Value {
+	t.Helper()
+	v := reflect.ValueOf(log).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if strings.Contains(v.Type().Field(i).Tag.Get("gorm"), "column:"+column+";") {
+			return v.Field(i)
+		}
+	}
+	t.Fatalf("column %s not found", column)
+	return reflect.Value{}
+}
+
+func TestPlanRows(t *testing.T) {
+	tests := []struct {
+		name string
+		plan []map[string]any
+		want int64
+	}{
+		{name: "filtered", plan: []map[string]any{{"rows": int64(1000), "filtered": float64(10)}}, want: 100},
+		{name: "filtered as string", plan: []map[string]any{{"rows": int64(1000), "filtered": "50.00"}}, want: 500},
+		{name: "no filtered", plan: []map[string]any{{"rows": int64(1000), "filtered": nil}}, want: 1000},
+		{name: "empty plan", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, planRows(tt.plan))
+		})
+	}
+}
+
+func TestEstimateCount(t *testing.T) {
+	conn := &explainConn{columns: []string{"id", "table", "rows", "filtered"}, row: []driver.Value{int64(1), "audit_log_1", int64(2000), float64(25)}}
+	sqlDB := sql.OpenDB(conn)
+	defer sqlDB.Close()
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{})
+	require.NoError(t, err)
+
+	n, err := estimateCount(db.Table("audit_log_1").Where("tenant_id = ?", "t1"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), n)
+	assert.Equal(t, "EXPLAIN SELECT * FROM `audit_log_1` WHERE tenant_id = ?", conn.query)
+	assert.Equal(t, []driver.Value{"t1"}, conn.args)
+}
+
+// explainConn 记录执行的语句，并对所有查询返回同一行结果
+type explainConn struct {
+	columns []string
+	row     []driver.Value
+	query   string
+	args    []driver.Value
+}
+
+func (c *explainConn) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *explainConn) Driver() driver.Driver                        { return nil }
+func (c *explainConn) Prepare(string) (driver.Stmt, error)          { return nil, driver.ErrSkip }
+func (c *explainConn) Close() error                                 { return nil }
+func (c *explainConn) Begin() (driver.Tx, error)                    { return nil, driver.ErrSkip }
+
+func (c *explainConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.query = query
+	c.args = c.args[:0]
+	for _, arg := range args {
+		c.args = append(c.args, arg.Value)
+	}
+	return &explainRows{columns: c.columns, row: c.row}, nil
+}
+
+type explainRows struct {
+	columns []string
+	row     []driver.Value
+	done    bool
+}
+
+func (r *explainRows) Columns() []string { return r.columns }
+func (r *explainRows) Close() error      { return nil }
+
+func (r *explainRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
@@ -0,0 +1,261 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+)
+
+// MaxStatsGroups 单张分表统计结果的最大分组数(维度组合数×桶数)，超出时拒绝查询，避免高基数维度拖垮数据库
+// 不小于统计接口的最大分桶数乘以10，分桶后仍可按十个以内的维度组合统计
+const MaxStatsGroups = 100000
+
+var ErrTooManyGroups = fmt.Errorf("stats: more than %d groups, narrow the filter or time range, or use top", MaxStatsGroups)
+
+// StatsQuery 跨分表分组统计
+type StatsQuery struct {
+	Tables   []string
+	Scope    func(*gorm.DB) *gorm.DB // 过滤条件，作用于每张分表
+	GroupBy  []string                // 分组维度，需为AuditLog的列名
+	Interval time.Duration           // 按created_at分桶的间隔，0表示不分桶；桶边界按UTC纪元对齐
+	Top      int                     // 只保留计数最多的前N个维度组合，0表示全部
+}
+
+// StatsRow 统计结果，Keys与GroupBy一一对应，Bucket为桶起始时间(毫秒)
+type StatsRow struct {
+	Bucket int64
+	Keys   []string
+	Count  int64
+}
+
+// statsPass 对每张分表执行一轮分组统计的方式
+type statsPass struct {
+	bucketed bool                    // 是否按时间分桶
+	top      int                     // 每张分表只取计数最多的前top组，0表示全部
+	scope    func(*gorm.DB) *gorm.DB // 在StatsQuery.Scope之外追加的条件
+}
+
+// QueryStats 各分表并行执行GROUP BY，按维度与时间桶合并计数，同时返回满足条件的总数
+// 未分桶时按计数降序返回，分桶时按桶时间升序、同桶内按计数降序返回
+func QueryStats(ctx context.Context, db *gorm.DB, q StatsQuery) ([]*StatsRow, int64, error) {
+	if q.Top > 0 && len(q.GroupBy) > 0 {
+		return queryTopStats(ctx, db, q)
+	}
+	results, err := runStats(ctx, db, q, statsPass{bucketed: q.Interval > 0})
+	if err != nil {
+		return nil, 0, err
+	}
+	merged := mergeStats(results)
+	var total int64
+	for _, row := range merged {
+		total += row.Count
+	}
+	sortStats(merged)
+	return merged, total, nil
+}
+
+// queryTopStats 分两轮统计前N个维度组合，分组数与维度基数无关
+// 第一轮各分表只按维度分组并取计数最多的前N组，合并后选出全局前N组；第二轮只统计选出的组，计数是完整的
+// 某组在每张分表中都未进入前N、合计却进入前N时会被遗漏，分表数越少越准确
+func queryTopStats(ctx context.Context, db *gorm.DB, q StatsQuery) ([]*StatsRow, int64, error) {
+	candidates, err := runStats(ctx, db, q, statsPass{top: q.Top})
+	if err != nil {
+		return nil, 0, err
+	}
+	top := topStats(mergeStats(candidates), q.Top)
+
+	var merged []*StatsRow
+	switch {
+	case len(top) == 0:
+		merged = top
+	case q.Interval == 0 && len(q.Tables) == 1:
+		// 只有一张分表时第一轮的结果就是完整的
+		merged = top
+	default:
+		results, err := runStats(ctx, db, q, statsPass{bucketed: q.Interval > 0, scope: inGroups(q.GroupBy, top)})
+		if err != nil {
+			return nil, 0, err
+		}
+		merged = mergeStats(results)
+	}
+
+	total, err := CountShards(ctx, db, q.Tables, q.Scope)
+	if err != nil {
+		return nil, 0, err
+	}
+	sortStats(merged)
+	return merged, total, nil
+}
+
+// runStats 按pass在各分表并行执行一轮分组统计
+func runStats(ctx context.Context, db *gorm.DB, q StatsQuery, pass statsPass) ([][]*StatsRow, error) {
+	secs := int64(q.Interval / time.Second)
+	if !pass.bucketed {
+		secs = 0
+	}
+	cols := make([]string, 0, len(q.GroupBy)+2)
+	groups := make([]string, 0, len(q.GroupBy)+1)
+	for _, field := range q.GroupBy {
+		cols = append(cols, "`"+field+"`")
+		groups = append(groups, "`"+field+"`")
+	}
+	if secs > 0 {
+		cols = append(cols, fmt.Sprintf("CAST(FLOOR(UNIX_TIMESTAMP(created_at) / %d) * %d AS SIGNED) AS bucket", secs, secs))
+		groups = append(groups, "bucket")
+	}
+	cols = append(cols, "COUNT(*) AS cnt")
+
+	results := make([][]*StatsRow, len(q.Tables))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(shardQueryConcurrency)
+	for i, table := range q.Tables {
+		g.Go(func() error {
+			query := db.WithContext(gctx).Table(table).Select(strings.Join(cols, ", "))
+			if q.Scope != nil {
+				query = q.Scope(query)
+			}
+			if pass.scope != nil {
+				query = pass.scope(query)
+			}
+			if len(groups) > 0 {
+				query = query.Group(strings.Join(groups, ", "))
+				if pass.top > 0 {
+					query = query.Order("cnt DESC").Limit(pass.top)
+				} else {
+					query = query.Limit(MaxStatsGroups + 1)
+				}
+			}
+			rows, err := query.Rows()
+			if err != nil {
+				return fmt.Errorf("stats %s: %w", table, err)
+			}
+			defer rows.Close()
+			if results[i], err = scanStats(rows, len(q.GroupBy), secs > 0); err != nil {
+				return fmt.Errorf("stats %s: %w", table, err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// inGroups 只统计rows中的维度组合
+func inGroups(fields []string, rows []*StatsRow) func(*gorm.DB) *gorm.DB {
+	cols := make([]string, 0, len(fields))
+	for _, field := range fields {
+		cols = append(cols, "`"+field+"`")
+	}
+	values := make([]any, 0, len(rows))
+	for _, row := range rows {
+		if len(fields) == 1 {
+			values = append(values, row.Keys[0])
+			continue
+		}
+		keys := make([]any, 0, len(row.Keys))
+		for _, key := range row.Keys {
+			keys = append(keys, key)
+		}
+		values = append(values, keys)
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("("+strings.Join(cols, ", ")+") IN ?", values)
+	}
+}
+
+// sortStats 按桶时间升序、同桶内按计数降序排列
+func sortStats(rows []*StatsRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Bucket != rows[j].Bucket {
+			return rows[i].Bucket < rows[j].Bucket
+		}
+		return rows[i].Count > rows[j].Count
+	})
+}
+
+func scanStats(rows *sql.Rows, dims int, bucketed bool) ([]*StatsRow, error) {
+	var out []*StatsRow
+	for rows.Next() {
+		keys := make([]sql.NullString, dims)
+		var bucket sql.NullInt64
+		row := &StatsRow{Keys: make([]string, dims)}
+		dest := make([]any, 0, dims+2)
+		for k := range keys {
+			dest = append(dest, &keys[k])
+		}
+		if bucketed {
+			dest = append(dest, &bucket)
+		}
+		dest = append(dest, &row.Count)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if len(out) == MaxStatsGroups {
+			return nil, ErrTooManyGroups
+		}
+		for k := range keys {
+			row.Keys[k] = keys[k].String
+		}
+		row.Bucket = bucket.Int64 * 1000
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// mergeStats 合并各分表的同组计数，维度值忽略大小写，与MySQL默认排序规则的分组一致
+func mergeStats(results [][]*StatsRow) []*StatsRow {
+	index := make(map[string]*StatsRow)
+	merged := make([]*StatsRow, 0)
+	for _, rows := range results {
+		for _, row := range rows {
+			key := fmt.Sprintf("%d\x00%s", row.Bucket, strings.ToLower(strings.Join(row.Keys, "\x00")))
+			if exist, ok := index[key]; ok {
+				exist.Count += row.Count
+				continue
+			}
+			index[key] = row
+			merged = append(merged, row)
+		}
+	}
+	return merged
+}
+
+// topStats 按维度组合汇总所有桶的计数，只保留总数最多的前n个组合
+func topStats(rows []*StatsRow, n int) []*StatsRow {
+	totals := make(map[string]int64)
+	for _, row := range rows {
+		totals[strings.ToLower(strings.Join(row.Keys, "\x00"))] += row.Count
+	}
+	if len(totals) <= n {
+		return rows
+	}
+	keys := make([]string, 0, len(totals))
+	for k := range totals {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if totals[keys[i]] != totals[keys[j]] {
+			return totals[keys[i]] > totals[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	keep := make(map[string]bool, n)
+	for _, k := range keys[:n] {
+		keep[k] = true
+	}
+	out := make([]*StatsRow, 0, len(rows))
+	for _, row := range rows {
+		if keep[strings.ToLower(strings.Join(row.Keys, "\x00"))] {
+			out = append(out, row)
+		}
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"testing"
+
+	"codexie.com/auditlog/internal/constant"
+	"github.com/stretchr/testify/assert"
+)
+
+func statsRow(bucket int64, count int64, keys ...string) *StatsRow {
+	return &StatsRow{Bucket: bucket, Keys: keys, Count: count}
+}
+
+func TestMergeStats(t *testing.T) {
+	results := [][]*StatsRow{
+		{statsRow(0, 3, "LOGIN", "success"), statsRow(60000, 1, "LOGIN", "success")},
+		{statsRow(0, 2, "login", "SUCCESS"), statsRow(0, 5, "logout", "success")},
+		{},
+		{statsRow(60000, 4, "LOGIN", "success")},
+	}
+	merged := mergeStats(results)
+	// 同桶同组的计数合并，维度值忽略大小写，保留最先出现的写法
+	assert.Equal(t, []*StatsRow{
+		statsRow(0, 5, "LOGIN", "success"),
+		statsRow(60000, 5, "LOGIN", "success"),
+		statsRow(0, 5, "logout", "success"),
+	}, merged)
+
+	assert.Empty(t, mergeStats(nil))
+}
+
+func TestTopStats(t *testing.T) {
+	rows := []*StatsRow{
+		statsRow(0, 5, "LOGIN"),
+		statsRow(60000, 5, "LOGIN"),
+		statsRow(0, 6, "create"),
+		statsRow(0, 3, "delete"),
+		statsRow(60000, 4, "delete"),
+		statsRow(0, 1, "update"),
+	}
+
+	// 按所有桶的合计排名，保留入选组合在每个桶的行
+	assert.Equal(t, []*StatsRow{rows[0], rows[1], rows[3], rows[4]}, topStats(rows, 2))
+	assert.Equal(t, []*StatsRow{rows[0], rows[1]}, topStats(rows, 1))
+	assert.Equal(t, rows, topStats(rows, 4))
+	assert.Equal(t, rows, topStats(rows, 10))
+
+	// 计数相同时按维度值排序，结果稳定
+	tied := []*StatsRow{statsRow(0, 2, "b"), statsRow(0, 2, "a"), statsRow(0, 2, "c")}
+	assert.Equal(t, []*StatsRow{tied[1]}, topStats(tied, 1))
+}
+
+func TestInGroups(t *testing.T) {
+	db := dryRunDB(t)
+	rows := []*StatsRow{statsRow(0, 5, "LOGIN", "success"), statsRow(0, 3, "logout", "fail")}
+
+	stmt := db.Table("audit_log_1").Scopes(inGroups([]string{"action", "result"}, rows)).Find(&[]*AuditLog{}).Statement
+	assert.Equal(t, "SELECT * FROM `audit_log_1` WHERE (`action`, `result`) IN ((?,?),(?,?))", stmt.SQL.String())
+	assert.Equal(t, []any{"LOGIN", "success", "logout", "fail"}, stmt.Vars)
+
+	stmt = db.Table("audit_log_1").Scopes(inGroups([]string{"action"}, rows)).Find(&[]*AuditLog{}).Statement
+	assert.Equal(t, "SELECT * FROM `audit_log_1` WHERE (`action`) IN (?,?)", stmt.SQL.String())
+	assert.Equal(t, []any{"LOGIN", "logout"}, stmt.Vars)
+}
+
+// TestStatsLimits 统计接口允许的最大分桶数在按少量维度组合分组时不能触发分组数上限
+func TestStatsLimits(t *testing.T) {
+	assert.LessOrEqual(t, constant.MAX_STATS_BUCKETS*10, MaxStatsGroups)
+}
@@ -2,6 +2,8 @@ package svc
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -9,15 +11,18 @@ import (
 	"time"
 
 	"codexie.com/auditlog/internal/config"
-	"codexie.com/auditlog/internal/constant"
 	"codexie.com/auditlog/internal/job"
 	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/blobstore"
 	"codexie.com/auditlog/pkg/pipeline"
 	"codexie.com/auditlog/pkg/plugin"
 	_ "codexie.com/auditlog/pkg/plugin/exporter"
 	_ "codexie.com/auditlog/pkg/plugin/filter"
 	_ "codexie.com/auditlog/pkg/plugin/lifecycle"
+	_ "codexie.com/auditlog/pkg/plugin/transformer"
+	"codexie.com/auditlog/pkg/ratelimit"
 	"codexie.com/auditlog/pkg/scheduler"
+	"codexie.com/auditlog/pkg/tail"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/zeromicro/go-zero/core/logx"
@@ -33,11 +38,19 @@ const (
 )
 
 type ServiceContext struct {
-	Config    config.Config
-	DB        *gorm.DB
-	Piplines  []*pipeline.Pipeline
-	Redis     *redis.Client
-	Scheduler *scheduler.Scheduler
+	Config        config.Config
+	DB            *gorm.DB
+	Piplines      []*pipeline.Pipeline
+	Redis         *redis.Client
+	Scheduler     *scheduler.Scheduler
+	TenantLimiter *ratelimit.TenantLimiter
+	CheckpointKey ed25519.PrivateKey // 检查点签名私钥，未配置时为nil
+	CursorKey     []byte             // 分页游标签名密钥
+	ExportKey     []byte             // 导出下载链接及回调签名密钥
+	ExportStore   blobstore.Store    // 导出文件存储
+	Tail          *tail.Hub          // 实时订阅中心，由tail导出器发布记录
+	// 按实体名配置的分表策略
+	ShardStrategies map[string]model.ShardStrategy
 }
 
 func NewServiceContext(c config.Config) *ServiceContext {
@@ -48,6 +61,11 @@ func NewServiceContext(c config.Config) *ServiceContext {
 	ctx.initDB(c.MySQL)
 	ctx.InitRedis(c.Redis)
 	ctx.initTables()
+	ctx.TenantLimiter = ratelimit.NewTenantLimiter(ctx.Redis, c.RateLimit)
+	ctx.initCheckpointKey(c.Checkpoint)
+	ctx.initCursorKey(c.Query)
+	ctx.initExport(c.Export)
+	ctx.Tail = tail.NewHub(ctx.Redis, c.Tail, func() any { return &model.AuditLog{} })
 
 	ctx.initPiplines(c.Pipelines)
 	ctx.initScheduler(c.Scheduler)
@@ -59,12 +77,18 @@ func (s *ServiceContext) ReleaseAll() {
 	for _, p := range s.Piplines {
 		p.Close()
 	}
+	s.Tail.Close()
 
 	s.Scheduler.Stop()
 }
 
 // 设置数据库
 func (s *ServiceContext) initDB(mysqlConf config.MySQLConf) {
+	s.DB = NewMySQL(mysqlConf)
+}
+
+// NewMySQL 创建数据库连接，命令行工具也通过它连接数据库
+func NewMySQL(mysqlConf config.MySQLConf) *gorm.DB {
 	datasource := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=%t&loc=%s",
 		mysqlConf.User,
 		mysqlConf.Password,
@@ -88,7 +112,7 @@ func (s *ServiceContext) initDB(mysqlConf config.MySQLConf) {
 	sqlDB.SetMaxIdleConns(mysqlConf.MaxIdleConns)
 	sqlDB.SetConnMaxLifetime(time.Duration(mysqlConf.ConnMaxLifetime) * time.Second)
 
-	s.DB = gormDB
+	return gormDB
 }
 
 // 设置piplines
@@ -100,47 +124,38 @@ func (s *ServiceContext) initPiplines(piplineConfigs []config.PiplineConfig) {
 		logx.Infof("init piplines: %s", string(jsonStr))
 
 		p := pipeline.New(piplineConfig)
-		for _, expConf := range piplineConfig.Plugins.Exporters {
-			conf := make(map[string]any)
-			for k, v := range expConf.Config {
-				if strings.HasPrefix(v, "#svc.") {
-					fieldName := strings.TrimPrefix(v, "#svc.")
-					conf[k] = s.getFieldVal(fieldName)
-				} else {
-					conf[k] = v
-				}
+		ids := piplineConfig.Plugins.InstanceIds()
+		seen := make(map[string]bool, len(ids))
+		for i, expConf := range piplineConfig.Plugins.Exporters {
+			if seen[ids[i]] {
+				panic(fmt.Sprintf("duplicate exporter id in pipeline %s: %s", piplineConfig.Name, ids[i]))
 			}
-			exporter := plugin.GetExporter(expConf.Name, conf)
-			p.RegisterExporter(exporter)
+			seen[ids[i]] = true
+			exporter := plugin.GetExporter(expConf.Name, s.resolvePluginConf(expConf.Config))
+			if exporter == nil {
+				panic(fmt.Sprintf("exporter plugin not found: %s", expConf.Name))
+			}
+			p.RegisterRoute(ids[i], exporter, s.newFilters(expConf.Filters), s.newTransformers(expConf.Transformers))
 		}
 
-		for _, filterConf := range piplineConfig.Plugins.Filters {
-			conf := make(map[string]any)
-			for k, v := range filterConf.Config {
-				if strings.HasPrefix(v, "#svc.") {
-					fieldName := strings.TrimPrefix(v, "#svc.")
-					conf[k] = s.getFieldVal(fieldName)
-				} else {
-					conf[k] = v
-				}
-			}
-			filter := plugin.GetFilter(filterConf.Name, conf)
+		for _, filter := range s.newFilters(piplineConfig.Plugins.Filters) {
 			p.RegisterFilter(filter)
 		}
 
+		for _, transformer := range s.newTransformers(piplineConfig.Plugins.Transformers) {
+			p.RegisterTransformer(transformer)
+		}
+
 		for _, lifecycleConf := range piplineConfig.Plugins.Lifecycles {
-			conf := make(map[string]any)
-			for k, v := range lifecycleConf.Config {
-				if strings.HasPrefix(v, "#svc.") {
-					fieldName := strings.TrimPrefix(v, "#svc.")
-					conf[k] = s.getFieldVal(fieldName)
-				} else {
-					conf[k] = v
-				}
+			lifecycle := plugin.GetLifecycle(lifecycleConf.Name, s.resolvePluginConf(lifecycleConf.Config))
+			if lifecycle == nil {
+				panic(fmt.Sprintf("lifecycle plugin not found: %s", lifecycleConf.Name))
 			}
-			lifecycle := plugin.GetLifecycle(lifecycleConf.Name, conf)
 			p.RegisterLifecycleHook(lifecycle)
 		}
+		if err := p.Validate(); err != nil {
+			panic(err)
+		}
 
 		piplines = append(piplines, p)
 	}
@@ -164,8 +179,63 @@ func (s *ServiceContext) InitRedis(redisConf config.RedisConf) {
 
 func (s *ServiceContext) initScheduler(conf scheduler.ScheduleConfig) {
 	s.Scheduler = scheduler.NewScheduler(s.DB, conf)
-	scheduleJob := job.NewScheduleJob(s.DB)
+	scheduleJob := job.NewScheduleJob(s.DB, s.Redis, s.ShardStrategies)
 	s.Scheduler.RegisterTask(scheduleJob)
+	if s.Config.Checkpoint.Enabled {
+		s.Scheduler.RegisterTask(job.NewCheckpointJob(s.DB, s.Config.Checkpoint, s.CheckpointKey))
+	}
+	s.Scheduler.RegisterTask(job.NewExportJob(s.DB, s.ExportStore, s.Config.Export, s.Config.Query.Search, s.ExportKey))
+	s.Scheduler.RegisterTask(job.NewExportCleanJob(s.DB, s.ExportStore))
+	s.Scheduler.RegisterTask(job.NewShardIndexJob(s.DB))
+}
+
+func (s *ServiceContext) initExport(conf config.ExportConf) {
+	var err error
+	if conf.Storage == "s3" {
+		s.ExportStore, err = blobstore.NewS3(conf.S3)
+	} else {
+		s.ExportStore, err = blobstore.NewLocal(conf.Dir)
+	}
+	if err != nil {
+		panic(err)
+	}
+	// 回调接收方持有回调密钥，与下载链接密钥相同时可以伪造任意任务的下载链接
+	if conf.CallbackSecret != "" && conf.CallbackSecret == conf.URLSecret {
+		panic("Export.CallbackSecret must differ from Export.URLSecret")
+	}
+
+	if conf.URLSecret != "" {
+		s.ExportKey = []byte(conf.URLSecret)
+		return
+	}
+	logx.Info("Export.URLSecret is not configured, download links are only valid on this instance until restart")
+	s.ExportKey = make([]byte, 32)
+	if _, err := rand.Read(s.ExportKey); err != nil {
+		panic(err)
+	}
+}
+
+func (s *ServiceContext) initCursorKey(conf config.QueryConf) {
+	if conf.CursorSecret != "" {
+		s.CursorKey = []byte(conf.CursorSecret)
+		return
+	}
+	logx.Info("Query.CursorSecret is not configured, cursors are only valid on this instance until restart")
+	s.CursorKey = make([]byte, 32)
+	if _, err := rand.Read(s.CursorKey); err != nil {
+		panic(err)
+	}
+}
+
+func (s *ServiceContext) initCheckpointKey(conf config.CheckpointConf) {
+	key, err := conf.PrivateKey()
+	if err != nil {
+		panic(err)
+	}
+	if conf.Enabled && key == nil {
+		panic("checkpoint is enabled but SigningKey is not configured")
+	}
+	s.CheckpointKey = key
 }
 
 func (s *ServiceContext) initTables() {
@@ -179,29 +249,110 @@ func (s *ServiceContext) genTables(entities []model.Entity) {
 	schedulePos := &model.SchedulePos{}
 	s.DB.AutoMigrate(schedulePos)
 	s.DB.AutoMigrate(&scheduler.ScheduleTask{})
+	s.DB.AutoMigrate(&model.ChainHead{})
+	s.DB.AutoMigrate(&model.Checkpoint{})
+	s.DB.AutoMigrate(&model.CheckpointProgress{})
+	s.DB.AutoMigrate(&model.CheckpointNode{})
+	s.DB.AutoMigrate(&model.ShardCatalog{})
+	s.DB.AutoMigrate(&model.ExportJob{})
 
 	//创建实体对象表
+	s.ShardStrategies = make(map[string]model.ShardStrategy)
 	for _, entity := range entities {
+		conf := s.Config.Sharding[entity.Name()]
+		strategy, err := model.NewShardStrategy(conf.Strategy, conf.MaxRows, conf.PreCreate)
+		if err != nil {
+			panic(err)
+		}
+		s.ShardStrategies[entity.Name()] = strategy
+
+		// 首次创建时按策略确定首张分表，多副本同时启动时以先写入的为准
+		first := strategy.First(time.Now())
+		if err := s.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoNothing: true,
+		}).Create(&model.SchedulePos{
+			Name:             entity.Name(),
+			ScheduleBeginPos: first,
+			ScheduleEndPos:   first,
+			Strategy:         strategy.Name(),
+		}).Error; err != nil {
+			panic(err)
+		}
 		pos, err := schedulePos.GetSchedulePos(s.DB, entity.Name())
-		if err != nil && err != gorm.ErrRecordNotFound {
+		if err != nil {
 			panic(err)
 		}
-		if err == gorm.ErrRecordNotFound {
-			pos = &model.SchedulePos{
-				Name:             entity.Name(),
-				ScheduleBeginPos: 1,
-				ScheduleEndPos:   1,
-			}
-			if err := s.DB.Model(pos).Clauses(clause.OnConflict{
-				Columns:   []clause.Column{{Name: "name"}},
-				DoNothing: true,
-			}).Create(pos).Error; err != nil {
+		// 已有数据的实体不支持切换分表策略，分表序号的含义不同
+		if pos.Strategy != strategy.Name() {
+			logx.Errorf("shard strategy of %s is %s, configured %s is ignored", entity.Name(), pos.Strategy, strategy.Name())
+			if strategy, err = pos.ShardStrategy(); err != nil {
 				panic(err)
 			}
 		}
-		s.Redis.Set(context.Background(), fmt.Sprintf("%s:%s", constant.SchedulePosKey, entity.Name()), pos.ScheduleEndPos, 0)
 		s.DB.Table(fmt.Sprintf("%s_%d", entity.Name(), pos.ScheduleEndPos)).AutoMigrate(entity)
+		if err := model.SyncShardCatalog(s.DB, pos); err != nil {
+			panic(err)
+		}
+		s.migrateShards(entity)
+		// 按时间分表时启动即推进到当前分表并预建后续分表
+		if _, err := model.RotateShard(s.DB, pos, strategy, time.Now()); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// migrateShards 将实体新增的列同步到所有已登记的分表，缺列会导致写入失败，因此失败时终止启动
+// 索引(含全文索引)在大表上创建耗时较长，由ShardIndexTask在后台补建，建好前关键字搜索退回LIKE
+func (s *ServiceContext) migrateShards(entity model.Entity) {
+	tables, err := model.ShardTables(s.DB, entity.Name())
+	if err != nil {
+		panic(err)
+	}
+	for _, table := range tables {
+		if err := model.MigrateShardColumns(s.DB, table, entity); err != nil {
+			panic(fmt.Sprintf("migrate shard %s failed: %v", table, err))
+		}
+	}
+}
+
+// newFilters 根据配置创建过滤器列表
+func (s *ServiceContext) newFilters(items []config.PluginItem) []plugin.Filter {
+	filters := make([]plugin.Filter, 0, len(items))
+	for _, item := range items {
+		filter := plugin.GetFilter(item.Name, s.resolvePluginConf(item.Config))
+		if filter == nil {
+			panic(fmt.Sprintf("filter plugin not found: %s", item.Name))
+		}
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+// newTransformers 根据配置创建转换器列表
+func (s *ServiceContext) newTransformers(items []config.PluginItem) []plugin.Transformer {
+	transformers := make([]plugin.Transformer, 0, len(items))
+	for _, item := range items {
+		transformer := plugin.GetTransformer(item.Name, s.resolvePluginConf(item.Config))
+		if transformer == nil {
+			panic(fmt.Sprintf("transformer plugin not found: %s", item.Name))
+		}
+		transformers = append(transformers, transformer)
+	}
+	return transformers
+}
+
+// resolvePluginConf 将插件配置中"#svc."开头的值替换为ServiceContext中对应的字段
+func (s *ServiceContext) resolvePluginConf(pluginConf map[string]any) map[string]any {
+	conf := make(map[string]any, len(pluginConf))
+	for k, v := range pluginConf {
+		if str, ok := v.(string); ok && strings.HasPrefix(str, "#svc.") {
+			conf[k] = s.getFieldVal(strings.TrimPrefix(str, "#svc."))
+		} else {
+			conf[k] = v
+		}
 	}
+	return conf
 }
 
 func (s *ServiceContext) getFieldVal(fieldName string) any {
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// MarshalJSON 未指定投影字段时输出全部字段(含零值)，指定时只输出投影字段、log_id和高亮片段
+func (r LogRecord) MarshalJSON() ([]byte, error) {
+	type record LogRecord
+	if r.Selected == nil {
+		return json.Marshal(record(r))
+	}
+
+	v := reflect.ValueOf(r)
+	buf := []byte{'{'}
+	for i := 0; i < v.NumField(); i++ {
+		name, opts, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		switch {
+		case name == "-":
+			continue
+		case name == "highlights":
+			if opts == "omitempty" && v.Field(i).Len() == 0 {
+				continue
+			}
+		case name != "log_id" && !r.Selected[name]:
+			continue
+		}
+		value, err := json.Marshal(v.Field(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		if len(buf) > 1 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, '"')
+		buf = append(buf, name...)
+		buf = append(buf, '"', ':')
+		buf = append(buf, value...)
+	}
+	return append(buf, '}'), nil
+}
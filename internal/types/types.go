@@ -2,18 +2,19 @@
 package types
 
 type AuditLog struct {
-	TenantID     string `json:"tenant_id"`     // 租户ID，必填
-	UserID       string `json:"user_id"`       // 用户ID，必填
-	Username     string `json:"username"`      // 用户名
-	Action       string `json:"action"`        // 操作名称（如CREATE_VM）
-	ResourceType string `json:"resource_type"` // 资源类型（如VM、DB）
-	ResourceID   string `json:"resource_id"`   // 操作对象ID
-	ResourceName string `json:"resource_name"` // 操作对象名称
-	Result       string `json:"result"`        // 操作结果（success/fail）
-	Message      string `json:"message"`       // 失败或详细信息
-	ClientIP     string `json:"client_ip"`     // 客户端IP
-	Module       string `json:"module"`        // 模块
-	TraceID      string `json:"trace_id"`      // 链路追踪ID
+	TenantID       string `json:"tenant_id"`                // 租户ID，必填
+	UserID         string `json:"user_id"`                  // 用户ID，必填
+	Username       string `json:"username"`                 // 用户名
+	Action         string `json:"action"`                   // 操作名称（如CREATE_VM）
+	ResourceType   string `json:"resource_type"`            // 资源类型（如VM、DB）
+	ResourceID     string `json:"resource_id"`              // 操作对象ID
+	ResourceName   string `json:"resource_name"`            // 操作对象名称
+	Result         string `json:"result"`                   // 操作结果（success/fail）
+	Message        string `json:"message"`                  // 失败或详细信息
+	ClientIP       string `json:"client_ip"`                // 客户端IP
+	Module         string `json:"module"`                   // 模块
+	TraceID        string `json:"trace_id"`                 // 链路追踪ID
+	IdempotencyKey string `json:"idempotency_key,optional"` // 幂等键，客户端重试时保持不变
 }
 
 type BaseResponse struct {
@@ -22,23 +23,25 @@ type BaseResponse struct {
 	Data    any    `json:"data"`
 }
 
+type ExportQuery struct {
+	TenantID     string         `json:"tenant_id,optional"`     // 租户ID，非管理员固定为本租户
+	UserID       string         `json:"user_id,optional"`       // 用户ID
+	Username     string         `json:"username,optional"`      // 用户名
+	Action       string         `json:"action,optional"`        // 操作类型
+	ResourceType string         `json:"resource_type,optional"` // 资源类型
+	ResourceID   string         `json:"resource_id,optional"`   // 资源ID
+	Result       string         `json:"result,optional"`        // 操作结果
+	Keyword      string         `json:"keyword,optional"`       // 关键字搜索
+	StartTime    int64          `json:"start_time,optional"`    // 起始时间戳（毫秒）
+	EndTime      int64          `json:"end_time,optional"`      // 结束时间戳（毫秒）
+	Filter       map[string]any `json:"filter,optional"`        // 查询条件DSL，与以上条件同时生效
+}
+
 type ExportRequest struct {
-	Query struct {
-		TenantID     string `form:"tenant_id"`              // 租户ID，必填
-		UserID       string `form:"user_id,optional"`       // 用户ID，可选
-		Username     string `form:"username,optional"`      // 用户名，可选
-		Action       string `form:"action,optional"`        // 操作类型
-		ResourceType string `form:"resource_type,optional"` // 资源类型
-		ResourceID   string `form:"resource_id,optional"`   // 资源ID
-		Result       string `form:"result,optional"`        // 操作结果
-		Keyword      string `form:"keyword,optional"`       // 关键字模糊搜索
-		StartTime    int64  `form:"start_time,optional"`    // 起始时间戳（毫秒）
-		EndTime      int64  `form:"end_time,optional"`      // 结束时间戳（毫秒）
-		Page         int    `form:"page,default=1"`         // 分页页码，默认1
-		PageSize     int    `form:"page_size,default=20"`   // 每页大小，默认20
-	} `json:"query"` // 查询条件
-	Format      string `json:"format"`       // 导出格式（csv/json）
-	CallbackURL string `json:"callback_url"` // 回调通知地址
+	Query       ExportQuery `json:"query,optional"`                                          // 查询条件
+	Fields      []string    `json:"fields,optional"`                                         // 导出的列，为空时导出全部列
+	Format      string      `json:"format,default=csv,options=csv|json|ndjson|xlsx|parquet"` // 导出格式，json与ndjson相同
+	CallbackURL string      `json:"callback_url,optional"`                                   // 回调通知地址，完成或失败时POST通知
 }
 
 type ExportResponse struct {
@@ -46,26 +49,198 @@ type ExportResponse struct {
 	TaskID string `json:"task_id"` // 导出任务ID
 }
 
+type ExportTaskRequest struct {
+	TaskID string `path:"task_id"` // 导出任务ID
+}
+
+type ExportDownloadRequest struct {
+	TaskID string `path:"task_id"` // 导出任务ID
+	Token  string `form:"token"`   // 下载令牌，取自任务详情或回调中的下载链接
+}
+
+type ExportListRequest struct {
+	TenantID string `form:"tenant_id,optional"`                                                // 租户ID，非管理员固定为本租户
+	Status   string `form:"status,optional,options=pending|running|succeeded|failed|canceled"` // 任务状态
+	Page     int    `form:"page,default=1"`                                                    // 分页页码，默认1
+	PageSize int    `form:"page_size,default=20"`                                              // 每页大小，默认20
+}
+
+type ExportTask struct {
+	TaskID      string `json:"task_id"`                // 导出任务ID
+	TenantID    string `json:"tenant_id"`              // 租户ID
+	UserID      string `json:"user_id,omitempty"`      // 提交人
+	Format      string `json:"format"`                 // 导出格式
+	Status      string `json:"status"`                 // pending/running/succeeded/failed/canceled
+	Progress    int64  `json:"progress"`               // 已写出的行数
+	Total       int64  `json:"total"`                  // 满足条件的总行数，开始执行后统计
+	FileSize    int64  `json:"file_size,omitempty"`    // 文件大小（字节）
+	Error       string `json:"error,omitempty"`        // 失败原因
+	DownloadURL string `json:"download_url,omitempty"` // 带签名的下载链接，仅成功的任务返回
+	LinkExpires int64  `json:"link_expires,omitempty"` // 下载链接过期时间戳（毫秒）
+	CreatedAt   int64  `json:"created_at"`             // 提交时间戳（毫秒）
+	StartedAt   int64  `json:"started_at,omitempty"`   // 开始执行时间戳（毫秒）
+	FinishedAt  int64  `json:"finished_at,omitempty"`  // 结束时间戳（毫秒）
+	ExpiresAt   int64  `json:"expires_at,omitempty"`   // 任务及文件的保留截止时间戳（毫秒）
+}
+
+type ExportTaskResponse struct {
+	BaseResponse
+	Task ExportTask `json:"task"` // 任务详情
+}
+
+type ExportListResponse struct {
+	Total int64        `json:"total"` // 总任务数
+	List  []ExportTask `json:"list"`  // 任务列表，按提交时间倒序
+}
+
+type LogRecord struct {
+	LogId          string            `json:"log_id"`               // 日志ID
+	TenantID       string            `json:"tenant_id"`            // 租户ID
+	UserID         string            `json:"user_id"`              // 用户ID
+	Username       string            `json:"username"`             // 用户名
+	Action         string            `json:"action"`               // 操作名称
+	ResourceType   string            `json:"resource_type"`        // 资源类型
+	ResourceID     string            `json:"resource_id"`          // 操作对象ID
+	ResourceName   string            `json:"resource_name"`        // 操作对象名称
+	Result         string            `json:"result"`               // 操作结果
+	Message        string            `json:"message"`              // 失败或详细信息
+	Timestamp      int64             `json:"timestamp"`            // 客户端上报的时间戳（毫秒）
+	ClientIP       string            `json:"client_ip"`            // 客户端IP
+	Module         string            `json:"module"`               // 模块
+	TraceID        string            `json:"trace_id"`             // 链路追踪ID
+	IdempotencyKey string            `json:"idempotency_key"`      // 幂等键
+	Seq            int64             `json:"seq"`                  // 租户内哈希链序号
+	PrevHash       string            `json:"prev_hash"`            // 前一条记录的哈希
+	Hash           string            `json:"hash"`                 // 本条记录的哈希
+	CreatedAt      int64             `json:"created_at"`           // 入库时间戳（毫秒）
+	UpdatedAt      int64             `json:"updated_at"`           // 更新时间戳（毫秒）
+	Highlights     map[string]string `json:"highlights,omitempty"` // 关键字搜索命中的高亮片段，键为字段名
+	Selected       map[string]bool   `json:"-"`                    // 投影字段，为空时返回全部字段
+}
+
 type QueryRequest struct {
-	TenantID     string `form:"tenant_id"`              // 租户ID，必填
-	UserID       string `form:"user_id,optional"`       // 用户ID，可选
-	Username     string `form:"username,optional"`      // 用户名，可选
-	Action       string `form:"action,optional"`        // 操作类型
-	ResourceType string `form:"resource_type,optional"` // 资源类型
-	ResourceID   string `form:"resource_id,optional"`   // 资源ID
-	Result       string `form:"result,optional"`        // 操作结果
-	Keyword      string `form:"keyword,optional"`       // 关键字模糊搜索
-	StartTime    int64  `form:"start_time,optional"`    // 起始时间戳（毫秒）
-	EndTime      int64  `form:"end_time,optional"`      // 结束时间戳（毫秒）
-	Page         int    `form:"page,default=1"`         // 分页页码，默认1
-	PageSize     int    `form:"page_size,default=20"`   // 每页大小，默认20
-	SortField    string `form:"sort_field,optional"`    // 排序字段，默认created_at
-	SortOrder    string `form:"sort_order,optional"`    // 排序方向，默认desc
+	TenantID     string `form:"tenant_id"`                                       // 租户ID，必填
+	UserID       string `form:"user_id,optional"`                                // 用户ID，可选
+	Username     string `form:"username,optional"`                               // 用户名，可选
+	Action       string `form:"action,optional"`                                 // 操作类型
+	ResourceType string `form:"resource_type,optional"`                          // 资源类型
+	ResourceID   string `form:"resource_id,optional"`                            // 资源ID
+	Result       string `form:"result,optional"`                                 // 操作结果
+	Keyword      string `form:"keyword,optional"`                                // 关键字模糊搜索
+	StartTime    int64  `form:"start_time,optional"`                             // 起始时间戳（毫秒）
+	EndTime      int64  `form:"end_time,optional"`                               // 结束时间戳（毫秒）
+	Page         int    `form:"page,default=1"`                                  // 分页页码，默认1
+	PageSize     int    `form:"page_size,default=20"`                            // 每页大小，默认20
+	SortField    string `form:"sort_field,optional"`                             // 排序字段，默认created_at
+	SortOrder    string `form:"sort_order,optional"`                             // 排序方向，默认desc
+	Cursor       string `form:"cursor,optional"`                                 // 游标，取上一页返回的next_cursor，传入时忽略page
+	Fields       string `form:"fields,optional"`                                 // 只返回指定字段，逗号分隔，log_id总会返回
+	TotalMode    string `form:"total_mode,default=exact,options=exact|estimate"` // 总数统计方式，estimate取执行计划的估算值
 }
 
 type QueryResponse struct {
-	Total int        `json:"total"` // 总记录数
-	List  []AuditLog `json:"list"`  // 日志列表
+	Total      int         `json:"total"`                 // 总记录数
+	Estimated  bool        `json:"estimated,omitempty"`   // 总数是否为估算值
+	List       []LogRecord `json:"list"`                  // 日志列表
+	NextCursor string      `json:"next_cursor,omitempty"` // 下一页游标，没有更多数据时为空
+}
+
+type StreamRequest struct {
+	TenantID     string `form:"tenant_id,optional"`                       // 租户ID，非管理员固定为本租户
+	UserID       string `form:"user_id,optional"`                         // 用户ID
+	Username     string `form:"username,optional"`                        // 用户名
+	Action       string `form:"action,optional"`                          // 操作类型
+	ResourceType string `form:"resource_type,optional"`                   // 资源类型
+	ResourceID   string `form:"resource_id,optional"`                     // 资源ID
+	Result       string `form:"result,optional"`                          // 操作结果
+	Keyword      string `form:"keyword,optional"`                         // 关键字搜索
+	StartTime    int64  `form:"start_time,optional"`                      // 起始时间戳（毫秒）
+	EndTime      int64  `form:"end_time,optional"`                        // 结束时间戳（毫秒）
+	SortOrder    string `form:"sort_order,default=desc,options=asc|desc"` // 按创建时间排序的方向
+	Fields       string `form:"fields,optional"`                          // 只返回指定字段，逗号分隔，log_id总会返回
+	Limit        int64  `form:"limit,optional"`                           // 最多返回的行数，不超过角色的限制
+	Format       string `form:"format,default=ndjson,options=ndjson|sse"` // 输出格式，请求头Accept为text/event-stream时使用sse
+}
+
+type TailRequest struct {
+	TenantID string `form:"tenant_id,optional"` // 租户ID，非管理员固定为本租户
+	Filter   string `form:"filter,optional"`    // 查询条件DSL的JSON，同search接口
+	Fields   string `form:"fields,optional"`    // 只返回指定字段，逗号分隔，log_id总会返回
+}
+
+type VerifyRequest struct {
+	TenantID string `form:"tenant_id"` // 租户ID，必填
+}
+
+type VerifyResponse struct {
+	TenantID    string `json:"tenant_id"`
+	Valid       bool   `json:"valid"`                   // 哈希链是否完整
+	Checked     int64  `json:"checked"`                 // 已校验的记录数
+	LastSeq     int64  `json:"last_seq"`                // 最后一条连续有效记录的序号
+	HeadSeq     int64  `json:"head_seq"`                // 链头已分配的序号
+	BrokenSeq   int64  `json:"broken_seq,omitempty"`    // 首个断裂位置
+	BrokenLogId string `json:"broken_log_id,omitempty"` // 首个断裂位置的日志ID
+	BrokenTable string `json:"broken_table,omitempty"`  // 首个断裂位置所在分表
+	Reason      string `json:"reason,omitempty"`        // missing/hash_mismatch/prev_hash_mismatch
+}
+
+type ProofRequest struct {
+	LogId string `form:"log_id"` // 日志ID，必填
+}
+
+type ProofNode struct {
+	Hash string `json:"hash"` // 兄弟节点哈希(hex)
+	Left bool   `json:"left"` // 兄弟节点是否位于左侧
+}
+
+type ProofResponse struct {
+	LogId       string      `json:"log_id"`
+	TenantID    string      `json:"tenant_id"`
+	LeafHash    string      `json:"leaf_hash"`    // 叶子哈希 H(0x00 || canonical(record))
+	LeafIndex   int         `json:"leaf_index"`   // 叶子在窗口内按log_id排序后的位置
+	Proof       []ProofNode `json:"proof"`        // 自底向上的证明路径，内部节点为 H(0x01 || left || right)
+	Root        string      `json:"root"`         // 检查点默克尔根
+	LeafCount   int64       `json:"leaf_count"`   // 窗口内叶子数
+	WindowStart int64       `json:"window_start"` // 窗口起始时间戳（毫秒）
+	WindowEnd   int64       `json:"window_end"`   // 窗口结束时间戳（毫秒）
+	Signature   string      `json:"signature"`    // 检查点签名(base64)
+	KeyID       string      `json:"key_id"`       // 签名公钥指纹
+	PublicKey   string      `json:"public_key"`   // 当前签名公钥(base64)，未配置时为空
+}
+
+type SearchRequest struct {
+	TenantID  string         `json:"tenant_id,optional"`                              // 租户ID，非管理员固定为本租户
+	Filter    map[string]any `json:"filter,optional"`                                 // 查询条件DSL，支持and/or/not及eq/ne/in/not_in/prefix/cidr/gt/gte/lt/lte/between
+	Page      int            `json:"page,default=1"`                                  // 分页页码，默认1
+	PageSize  int            `json:"page_size,default=20"`                            // 每页大小，默认20
+	SortField string         `json:"sort_field,optional"`                             // 排序字段，默认created_at
+	SortOrder string         `json:"sort_order,optional"`                             // 排序方向，默认desc
+	Cursor    string         `json:"cursor,optional"`                                 // 游标，取上一页返回的next_cursor，传入时忽略page
+	Fields    []string       `json:"fields,optional"`                                 // 只返回指定字段，log_id总会返回
+	TotalMode string         `json:"total_mode,default=exact,options=exact|estimate"` // 总数统计方式，estimate取执行计划的估算值
+}
+
+type StatsRequest struct {
+	TenantID  string         `json:"tenant_id,optional"`  // 租户ID，非管理员固定为本租户
+	Filter    map[string]any `json:"filter,optional"`     // 查询条件DSL，同search接口
+	GroupBy   []string       `json:"group_by,optional"`   // 分组维度：action/result/module/user_id/resource_type/tenant_id
+	Interval  string         `json:"interval,optional"`   // 按创建时间分桶的间隔：1m/5m/15m/1h/6h/1d/1w，为空不分桶
+	StartTime int64          `json:"start_time,optional"` // 起始时间戳（毫秒），默认结束时间前24小时
+	EndTime   int64          `json:"end_time,optional"`   // 结束时间戳（毫秒，不含），默认当前时间
+	Top       int            `json:"top,optional"`        // 只返回计数最多的前N个维度组合，0表示全部
+}
+
+type StatsBucket struct {
+	Time  int64             `json:"time,omitempty"` // 桶起始时间戳（毫秒），未分桶时为空
+	Keys  map[string]string `json:"keys,omitempty"` // 维度取值，键为维度名
+	Count int64             `json:"count"`
+}
+
+type StatsResponse struct {
+	Total     int64         `json:"total"`      // 满足条件的总记录数
+	StartTime int64         `json:"start_time"` // 实际统计的起始时间戳（毫秒）
+	EndTime   int64         `json:"end_time"`   // 实际统计的结束时间戳（毫秒）
+	Buckets   []StatsBucket `json:"buckets"`
 }
 
 type User struct {
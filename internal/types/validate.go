@@ -2,6 +2,8 @@ package types
 
 import (
 	"context"
+	"net/url"
+	"strings"
 
 	"codexie.com/auditlog/internal/constant"
 	"codexie.com/auditlog/pkg/apierr"
@@ -9,31 +11,158 @@ import (
 )
 
 func (q *QueryRequest) Validate(ctx context.Context) error {
+	if err := validateFields(ctx, q.FieldList()); err != nil {
+		return err
+	}
+	return validatePaging(ctx, &q.Page, &q.PageSize, &q.SortField, &q.SortOrder, q.Cursor)
+}
+
+// FieldList 返回需要投影的字段，未指定时为空
+func (q *QueryRequest) FieldList() []string {
+	return splitFields(q.Fields)
+}
+
+func (q *StreamRequest) Validate(ctx context.Context) error {
+	if err := validateFields(ctx, q.FieldList()); err != nil {
+		return err
+	}
+	if q.Limit < 0 {
+		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "limit must not be negative")
+	}
+	if q.StartTime < 0 || q.EndTime < 0 || (q.EndTime != 0 && q.StartTime > q.EndTime) {
+		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "start_time must be before end_time")
+	}
+	return nil
+}
+
+// FieldList 返回需要投影的字段，未指定时为空
+func (q *StreamRequest) FieldList() []string {
+	return splitFields(q.Fields)
+}
+
+func (q *TailRequest) Validate(ctx context.Context) error {
+	return validateFields(ctx, q.FieldList())
+}
+
+// FieldList 返回需要投影的字段，未指定时为空
+func (q *TailRequest) FieldList() []string {
+	return splitFields(q.Fields)
+}
+
+// splitFields 拆分逗号分隔的字段列表
+func splitFields(s string) []string {
+	if s == "" {
+		return nil
+	}
+	fields := strings.Split(s, ",")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
+
+func (q *SearchRequest) Validate(ctx context.Context) error {
+	if err := validateFields(ctx, q.Fields); err != nil {
+		return err
+	}
+	return validatePaging(ctx, &q.Page, &q.PageSize, &q.SortField, &q.SortOrder, q.Cursor)
+}
+
+// validateFields 校验投影字段
+func validateFields(ctx context.Context, fields []string) error {
+	for _, field := range fields {
+		if !constant.RecordFields[field] {
+			return apierr.WithErrf(logx.WithContext(ctx), "E00001", "fields must be in %v", constant.RecordFields)
+		}
+	}
+	return nil
+}
+
+// validatePaging 校验分页与排序参数并设置默认值
+// 偏移分页每张分表都要读取page*page_size条记录，深度超过MAX_OFFSET时需按游标翻页，传入游标时忽略页码
+func validatePaging(ctx context.Context, page, pageSize *int, sortField, sortOrder *string, cursor string) error {
 	// 参数合法性校验
-	if q.Page < 0 || q.Page > constant.MAX_PAGE {
+	if *page < 0 || *page > constant.MAX_PAGE {
 		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "page must be less than %d", constant.MAX_PAGE)
 	}
-	if q.PageSize < 0 || q.PageSize > constant.MAX_PAGE_SIZE {
+	if *pageSize < 0 || *pageSize > constant.MAX_PAGE_SIZE {
 		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "page_size must be less than %d", constant.MAX_PAGE_SIZE)
 	}
 
-	if q.SortField != "" && !constant.ValidFields[q.SortField] {
+	if *sortField != "" && !constant.ValidFields[*sortField] {
 		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "sort_field must be one of %v", constant.ValidFields)
 	}
 
+	if *sortOrder != "" && *sortOrder != "asc" && *sortOrder != "desc" {
+		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "sort_order must be asc or desc")
+	}
+
 	// 设置参数默认字段
-	if q.SortField == "" {
-		q.SortField = "created_at"
+	if *sortField == "" {
+		*sortField = "created_at"
+	}
+	if *sortOrder == "" {
+		*sortOrder = "desc"
 	}
-	if q.SortOrder == "" {
-		q.SortOrder = "desc"
+	if *page == 0 {
+		*page = 1
 	}
-	if q.Page == 0 {
-		q.Page = 1
+	if *pageSize == 0 {
+		*pageSize = 10
 	}
-	if q.PageSize == 0 {
-		q.PageSize = 10
+	if cursor == "" && *page**pageSize > constant.MAX_OFFSET {
+		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "page*page_size must not exceed %d, use next_cursor to page further", constant.MAX_OFFSET)
 	}
 
 	return nil
 }
+
+func (q *StatsRequest) Validate(ctx context.Context) error {
+	if len(q.GroupBy) > constant.MAX_STATS_DIMENSIONS {
+		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "group_by supports at most %d dimensions", constant.MAX_STATS_DIMENSIONS)
+	}
+	seen := make(map[string]bool, len(q.GroupBy))
+	for _, dim := range q.GroupBy {
+		if !constant.StatsDimensions[dim] || seen[dim] {
+			return apierr.WithErrf(logx.WithContext(ctx), "E00001", "group_by must be distinct values of %v", constant.StatsDimensions)
+		}
+		seen[dim] = true
+	}
+	if _, ok := constant.StatsIntervals[q.Interval]; q.Interval != "" && !ok {
+		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "interval must be one of %v", constant.StatsIntervals)
+	}
+	if q.Top < 0 || q.Top > constant.MAX_STATS_TOP {
+		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "top must be between 0 and %d", constant.MAX_STATS_TOP)
+	}
+	if q.StartTime < 0 || q.EndTime < 0 || (q.EndTime != 0 && q.StartTime >= q.EndTime) {
+		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "start_time must be before end_time")
+	}
+	return nil
+}
+
+func (q *ExportRequest) Validate(ctx context.Context) error {
+	if err := validateFields(ctx, q.Fields); err != nil {
+		return err
+	}
+	if q.CallbackURL != "" {
+		u, err := url.Parse(q.CallbackURL)
+		// 主机白名单在提交时校验，白名单外的主机由回调客户端在连接时拒绝内网地址
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return apierr.WithErrf(logx.WithContext(ctx), "E00001", "callback_url must be an http(s) url")
+		}
+	}
+	if q.Query.StartTime < 0 || q.Query.EndTime < 0 || (q.Query.EndTime != 0 && q.Query.StartTime > q.Query.EndTime) {
+		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "start_time must be before end_time")
+	}
+	return nil
+}
+
+func (q *ExportListRequest) Validate(ctx context.Context) error {
+	if q.Page < 1 || q.Page > constant.MAX_PAGE {
+		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "page must be between 1 and %d", constant.MAX_PAGE)
+	}
+	if q.PageSize < 1 || q.PageSize > constant.MAX_PAGE_SIZE {
+		return apierr.WithErrf(logx.WithContext(ctx), "E00001", "page_size must be between 1 and %d", constant.MAX_PAGE_SIZE)
+	}
+	return nil
+}
@@ -4,3 +4,39 @@ package apierr
 var (
 	ErrInvalidParams = WithErr("E00000", "参数校验错误")
 )
+
+// 限流错误
+var (
+	ErrRateLimited   = WithErr("E00002", "租户上报速率超过限制")
+	ErrQuotaExceeded = WithErr("E00003", "租户当日上报量超过配额")
+)
+
+// 检查点错误
+var (
+	ErrLogNotFound        = WithErr("E00004", "日志不存在")
+	ErrNotCheckpointed    = WithErr("E00005", "日志所在窗口尚未生成检查点")
+	ErrCheckpointMismatch = WithErr("E00006", "窗口数据与检查点不一致，数据可能被篡改")
+)
+
+// 导出任务错误
+var (
+	ErrExportNotFound = WithErr("E00007", "导出任务不存在")
+	ErrExportFinished = WithErr("E00008", "导出任务已结束，无法取消")
+	ErrExportNotReady = WithErr("E00009", "导出文件尚未生成或已过期")
+	ErrDownloadDenied = WithErr("E00010", "下载链接无效或已过期")
+)
+
+// 实时订阅错误
+var (
+	ErrTooManySubscribers = WithErr("E00011", "实时订阅数已达上限，请稍后重试")
+)
+
+// 租户权限错误
+var (
+	ErrTenantRequired = WithErr("E00012", "无法确认调用方的租户，请指定租户ID")
+)
+
+// 身份认证错误
+var (
+	ErrUnauthenticated = WithErr("E00013", "未登录或身份令牌无效")
+)
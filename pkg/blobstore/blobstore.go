@@ -0,0 +1,83 @@
+// Package blobstore 导出文件等制品的存储
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrNotFound = errors.New("blobstore: object not found")
+
+// Info 对象元信息
+type Info struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Store 制品存储，key为以/分隔的相对路径，对象不存在时返回ErrNotFound
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Open 从offset处开始读取对象直到结尾
+	Open(ctx context.Context, key string, offset int64) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (*Info, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewReader 返回可Seek的对象读取器，每次Seek后从新位置重新打开对象，
+// 可直接用于http.ServeContent处理Range请求
+func NewReader(ctx context.Context, store Store, key string, size int64) io.ReadSeekCloser {
+	return &reader{ctx: ctx, store: store, key: key, size: size}
+}
+
+type reader struct {
+	ctx    context.Context
+	store  Store
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.store.Open(r.ctx, r.key, r.offset)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, errors.New("blobstore: negative position")
+	}
+	if offset != r.offset {
+		r.Close()
+		r.offset = offset
+	}
+	return offset, nil
+}
+
+func (r *reader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
@@ -0,0 +1,53 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal_PutDelete(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocal(dir)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "t1/job.csv", strings.NewReader("a,b\n"), 4))
+	data, err := os.ReadFile(filepath.Join(dir, "t1", "job.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "a,b\n", string(data))
+
+	assert.Error(t, store.Put(ctx, "t1/short.csv", strings.NewReader("a"), 4))
+	_, err = os.Stat(filepath.Join(dir, "t1", "short.csv"))
+	assert.True(t, os.IsNotExist(err))
+
+	info, err := store.Stat(ctx, "t1/job.csv")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), info.Size)
+	r := NewReader(ctx, store, "t1/job.csv", info.Size)
+	_, err = r.Seek(-2, io.SeekEnd)
+	require.NoError(t, err)
+	data, err = io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "b\n", string(data))
+	require.NoError(t, r.Close())
+
+	require.NoError(t, store.Delete(ctx, "t1/job.csv"))
+	require.NoError(t, store.Delete(ctx, "t1/job.csv"))
+	_, err = store.Stat(ctx, "t1/job.csv")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocal_InvalidKey(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	require.NoError(t, err)
+	for _, key := range []string{"", "../x", "/etc/passwd", "a/../../x"} {
+		assert.Error(t, store.Put(context.Background(), key, strings.NewReader(""), 0), key)
+	}
+}
@@ -0,0 +1,40 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type page struct {
+	Value int64  `json:"v"`
+	Id    string `json:"i"`
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	key := []byte("secret")
+	token, err := Encode(key, page{Value: 42, Id: "01JAX_3"})
+	require.NoError(t, err)
+
+	var got page
+	require.NoError(t, Decode(key, token, &got))
+	assert.Equal(t, page{Value: 42, Id: "01JAX_3"}, got)
+}
+
+func TestCursor_Tampered(t *testing.T) {
+	key := []byte("secret")
+	token, err := Encode(key, page{Value: 42})
+	require.NoError(t, err)
+
+	var got page
+	assert.ErrorIs(t, Decode([]byte("other"), token, &got), ErrInvalidCursor)
+
+	forged, _ := Encode([]byte("other"), page{Value: 1})
+	body, _, _ := strings.Cut(forged, ".")
+	_, sig, _ := strings.Cut(token, ".")
+	assert.ErrorIs(t, Decode(key, body+"."+sig, &got), ErrInvalidCursor)
+
+	assert.ErrorIs(t, Decode(key, "garbage", &got), ErrInvalidCursor)
+}
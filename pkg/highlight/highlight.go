@@ -0,0 +1,88 @@
+package highlight
+
+import (
+	"html"
+	"strings"
+)
+
+const (
+	DefaultPreTag  = "<em>"
+	DefaultPostTag = "</em>"
+)
+
+// Highlighter 关键字高亮，截取首个命中位置附近的片段并标记其中所有命中
+// 原文按HTML转义后再插入标签，避免日志内容被当作HTML渲染
+type Highlighter struct {
+	Radius  int // 命中位置前后保留的字符数，0表示返回全文
+	PreTag  string
+	PostTag string
+}
+
+func New(radius int) *Highlighter {
+	return &Highlighter{Radius: radius, PreTag: DefaultPreTag, PostTag: DefaultPostTag}
+}
+
+// Snippet 返回高亮片段，未命中时返回false；匹配忽略大小写
+func (h *Highlighter) Snippet(text, keyword string) (string, bool) {
+	if keyword == "" {
+		return "", false
+	}
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	kw := []rune(strings.ToLower(keyword))
+	// 大小写转换改变了字符数时无法按位置对应，退回逐字节的区分大小写匹配
+	if len(lower) != len(runes) {
+		lower, kw = runes, []rune(keyword)
+	}
+
+	first := indexRunes(lower, kw, 0)
+	if first < 0 {
+		return "", false
+	}
+	start, end := 0, len(runes)
+	if h.Radius > 0 {
+		start = max(0, first-h.Radius)
+		end = min(len(runes), first+len(kw)+h.Radius)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	for pos := start; pos < end; {
+		i := indexRunes(lower[:end], kw, pos)
+		if i < 0 {
+			b.WriteString(html.EscapeString(string(runes[pos:end])))
+			break
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:i])))
+		b.WriteString(h.PreTag)
+		b.WriteString(html.EscapeString(string(runes[i : i+len(kw)])))
+		b.WriteString(h.PostTag)
+		pos = i + len(kw)
+	}
+	if end < len(runes) {
+		b.WriteString("...")
+	}
+	return b.String(), true
+}
+
+// indexRunes 从from开始查找子串位置
+func indexRunes(s, sub []rune, from int) int {
+	if len(sub) == 0 {
+		return -1
+	}
+	for i := from; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
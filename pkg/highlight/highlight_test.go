@@ -0,0 +1,34 @@
+package highlight
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnippet(t *testing.T) {
+	h := New(0)
+
+	s, ok := h.Snippet("Delete VM vm-01, delete disk", "delete")
+	assert.True(t, ok)
+	assert.Equal(t, "<em>Delete</em> VM vm-01, <em>delete</em> disk", s)
+
+	_, ok = h.Snippet("create vm", "delete")
+	assert.False(t, ok)
+
+	s, ok = h.Snippet("用户删除了虚拟机", "删除")
+	assert.True(t, ok)
+	assert.Equal(t, "用户<em>删除</em>了虚拟机", s)
+}
+
+func TestSnippet_RadiusAndEscape(t *testing.T) {
+	h := New(3)
+
+	s, ok := h.Snippet("0123456789 token abcdefghij", "token")
+	assert.True(t, ok)
+	assert.Equal(t, "...89 <em>token</em> ab...", s)
+
+	s, ok = h.Snippet("<b>x</b> hit", "hit")
+	assert.True(t, ok)
+	assert.Equal(t, "...b&gt; <em>hit</em>", s)
+}
@@ -0,0 +1,66 @@
+package idgen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// 生成器类型
+const (
+	KindUUID      = "uuid"      // 随机UUID，无序，仅用于兼容
+	KindULID      = "ulid"      // 毫秒时间戳+随机数，按字典序递增
+	KindSnowflake = "snowflake" // 毫秒时间戳+机器号+序号，机器号通过Redis租约分配
+)
+
+// shardSep 日志ID与分表序号之间的分隔符，生成器输出中不得包含该字符
+const shardSep = "_"
+
+// Generator 日志ID生成器，只生成ID主体，分表序号由Format追加
+type Generator interface {
+	Next() (string, error)
+}
+
+// Format 拼接日志ID与分表序号，格式为 <id>_<shard>
+func Format(id string, shard int) string {
+	return id + shardSep + strconv.Itoa(shard)
+}
+
+// Parse 从日志ID中解析ID主体与分表序号，兼容历史的 uuid_<shard> 格式
+func Parse(logId string) (id string, shard int, ok bool) {
+	i := strings.LastIndex(logId, shardSep)
+	if i < 0 {
+		return logId, 0, false
+	}
+	shard, err := strconv.Atoi(logId[i+1:])
+	if err != nil {
+		return logId, 0, false
+	}
+	return logId[:i], shard, true
+}
+
+// UUID 随机UUID生成器
+type UUID struct{}
+
+func (UUID) Next() (string, error) {
+	return uuid.New().String(), nil
+}
+
+// New 按类型创建生成器，snowflake需传入已获取的机器号租约
+func New(kind string, lease *WorkerLease) (Generator, error) {
+	switch kind {
+	case KindUUID:
+		return UUID{}, nil
+	case KindULID, "":
+		return NewULID(), nil
+	case KindSnowflake:
+		if lease == nil {
+			return nil, fmt.Errorf("idgen: snowflake requires a worker lease")
+		}
+		return NewSnowflake(lease), nil
+	default:
+		return nil, fmt.Errorf("idgen: unknown generator %q", kind)
+	}
+}
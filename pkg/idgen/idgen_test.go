@@ -0,0 +1,121 @@
+package idgen
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	id, shard, ok := Parse("0b3c6f1e-3f7a-4c1e-9a55-2d3f4b5c6d7e_12")
+	assert.True(t, ok)
+	assert.Equal(t, "0b3c6f1e-3f7a-4c1e-9a55-2d3f4b5c6d7e", id)
+	assert.Equal(t, 12, shard)
+
+	id, shard, ok = Parse(Format("01JAX3Y8Z5KQ0000000000000A", 3))
+	assert.True(t, ok)
+	assert.Equal(t, "01JAX3Y8Z5KQ0000000000000A", id)
+	assert.Equal(t, 3, shard)
+
+	_, _, ok = Parse("no-shard")
+	assert.False(t, ok)
+	_, _, ok = Parse("abc_x")
+	assert.False(t, ok)
+}
+
+func TestULID_Monotonic(t *testing.T) {
+	g := NewULID()
+	fixed := time.UnixMilli(1700000000000)
+	g.now = func() time.Time { return fixed }
+
+	ids := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		id, err := g.Next()
+		require.NoError(t, err)
+		require.Len(t, id, 26)
+		assert.NotContains(t, id, shardSep)
+		ids = append(ids, id)
+	}
+	assert.True(t, sort.StringsAreSorted(ids))
+
+	// 时钟回拨仍保持递增
+	g.now = func() time.Time { return fixed.Add(-time.Second) }
+	id, err := g.Next()
+	require.NoError(t, err)
+	assert.Greater(t, id, ids[len(ids)-1])
+
+	// 时间戳编码在前10个字符
+	g2 := NewULID()
+	g2.now = func() time.Time { return fixed.Add(time.Millisecond) }
+	later, _ := g2.Next()
+	assert.Greater(t, later[:10], ids[0][:10])
+}
+
+func TestSnowflake(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+
+	lease, err := LeaseWorker(context.Background(), client, "", time.Minute)
+	require.NoError(t, err)
+
+	g := NewSnowflake(lease)
+	fixed := time.Now()
+	g.now = func() time.Time { return fixed }
+	prev := ""
+	for i := 0; i < maxSequence+10; i++ {
+		id, err := g.Next()
+		require.NoError(t, err)
+		require.Len(t, id, 19)
+		assert.Greater(t, id, prev)
+		prev = id
+	}
+
+	// 机器号被其他实例占用后停止发号
+	lost := lease.key
+	s.Set(lost, "other")
+	lease.renew()
+	_, err = g.Next()
+	assert.ErrorIs(t, err, ErrWorkerLost)
+
+	// 下次续期时占用其他空闲机器号后恢复发号
+	lease.renew()
+	assert.NotEqual(t, lost, lease.key)
+	_, err = g.Next()
+	assert.NoError(t, err)
+
+	// 关闭时释放机器号
+	require.NoError(t, lease.Close())
+	assert.False(t, s.Exists(lease.key))
+}
+
+func TestLeaseWorker_Unique(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+
+	seen := make(map[int]bool)
+	for i := 0; i < 20; i++ {
+		lease, err := LeaseWorker(context.Background(), client, "test:", time.Minute)
+		require.NoError(t, err)
+		assert.False(t, seen[lease.ID()])
+		seen[lease.ID()] = true
+		assert.True(t, strings.HasPrefix(lease.key, "test:"))
+	}
+}
+
+func TestNew(t *testing.T) {
+	g, err := New("", nil)
+	require.NoError(t, err)
+	assert.IsType(t, &ULID{}, g)
+
+	_, err = New(KindSnowflake, nil)
+	assert.Error(t, err)
+	_, err = New("bogus", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// Crockford base32字母表，按ASCII递增，编码后的字符串保持数值顺序
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID 单调ULID生成器：48位毫秒时间戳+80位随机数，共26个字符
+// 同一毫秒内随机部分递增，时钟回拨时沿用上次的时间戳，保证同一进程内严格递增
+type ULID struct {
+	mu     sync.Mutex
+	lastMs uint64
+	randHi uint64 // 随机数高16位
+	randLo uint64 // 随机数低64位
+	now    func() time.Time
+}
+
+func NewULID() *ULID {
+	return &ULID{now: time.Now}
+}
+
+func (g *ULID) Next() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(g.now().UnixMilli())
+	if ms > g.lastMs {
+		var buf [10]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			return "", err
+		}
+		g.lastMs = ms
+		g.randHi = uint64(binary.BigEndian.Uint16(buf[:2]))
+		g.randLo = binary.BigEndian.Uint64(buf[2:])
+	} else {
+		// 随机部分加一，溢出时借用下一毫秒
+		g.randLo++
+		if g.randLo == 0 {
+			g.randHi = (g.randHi + 1) & 0xffff
+			if g.randHi == 0 {
+				g.lastMs++
+			}
+		}
+	}
+
+	return encodeULID(g.lastMs<<16|g.randHi, g.randLo), nil
+}
+
+// encodeULID 将128位数值编码为26个字符，首字符只使用3位
+func encodeULID(hi, lo uint64) string {
+	var out [26]byte
+	for i := range out {
+		out[i] = crockford[bits5(hi, lo, uint(125-5*i))]
+	}
+	return string(out[:])
+}
+
+// bits5 取128位数值从第n位开始的5位
+func bits5(hi, lo uint64, n uint) uint64 {
+	switch {
+	case n >= 64:
+		return (hi >> (n - 64)) & 31
+	case n+5 <= 64:
+		return (lo >> n) & 31
+	default:
+		return ((lo >> n) | (hi << (64 - n))) & 31
+	}
+}
@@ -0,0 +1,162 @@
+package idgen
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	defaultWorkerPrefix = "idgen:worker:"
+	workerRedisTimeout  = time.Second
+)
+
+// 仅当租约仍属于当前实例时续期或释放
+var (
+	renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// WorkerLease 通过Redis租约分配的雪花机器号，后台按ttl/3周期续期
+// Redis短暂不可用时租约在到期前仍然有效；到期或被其他实例占用后生成器停止发号，
+// 被占用时下一个续期周期重新占用一个空闲机器号
+type WorkerLease struct {
+	client *redis.Client
+	prefix string
+	holder string
+	ttl    time.Duration
+	expire atomic.Int64 // 租约到期时间(UnixNano)，以发起请求的时间计算，偏保守
+	lost   atomic.Bool  // 机器号已被其他实例占用
+	stop   chan struct{}
+
+	mu  sync.Mutex
+	key string
+	id  atomic.Int64
+}
+
+// LeaseWorker 从随机位置开始依次尝试占用机器号，全部被占用时返回错误
+func LeaseWorker(ctx context.Context, client *redis.Client, prefix string, ttl time.Duration) (*WorkerLease, error) {
+	if prefix == "" {
+		prefix = defaultWorkerPrefix
+	}
+	host, _ := os.Hostname()
+	l := &WorkerLease{
+		client: client,
+		prefix: prefix,
+		holder: host + "-" + uuid.New().String()[:12],
+		ttl:    ttl,
+		stop:   make(chan struct{}),
+	}
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	go l.renewLoop()
+	return l, nil
+}
+
+// acquire 从随机位置开始依次尝试占用机器号
+func (l *WorkerLease) acquire(ctx context.Context) error {
+	start := rand.Intn(MaxWorkerID + 1)
+	for i := 0; i <= MaxWorkerID; i++ {
+		id := (start + i) % (MaxWorkerID + 1)
+		key := fmt.Sprintf("%s%d", l.prefix, id)
+		now := time.Now()
+		ok, err := l.client.SetNX(ctx, key, l.holder, l.ttl).Result()
+		if err != nil {
+			return fmt.Errorf("idgen: lease worker id: %w", err)
+		}
+		if !ok {
+			continue
+		}
+
+		l.mu.Lock()
+		l.key = key
+		l.id.Store(int64(id))
+		l.mu.Unlock()
+		l.lost.Store(false)
+		l.expire.Store(now.Add(l.ttl).UnixNano())
+		logx.Infof("idgen: leased snowflake worker id %d", id)
+		return nil
+	}
+	return fmt.Errorf("idgen: no free worker id")
+}
+
+// ID 返回机器号
+func (l *WorkerLease) ID() int { return int(l.id.Load()) }
+
+// Valid 租约是否仍然有效
+func (l *WorkerLease) Valid() bool { return time.Now().UnixNano() < l.expire.Load() }
+
+// Close 停止续期并释放机器号
+func (l *WorkerLease) Close() error {
+	close(l.stop)
+	l.expire.Store(0)
+	ctx, cancel := context.WithTimeout(context.Background(), workerRedisTimeout)
+	defer cancel()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return releaseScript.Run(ctx, l.client, []string{l.key}, l.holder).Err()
+}
+
+func (l *WorkerLease) renewLoop() {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.renew()
+		}
+	}
+}
+
+// renew 续期租约，键已过期时尝试重新占用同一机器号；机器号已被其他实例占用时立即失效，
+// 下次续期时重新占用其他空闲机器号
+func (l *WorkerLease) renew() {
+	ctx, cancel := context.WithTimeout(context.Background(), workerRedisTimeout)
+	defer cancel()
+
+	if l.lost.Load() {
+		if err := l.acquire(ctx); err != nil {
+			logx.Errorf("idgen: re-lease worker id failed: %v", err)
+		}
+		return
+	}
+
+	l.mu.Lock()
+	key := l.key
+	l.mu.Unlock()
+	now := time.Now()
+	n, err := renewScript.Run(ctx, l.client, []string{key}, l.holder, l.ttl.Milliseconds()).Int()
+	if err == nil && n == 0 {
+		var ok bool
+		if ok, err = l.client.SetNX(ctx, key, l.holder, l.ttl).Result(); err == nil && !ok {
+			l.expire.Store(0)
+			l.lost.Store(true)
+			logx.Errorf("idgen: worker id %d is taken by another instance", l.ID())
+			return
+		}
+	}
+	if err != nil {
+		logx.Errorf("idgen: renew worker id %d failed: %v", l.ID(), err)
+		return
+	}
+	l.expire.Store(now.Add(l.ttl).UnixNano())
+}
@@ -0,0 +1,126 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+// 叶子节点与内部节点使用不同前缀，防止第二原像攻击(参考RFC 6962)
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+var ErrIndexOutOfRange = errors.New("merkle: leaf index out of range")
+
+// ProofNode 包含证明路径上的一个兄弟节点
+type ProofNode struct {
+	Hash []byte `json:"hash"`
+	Left bool   `json:"left"` // 兄弟节点是否位于左侧
+}
+
+// Tree 默克尔树，levels[0]为叶子哈希，最后一层为根
+// 某层节点数为奇数时，最后一个节点直接提升到上一层
+type Tree struct {
+	levels [][][]byte
+}
+
+// LeafHash 计算叶子哈希 H(0x00 || data)
+func LeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// New 由叶子哈希构建默克尔树
+func New(leaves [][]byte) *Tree {
+	t := &Tree{levels: [][][]byte{leaves}}
+	for level := leaves; len(level) > 1; {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, nodeHash(level[i], level[i+1]))
+		}
+		t.levels = append(t.levels, next)
+		level = next
+	}
+	return t
+}
+
+// Root 返回根哈希，空树返回nil
+func (t *Tree) Root() []byte {
+	top := t.levels[len(t.levels)-1]
+	if len(top) == 0 {
+		return nil
+	}
+	return top[0]
+}
+
+// Levels 按层返回所有节点，levels[0]为叶子哈希，最后一层为根，供持久化整棵树
+func (t *Tree) Levels() [][][]byte {
+	return t.levels
+}
+
+// Proof 返回指定叶子的包含证明，自底向上排列
+func (t *Tree) Proof(index int) ([]ProofNode, error) {
+	positions, err := ProofPositions(len(t.levels[0]), index)
+	if err != nil {
+		return nil, err
+	}
+	proof := make([]ProofNode, 0, len(positions))
+	for _, pos := range positions {
+		proof = append(proof, ProofNode{Hash: t.levels[pos.Level][pos.Index], Left: pos.Left})
+	}
+	return proof, nil
+}
+
+// Position 节点在树中的位置，Left表示作为兄弟节点时位于左侧
+type Position struct {
+	Level int
+	Index int
+	Left  bool
+}
+
+// ProofPositions 返回count个叶子的树中，第index个叶子的证明路径上兄弟节点的位置，自底向上排列
+// 树已持久化时只需读取这些节点即可生成证明，无需重建整棵树
+func ProofPositions(count, index int) ([]Position, error) {
+	if index < 0 || index >= count {
+		return nil, ErrIndexOutOfRange
+	}
+	var positions []Position
+	for level, size := 0, count; size > 1; level, size = level+1, (size+1)/2 {
+		if index%2 == 1 {
+			positions = append(positions, Position{Level: level, Index: index - 1, Left: true})
+		} else if index+1 < size {
+			positions = append(positions, Position{Level: level, Index: index + 1})
+		}
+		index /= 2
+	}
+	return positions, nil
+}
+
+// Verify 校验叶子哈希能否通过证明路径得到根哈希
+func Verify(leaf []byte, proof []ProofNode, root []byte) bool {
+	hash := leaf
+	for _, node := range proof {
+		if node.Left {
+			hash = nodeHash(node.Hash, hash)
+		} else {
+			hash = nodeHash(hash, node.Hash)
+		}
+	}
+	return bytes.Equal(hash, root)
+}
@@ -0,0 +1,74 @@
+package merkle
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func leaves(n int) [][]byte {
+	res := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		res = append(res, LeafHash([]byte(fmt.Sprintf("log-%d", i))))
+	}
+	return res
+}
+
+func TestTree_ProofRoundTrip(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 5, 8, 13} {
+		ls := leaves(n)
+		tree := New(ls)
+		require.NotNil(t, tree.Root())
+		for i := range ls {
+			proof, err := tree.Proof(i)
+			require.NoError(t, err)
+			assert.True(t, Verify(ls[i], proof, tree.Root()), "n=%d i=%d", n, i)
+		}
+	}
+}
+
+func TestTree_TamperDetected(t *testing.T) {
+	ls := leaves(7)
+	tree := New(ls)
+	proof, err := tree.Proof(3)
+	require.NoError(t, err)
+
+	assert.False(t, Verify(LeafHash([]byte("forged")), proof, tree.Root()))
+	assert.False(t, Verify(ls[4], proof, tree.Root()))
+
+	ls[2] = LeafHash([]byte("changed"))
+	assert.NotEqual(t, tree.Root(), New(ls).Root())
+}
+
+func TestTree_Edge(t *testing.T) {
+	assert.Nil(t, New(nil).Root())
+
+	single := leaves(1)
+	assert.Equal(t, single[0], New(single).Root())
+
+	_, err := New(leaves(3)).Proof(3)
+	assert.ErrorIs(t, err, ErrIndexOutOfRange)
+}
+
+func TestProofPositions(t *testing.T) {
+	// 位置只由叶子数和下标决定，按位置取出的节点与Proof一致
+	for _, n := range []int{1, 2, 3, 5, 8, 13} {
+		tree := New(leaves(n))
+		levels := tree.Levels()
+		assert.Equal(t, tree.Root(), levels[len(levels)-1][0])
+		for i := 0; i < n; i++ {
+			positions, err := ProofPositions(n, i)
+			require.NoError(t, err)
+			proof, err := tree.Proof(i)
+			require.NoError(t, err)
+			require.Len(t, positions, len(proof))
+			for k, pos := range positions {
+				assert.Equal(t, proof[k], ProofNode{Hash: levels[pos.Level][pos.Index], Left: pos.Left})
+			}
+		}
+	}
+	_, err := ProofPositions(3, -1)
+	assert.ErrorIs(t, err, ErrIndexOutOfRange)
+}
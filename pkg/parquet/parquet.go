@@ -0,0 +1,233 @@
+// Package parquet 基于parquet-go以行组为单位流式写出Parquet文件，内存中只缓冲当前行组
+// 所有列均为OPTIONAL的扁平列，字符串列使用字典编码，时间列为TIMESTAMP(MILLIS, UTC)，页数据使用snappy压缩
+// 列在文件中按列名排序，读取端应按列名而不是位置取值；testdata/audit_log.parquet为对应的基准文件
+package parquet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	pq "github.com/parquet-go/parquet-go"
+)
+
+// Type 列的逻辑类型
+type Type int
+
+const (
+	String    Type = iota // BYTE_ARRAY，UTF8字符串
+	Int64                 // INT64
+	Timestamp             // INT64，UTC毫秒时间戳
+	Bool                  // BOOLEAN
+)
+
+// Column 列定义
+type Column struct {
+	Name string
+	Type Type
+}
+
+// DefaultRowGroupSize 默认行组大小，按未压缩的数据量估算
+const DefaultRowGroupSize = 64 << 20
+
+var ErrClosed = errors.New("parquet: writer is closed")
+
+// ColumnsOf 按结构体原型中字段的json标签推导列类型，names为nil时返回所有支持的字段
+func ColumnsOf(prototype any, names []string) ([]Column, error) {
+	t := reflect.TypeOf(prototype)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("parquet: prototype must be a struct, got %T", prototype)
+	}
+
+	fields := make(map[string]reflect.Type, t.NumField())
+	var all []Column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+		if typ, ok := typeOf(f.Type); ok {
+			all = append(all, Column{Name: name, Type: typ})
+		}
+	}
+	if names == nil {
+		return all, nil
+	}
+
+	columns := make([]Column, len(names))
+	for i, name := range names {
+		ft, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("parquet: unknown column %q", name)
+		}
+		typ, ok := typeOf(ft)
+		if !ok {
+			return nil, fmt.Errorf("parquet: unsupported type %s of column %q", ft, name)
+		}
+		columns[i] = Column{Name: name, Type: typ}
+	}
+	return columns, nil
+}
+
+func typeOf(t reflect.Type) (Type, bool) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return Timestamp, true
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return String, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Int64, true
+	case reflect.Bool:
+		return Bool, true
+	}
+	return 0, false
+}
+
+// node 列对应的Parquet节点
+func (c Column) node() pq.Node {
+	switch c.Type {
+	case String:
+		return pq.Optional(pq.Encoded(pq.String(), &pq.RLEDictionary))
+	case Timestamp:
+		return pq.Optional(pq.Timestamp(pq.Millisecond))
+	case Bool:
+		return pq.Optional(pq.Leaf(pq.BooleanType))
+	}
+	return pq.Optional(pq.Int(64))
+}
+
+// Writer 逐行写入，缓冲的数据达到行组大小后写出一个行组，Close时写入文件尾
+// Close不关闭底层io.Writer
+type Writer struct {
+	w            *pq.GenericWriter[map[string]any]
+	columns      []Column
+	rowGroupSize int64
+	buffered     int64 // 当前行组已缓冲的数据量
+	row          []map[string]any
+	err          error
+}
+
+// NewWriter 按列定义创建写入器，rowGroupSize不大于0时使用DefaultRowGroupSize
+func NewWriter(w io.Writer, columns []Column, rowGroupSize int64) (*Writer, error) {
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+	group := make(pq.Group, len(columns))
+	for _, col := range columns {
+		if _, ok := group[col.Name]; ok {
+			return nil, fmt.Errorf("parquet: duplicate column %q", col.Name)
+		}
+		group[col.Name] = col.node()
+	}
+	schema := pq.NewSchema("audit_log", group)
+	return &Writer{
+		w:            pq.NewGenericWriter[map[string]any](w, schema, pq.Compression(&pq.Snappy)),
+		columns:      columns,
+		rowGroupSize: rowGroupSize,
+		row:          make([]map[string]any, 1),
+	}, nil
+}
+
+// Write 写入一行，row与列定义一一对应，nil和零值时间写为null
+func (w *Writer) Write(row []any) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values, expected %d", len(row), len(w.columns))
+	}
+	values := make(map[string]any, len(w.columns))
+	for i, col := range w.columns {
+		v, n, err := col.value(row[i])
+		if err != nil {
+			return err
+		}
+		values[col.Name] = v
+		w.buffered += n
+	}
+	w.row[0] = values
+	if _, err := w.w.Write(w.row); err != nil {
+		w.err = err
+		return err
+	}
+	if w.buffered >= w.rowGroupSize {
+		w.buffered = 0
+		if err := w.w.Flush(); err != nil {
+			w.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 写出剩余数据和文件尾
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.w.Close(); err != nil {
+		w.err = err
+		return err
+	}
+	w.err = ErrClosed
+	return nil
+}
+
+// value 将值转换为列类型对应的Go值，同时返回估算的数据量
+func (c Column) value(v any) (any, int64, error) {
+	if v == nil {
+		return nil, 1, nil
+	}
+	switch c.Type {
+	case String:
+		s, ok := v.(string)
+		if !ok {
+			s = fmt.Sprint(v)
+		}
+		return s, int64(len(s) + 4), nil
+	case Timestamp:
+		switch t := v.(type) {
+		case time.Time:
+			if t.IsZero() {
+				return nil, 1, nil
+			}
+			return t, 8, nil
+		case *time.Time:
+			if t == nil || t.IsZero() {
+				return nil, 1, nil
+			}
+			return *t, 8, nil
+		}
+		return nil, 0, fmt.Errorf("parquet: column %s expects time.Time, got %T", c.Name, v)
+	case Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, 0, fmt.Errorf("parquet: column %s expects bool, got %T", c.Name, v)
+		}
+		return b, 1, nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), 8, nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int64(rv.Uint()), 8, nil
+	}
+	return nil, 0, fmt.Errorf("parquet: column %s expects an integer, got %T", c.Name, v)
+}
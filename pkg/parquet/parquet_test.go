@@ -0,0 +1,195 @@
+package parquet
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/audit_log.parquet")
+
+const goldenFile = "testdata/audit_log.parquet"
+
+type record struct {
+	LogId     string     `json:"log_id"`
+	TenantID  string     `json:"tenant_id"`
+	Seq       int64      `json:"seq"`
+	OK        bool       `json:"ok"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Tags      []string   `json:"tags"`
+	internal  string
+}
+
+func TestColumnsOf(t *testing.T) {
+	cols, err := ColumnsOf(&record{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Column{
+		{"log_id", String}, {"tenant_id", String}, {"seq", Int64}, {"ok", Bool},
+		{"created_at", Timestamp}, {"deleted_at", Timestamp},
+	}, cols)
+
+	cols, err = ColumnsOf(record{}, []string{"seq", "log_id"})
+	require.NoError(t, err)
+	assert.Equal(t, []Column{{"seq", Int64}, {"log_id", String}}, cols)
+
+	_, err = ColumnsOf(record{}, []string{"tags"})
+	assert.Error(t, err)
+	_, err = ColumnsOf(record{}, []string{"missing"})
+	assert.Error(t, err)
+}
+
+// goldenRows 覆盖字符串列、空值、布尔列和时间列，行组很小时分布在多个行组中
+// want按列名取值，整数和时间列的值以int64表示
+func goldenRows() (cols []Column, rows [][]any, want []map[string]any) {
+	cols = []Column{{"log_id", String}, {"tenant_id", String}, {"seq", Int64}, {"ok", Bool}, {"created_at", Timestamp}}
+	base := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	for i := 0; i < 50; i++ {
+		at := base.Add(time.Duration(i) * time.Second)
+		row := []any{fmt.Sprintf("01J_%03d", i), fmt.Sprintf("t%d", i%2), int64(i), i%3 == 0, at}
+		expect := map[string]any{"log_id": row[0], "tenant_id": row[1], "seq": int64(i), "ok": row[3], "created_at": at.UnixMilli()}
+		if i%7 == 0 {
+			row[1], row[4] = nil, time.Time{}
+			expect["tenant_id"], expect["created_at"] = nil, nil
+		}
+		rows = append(rows, row)
+		want = append(want, expect)
+	}
+	return cols, rows, want
+}
+
+func writeGolden(t *testing.T) []byte {
+	t.Helper()
+	cols, rows, _ := goldenRows()
+	var buf bytes.Buffer
+	// 行组很小，写入的数据会分布在多个行组中
+	w, err := NewWriter(&buf, cols, 300)
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.NoError(t, w.Write(row))
+	}
+	require.NoError(t, w.Close())
+	assert.ErrorIs(t, w.Write(rows[0]), ErrClosed)
+	return buf.Bytes()
+}
+
+func TestWriter(t *testing.T) {
+	_, _, want := goldenRows()
+	data := writeGolden(t)
+
+	f, err := pq.OpenFile(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(want)), f.NumRows())
+	assert.Greater(t, len(f.RowGroups()), 1)
+
+	fields := map[string]pq.Field{}
+	for _, field := range f.Schema().Fields() {
+		fields[field.Name()] = field
+		assert.True(t, field.Optional(), field.Name())
+	}
+	assert.Len(t, fields, 5)
+	assert.NotNil(t, fields["tenant_id"].Type().LogicalType().UTF8)
+	ts := fields["created_at"].Type().LogicalType().Timestamp
+	require.NotNil(t, ts)
+	assert.True(t, ts.IsAdjustedToUTC)
+	assert.NotNil(t, ts.Unit.Millis)
+
+	// 字符串列的数据页使用字典编码
+	for _, rg := range f.Metadata().RowGroups {
+		for _, chunk := range rg.Columns {
+			if chunk.MetaData.Type == format.ByteArray {
+				assert.Contains(t, chunk.MetaData.Encoding, format.RLEDictionary, chunk.MetaData.PathInSchema)
+			}
+		}
+	}
+
+	assert.Equal(t, want, readRows(t, data))
+}
+
+// TestWriter_Golden 写出的字节与testdata中的文件一致；升级parquet-go或变更schema后用 go test -run Golden -update 重新生成，
+// 并确认新文件仍能被其他读取端(如pyarrow、DuckDB)读取
+func TestWriter_Golden(t *testing.T) {
+	data := writeGolden(t)
+	if *update {
+		require.NoError(t, os.WriteFile(goldenFile, data, 0o644))
+	}
+	golden, err := os.ReadFile(goldenFile)
+	require.NoError(t, err)
+	assert.Equal(t, golden, data)
+
+	_, _, want := goldenRows()
+	assert.Equal(t, want, readRows(t, golden))
+}
+
+func TestWriter_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []Column{{"log_id", String}}, 0)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Empty(t, readRows(t, buf.Bytes()))
+}
+
+func TestWriter_TypeMismatch(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, []Column{{"seq", Int64}, {"seq", String}}, 0)
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []Column{{"seq", Int64}}, 0)
+	require.NoError(t, err)
+	assert.Error(t, w.Write([]any{"x"}))
+	assert.Error(t, w.Write([]any{int64(1), int64(2)}))
+	// 类型不符的行不会写入，写入器仍可使用
+	require.NoError(t, w.Write([]any{int32(7)}))
+	require.NoError(t, w.Close())
+	assert.Equal(t, []map[string]any{{"seq": int64(7)}}, readRows(t, buf.Bytes()))
+}
+
+// readRows 用parquet-go按行读取文件，按列名返回值，整数和时间列的值以int64表示，空值为nil
+func readRows(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	f, err := pq.OpenFile(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	var names []string
+	for _, field := range f.Schema().Fields() {
+		names = append(names, field.Name())
+	}
+
+	r := pq.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	var out []map[string]any
+	buf := make([]pq.Row, 16)
+	for {
+		n, err := r.ReadRows(buf)
+		for _, row := range buf[:n] {
+			values := make(map[string]any, len(row))
+			for _, v := range row {
+				name := names[v.Column()]
+				switch {
+				case v.IsNull():
+					values[name] = nil
+				case v.Kind() == pq.ByteArray:
+					values[name] = string(v.ByteArray())
+				case v.Kind() == pq.Boolean:
+					values[name] = v.Boolean()
+				default:
+					values[name] = v.Int64()
+				}
+			}
+			out = append(out, values)
+		}
+		if errors.Is(err, io.EOF) {
+			return out
+		}
+		require.NoError(t, err)
+	}
+}
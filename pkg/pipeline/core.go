@@ -2,8 +2,10 @@ package pipeline
 
 import (
 	"context"
+	"io"
 	"path"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"codexie.com/auditlog/internal/config"
@@ -12,9 +14,17 @@ import (
 )
 
 type plugins struct {
-	exporter   map[string]plugin.Exporter
-	filters    []plugin.Filter
-	lifecycles []plugin.LifecycleHook
+	exporter     map[string]plugin.Exporter // key为导出器实例ID，同类型的多个导出器互不覆盖
+	routes       map[string]*route          // key为导出器实例ID
+	filters      []plugin.Filter
+	transformers []plugin.Transformer
+	lifecycles   []plugin.LifecycleHook
+}
+
+// route 导出路由，导出器独立的过滤器和转换链，在全局过滤和转换之后执行
+type route struct {
+	filters      []plugin.Filter
+	transformers []plugin.Transformer
 }
 
 // 管道核心结构
@@ -24,6 +34,8 @@ type Pipeline struct {
 	queue      chan interface{}
 	plugins    plugins
 	blockData  []interface{}
+	held       []interface{} // 生命周期钩子失败的数据，保留原始对象以便重新执行钩子，只在processor协程中访问
+	heldSize   atomic.Int64
 	state      *State
 	localStore *LocalStorage
 	metrics    *Metrics
@@ -46,9 +58,11 @@ func New(cfg config.PiplineConfig) *Pipeline {
 		cancel:        cancel,
 		blockData:     make([]interface{}, 0),
 		plugins: plugins{
-			exporter:   make(map[string]plugin.Exporter),
-			filters:    make([]plugin.Filter, 0),
-			lifecycles: make([]plugin.LifecycleHook, 0),
+			exporter:     make(map[string]plugin.Exporter),
+			routes:       make(map[string]*route),
+			filters:      make([]plugin.Filter, 0),
+			transformers: make([]plugin.Transformer, 0),
+			lifecycles:   make([]plugin.LifecycleHook, 0),
 		},
 	}
 
@@ -92,6 +106,10 @@ func (p *Pipeline) Push(data interface{}) error {
 	if p.state.IsBlocked() {
 		return ErrPipelineBlocked
 	}
+	// 钩子持续失败时保留的数据达到队列容量后拒绝写入，由调用方重试
+	if p.heldSize.Load() >= int64(cap(p.queue)) {
+		return ErrHooksFailing
+	}
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 	if !p.started {
@@ -118,9 +136,12 @@ func (p *Pipeline) processor() {
 			for data := range p.queue {
 				batch = append(batch, data)
 			}
-			if len(batch) > 0 {
+			if len(batch) > 0 || len(p.held) > 0 {
 				p.flushBatch(batch)
 			}
+			if len(p.held) > 0 {
+				logx.Errorf("pipeline %s closed with %d records failing lifecycle hooks", p.Name, len(p.held))
+			}
 			return
 
 		case data := <-p.queue:
@@ -133,7 +154,7 @@ func (p *Pipeline) processor() {
 			}
 
 		case <-timer.C:
-			if len(batch) > 0 {
+			if len(batch) > 0 || len(p.held) > 0 {
 				p.flushBatch(batch)
 				batch = batch[:0]
 			}
@@ -198,7 +219,14 @@ func (p *Pipeline) tryRecoverFromDisk() {
 			}()
 			continue
 		}
-		exporter := p.plugins.exporter[batch.Name]
+		// 落盘数据按导出器实例ID归属，配置变更后找不到的实例保留文件，待恢复配置后重试
+		exporter, ok := p.plugins.exporter[batch.Name]
+		if !ok {
+			exportSuccess = false
+			errorCount++
+			logx.Errorf("pipeline %s: exporter %s of recovered data is not registered", p.Name, batch.Name)
+			continue
+		}
 		if err := exporter.Export(ctx, batch.Data); err != nil {
 			exportSuccess = false
 			errorCount++
@@ -223,54 +251,186 @@ func (p *Pipeline) flushBatch(batch []interface{}) {
 		p.blockData = append(p.blockData, batch...)
 		return
 	}
-	// 执行前置钩子
-	for _, hook := range p.plugins.lifecycles {
-		ctx = hook.BeforeExport(ctx, batch)
-	}
-
 	// 创建一个新的slice来存储通过过滤的数据
 	filteredBatch := make([]interface{}, 0, len(batch))
 	for i := range batch {
-		shouldKeep := true
-		// 检查所有过滤器
-		for _, filter := range p.plugins.filters {
-			if !filter.Filter(batch[i]) {
-				shouldKeep = false
-				break
-			}
-		}
 		// 如果通过所有过滤器，则保留该数据
-		if shouldKeep {
+		if p.applyFilters(p.plugins.filters, batch[i]) {
 			filteredBatch = append(filteredBatch, batch[i])
 		}
 	}
 
+	// 依次执行转换器，转换器可修改、拆分或丢弃单条数据
+	filteredBatch = applyTransformers(p.plugins.transformers, filteredBatch, false)
+
+	// 之前钩子失败保留的数据排在前面，与本批一起重新执行钩子
+	if len(p.held) > 0 {
+		filteredBatch = append(p.held, filteredBatch...)
+		p.held = nil
+		p.heldSize.Store(0)
+	}
+
 	// 用过滤后的数据替换原始batch
 	batch = filteredBatch
+	if len(batch) == 0 {
+		p.metrics.SuccessCounter.WithLabelValues(p.Name).Inc()
+		return
+	}
+
+	// 执行前置钩子，钩子作用于最终导出的数据(如生成日志ID、计算哈希链)
+	// 任一钩子失败时整批不导出，保留到下次刷新重试，避免导出缺少日志ID或未入链的数据
+	if !p.runHooks(ctx, batch) {
+		return
+	}
+
+	// 按导出器路由过滤并转换，失败时落盘的也是路由后的数据
+	// 导出器并发执行且可能回写数据(如MySQL回填自增ID和创建时间)，只有一个导出器使用原始数据，其余使用副本
+	routed := make(map[string][]interface{}, len(p.plugins.exporter))
+	for name := range p.plugins.exporter {
+		routed[name] = p.routeBatch(name, batch, len(routed) > 0)
+	}
 
 	// 导出日志
 	wg := sync.WaitGroup{}
-	for _, exporter := range p.plugins.exporter {
+	failed := sync.Map{}
+	for name, exporter := range p.plugins.exporter {
 		wg.Add(1)
-		go func(exporter plugin.Exporter) {
+		go func(name string, exporter plugin.Exporter, data []interface{}) {
 			defer wg.Done()
+			if len(data) == 0 {
+				return
+			}
 			start := time.Now()
-			if err := exporter.Export(ctx, filteredBatch); err != nil {
-				p.handleExportError(exporter.Name(), batch)
+			if err := exporter.Export(ctx, data); err != nil {
+				failed.Store(name, true)
+				p.handleExportError(name, data)
 				// 执行错误钩子
 				for _, hook := range p.plugins.lifecycles {
-					hook.OnError(context.Background(), err, batch)
+					hook.OnError(context.Background(), err, data)
 				}
 				return
 			}
-			p.metrics.ExportLatency.WithLabelValues(exporter.Name()).Observe(float64(time.Since(start).Milliseconds()))
-		}(exporter)
+			p.metrics.ExportLatency.WithLabelValues(name).Observe(float64(time.Since(start).Milliseconds()))
+		}(name, exporter, routed[name])
 	}
 	wg.Wait()
+	p.notifyExported(ctx, batch, routed, &failed)
 
 	p.metrics.SuccessCounter.WithLabelValues(p.Name).Inc()
 }
 
+// notifyExported 通知实现了ExportObserver的过滤器数据已导出
+// 导出失败落盘的数据恢复时不再通知，观察者需自行处理未确认的情况(如去重过滤器的预留键到期释放)
+func (p *Pipeline) notifyExported(ctx context.Context, batch []interface{}, routed map[string][]interface{}, failed *sync.Map) {
+	allExported := true
+	failed.Range(func(any, any) bool {
+		allExported = false
+		return false
+	})
+	if allExported {
+		for _, filter := range p.plugins.filters {
+			if o, ok := filter.(plugin.ExportObserver); ok {
+				o.OnExported(ctx, batch)
+			}
+		}
+	}
+	for name, r := range p.plugins.routes {
+		if _, ok := failed.Load(name); ok || len(routed[name]) == 0 {
+			continue
+		}
+		for _, filter := range r.filters {
+			if o, ok := filter.(plugin.ExportObserver); ok {
+				o.OnExported(ctx, routed[name])
+			}
+		}
+	}
+}
+
+// runHooks 依次执行前置钩子，失败时通知各钩子并保留整批数据
+// 磁盘恢复的数据不会重新执行钩子，因此钩子失败的数据保留在内存中而不落盘
+func (p *Pipeline) runHooks(ctx context.Context, batch []interface{}) bool {
+	for _, hook := range p.plugins.lifecycles {
+		var err error
+		if ctx, err = hook.BeforeExport(ctx, batch); err != nil {
+			logx.Errorf("pipeline %s: lifecycle hook %s failed, holding %d records: %v", p.Name, hook.Name(), len(batch), err)
+			p.metrics.HookFailed.WithLabelValues(hook.Name()).Add(float64(len(batch)))
+			for _, h := range p.plugins.lifecycles {
+				h.OnError(context.Background(), err, batch)
+			}
+			p.held = batch
+			p.heldSize.Store(int64(len(batch)))
+			return false
+		}
+	}
+	return true
+}
+
+// routeBatch 执行导出器路由上的过滤器和转换器，name为导出器实例ID，转换作用于数据副本；clone为true时未配置转换器也返回副本
+func (p *Pipeline) routeBatch(name string, batch []interface{}, clone bool) []interface{} {
+	r, ok := p.plugins.routes[name]
+	if !ok {
+		p.metrics.RouteRouted.WithLabelValues(name).Add(float64(len(batch)))
+		if clone {
+			return cloneBatch(batch)
+		}
+		return batch
+	}
+
+	routed := make([]interface{}, 0, len(batch))
+	for _, data := range batch {
+		if p.applyFilters(r.filters, data) {
+			routed = append(routed, data)
+		}
+	}
+	p.metrics.RouteDropped.WithLabelValues(name).Add(float64(len(batch) - len(routed)))
+
+	if clone {
+		routed = cloneBatch(routed)
+	}
+	routed = applyTransformers(r.transformers, routed, !clone)
+	p.metrics.RouteRouted.WithLabelValues(name).Add(float64(len(routed)))
+	return routed
+}
+
+// applyFilters 判断数据是否通过所有过滤器，被丢弃时按丢弃它的过滤器计数
+// 全局过滤器和导出路由上的过滤器都计入FilterDropped
+func (p *Pipeline) applyFilters(filters []plugin.Filter, data interface{}) bool {
+	for _, filter := range filters {
+		if !filter.Filter(data) {
+			p.metrics.FilterDropped.WithLabelValues(filter.Name()).Inc()
+			return false
+		}
+	}
+	return true
+}
+
+// applyTransformers 依次对每条数据执行转换器，clone为true时先复制数据再转换
+func applyTransformers(transformers []plugin.Transformer, batch []interface{}, clone bool) []interface{} {
+	if len(transformers) == 0 {
+		return batch
+	}
+	if clone {
+		batch = cloneBatch(batch)
+	}
+	for _, transformer := range transformers {
+		transformed := make([]interface{}, 0, len(batch))
+		for _, data := range batch {
+			transformed = append(transformed, transformer.Transform(data)...)
+		}
+		batch = transformed
+	}
+	return batch
+}
+
+// cloneBatch 浅拷贝每条数据
+func cloneBatch(batch []interface{}) []interface{} {
+	copied := make([]interface{}, 0, len(batch))
+	for _, data := range batch {
+		copied = append(copied, plugin.Clone(data))
+	}
+	return copied
+}
+
 func (p *Pipeline) handleExportError(name string, batch []interface{}) {
 	p.metrics.ErrorCounter.WithLabelValues(p.Name).Add(float64(len(batch)))
 
@@ -287,6 +447,26 @@ func (p *Pipeline) handleExportError(name string, batch []interface{}) {
 	}
 }
 
+// closePlugins 关闭持有资源的插件，如logid钩子的雪花机器号租约
+func (p *Pipeline) closePlugins() {
+	closers := make([]io.Closer, 0)
+	for _, hook := range p.plugins.lifecycles {
+		if c, ok := hook.(io.Closer); ok {
+			closers = append(closers, c)
+		}
+	}
+	for _, exporter := range p.plugins.exporter {
+		if c, ok := exporter.(io.Closer); ok {
+			closers = append(closers, c)
+		}
+	}
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			logx.Errorf("pipeline %s: close plugin failed: %v", p.Name, err)
+		}
+	}
+}
+
 // 关闭管道，等待所有数据处理完成
 func (p *Pipeline) Close() error {
 	p.mu.Lock()
@@ -299,6 +479,7 @@ func (p *Pipeline) Close() error {
 	p.cancel()
 	close(p.queue)
 	p.wg.Wait()
+	p.closePlugins()
 	logx.Infof("===========================pipeline %s closed===========================", p.Name)
 	return p.localStore.Close()
 }
@@ -8,6 +8,7 @@ var (
 	ErrPipelineBlocked    = errors.New("pipeline is blocked due to disk full")
 	ErrQueueFull          = errors.New("pipeline queue is full")
 	ErrPipelineNotStarted = errors.New("pipeline is not started")
+	ErrHooksFailing       = errors.New("pipeline is holding records that failed lifecycle hooks")
 
 	// 导出相关错误
 	ErrExporterFailed    = errors.New("exporter failed to export data")
@@ -12,6 +12,10 @@ type Metrics struct {
 	ExportCounter  *prometheus.CounterVec
 	DiskUsage      *prometheus.GaugeVec
 	ExportLatency  *prometheus.HistogramVec
+	FilterDropped  *prometheus.CounterVec
+	RouteRouted    *prometheus.CounterVec
+	RouteDropped   *prometheus.CounterVec
+	HookFailed     *prometheus.CounterVec
 }
 
 func NewMetrics(namespace string) *Metrics {
@@ -47,6 +51,26 @@ func NewMetrics(namespace string) *Metrics {
 			Help:      "Export operation latency in seconds",
 			Buckets:   prometheus.DefBuckets,
 		}, []string{"exporter"}),
+		FilterDropped: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "filter_dropped_total",
+			Help:      "Total number of records dropped by filters",
+		}, []string{"filter"}),
+		RouteRouted: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "route_routed_total",
+			Help:      "Total number of records routed to each exporter",
+		}, []string{"route"}),
+		RouteDropped: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "route_dropped_total",
+			Help:      "Total number of records dropped by each exporter route",
+		}, []string{"route"}),
+		HookFailed: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "hook_failed_total",
+			Help:      "Total number of records held back because a lifecycle hook failed",
+		}, []string{"hook"}),
 	}
 
 	return m
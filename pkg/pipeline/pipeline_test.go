@@ -13,6 +13,7 @@ import (
 
 	"codexie.com/auditlog/internal/config"
 	"codexie.com/auditlog/pkg/plugin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/zeromicro/go-zero/core/logx"
@@ -173,3 +174,277 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+// splitTransformer 将"split-"开头的数据拆分为两条，丢弃"drop-"开头的数据
+type splitTransformer struct{}
+
+func (s *splitTransformer) Name() string { return "split-test" }
+
+func (s *splitTransformer) Transform(data interface{}) []interface{} {
+	str := data.(string)
+	switch {
+	case strings.HasPrefix(str, "split-"):
+		return []interface{}{str + "-1", str + "-2"}
+	case strings.HasPrefix(str, "drop-"):
+		return nil
+	}
+	return []interface{}{data}
+}
+
+// recordingExporter 记录导出的数据
+type recordingExporter struct {
+	mu   sync.Mutex
+	data []interface{}
+}
+
+func (r *recordingExporter) Name() string { return "recording-test" }
+
+func (r *recordingExporter) Export(ctx context.Context, data []interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data = append(r.data, data...)
+	return nil
+}
+
+func TestPipeline_Transformers(t *testing.T) {
+	p := New(config.PiplineConfig{
+		Name:       "test_transform_pipeline",
+		BatchSize:  10,
+		StorageDir: t.TempDir(),
+	})
+	exporter := &recordingExporter{}
+	p.RegisterExporter(exporter)
+	p.RegisterTransformer(&splitTransformer{})
+
+	p.flushBatch([]interface{}{"keep-1", "split-a", "drop-b"})
+
+	assert.Equal(t, []interface{}{"keep-1", "split-a-1", "split-a-2"}, exporter.data)
+}
+
+type testRecord struct {
+	Message string `json:"message"`
+}
+
+// maskTransformer 将message替换为掩码
+type maskTransformer struct{}
+
+func (m *maskTransformer) Name() string { return "mask-test" }
+
+func (m *maskTransformer) Transform(data interface{}) []interface{} {
+	data.(*testRecord).Message = "***"
+	return []interface{}{data}
+}
+
+type namedExporter struct {
+	recordingExporter
+	name string
+}
+
+func (n *namedExporter) Name() string { return n.name }
+
+func TestPipeline_ExporterTransformers(t *testing.T) {
+	p := New(config.PiplineConfig{
+		Name:       "test_exporter_transform_pipeline",
+		BatchSize:  10,
+		StorageDir: t.TempDir(),
+	})
+	raw := &namedExporter{name: "raw"}
+	masked := &namedExporter{name: "masked"}
+	p.RegisterExporter(raw)
+	p.RegisterRoute("masked", masked, nil, []plugin.Transformer{&maskTransformer{}})
+
+	record := &testRecord{Message: "secret"}
+	p.flushBatch([]interface{}{record})
+
+	require.Len(t, raw.data, 1)
+	require.Len(t, masked.data, 1)
+	assert.Equal(t, "secret", raw.data[0].(*testRecord).Message)
+	assert.Equal(t, "***", masked.data[0].(*testRecord).Message)
+	assert.Equal(t, "secret", record.Message)
+}
+
+// prefixFilter 保留指定前缀的数据
+type prefixFilter struct {
+	prefix string
+}
+
+func (f *prefixFilter) Name() string { return "prefix-test" }
+
+func (f *prefixFilter) Filter(data interface{}) bool {
+	return strings.HasPrefix(data.(string), f.prefix)
+}
+
+func TestPipeline_RouteFilters(t *testing.T) {
+	p := New(config.PiplineConfig{
+		Name:       "test_route_pipeline",
+		BatchSize:  10,
+		StorageDir: t.TempDir(),
+	})
+	all := &namedExporter{name: "all"}
+	failOnly := &namedExporter{name: "fail-only"}
+	p.RegisterExporter(all)
+	p.RegisterRoute("fail-only", failOnly, []plugin.Filter{&prefixFilter{prefix: "fail-"}}, nil)
+
+	p.flushBatch([]interface{}{"success-1", "fail-1", "success-2"})
+
+	assert.Equal(t, []interface{}{"success-1", "fail-1", "success-2"}, all.data)
+	assert.Equal(t, []interface{}{"fail-1"}, failOnly.data)
+	// 路由过滤器丢弃的数据与全局过滤器一样计入FilterDropped
+	assert.Equal(t, float64(2), testutil.ToFloat64(p.metrics.FilterDropped.WithLabelValues("prefix-test")))
+}
+
+func TestPipeline_ExportersGetSeparateCopies(t *testing.T) {
+	p := New(config.PiplineConfig{
+		Name:       "test_copy_pipeline",
+		BatchSize:  10,
+		StorageDir: t.TempDir(),
+	})
+	a := &namedExporter{name: "a"}
+	b := &namedExporter{name: "b"}
+	p.RegisterExporter(a)
+	p.RegisterExporter(b)
+
+	p.flushBatch([]interface{}{&testRecord{Message: "m"}})
+
+	// 并发执行的导出器回写数据时互不影响
+	require.Len(t, a.data, 1)
+	require.Len(t, b.data, 1)
+	assert.Equal(t, a.data[0], b.data[0])
+	assert.NotSame(t, a.data[0], b.data[0])
+}
+
+// flakyHook 前failures次执行失败
+type flakyHook struct {
+	NoopLifecycleHook
+	failures int
+	calls    int
+}
+
+func (h *flakyHook) Name() string { return "flaky-test" }
+
+func (h *flakyHook) BeforeExport(ctx context.Context, batch []interface{}) (context.Context, error) {
+	h.calls++
+	if h.calls <= h.failures {
+		return ctx, fmt.Errorf("simulated hook error")
+	}
+	return ctx, nil
+}
+
+func TestPipeline_HookFailureHoldsBatch(t *testing.T) {
+	p := New(config.PiplineConfig{
+		Name:       "test_hook_pipeline",
+		BatchSize:  1,
+		StorageDir: t.TempDir(),
+	})
+	exporter := &namedExporter{name: "hooked"}
+	p.RegisterExporter(exporter)
+	p.RegisterLifecycleHook(&flakyHook{failures: 1})
+	p.mu.Lock()
+	p.started = true
+	p.mu.Unlock()
+
+	// 钩子失败时不导出，数据保留并计数
+	held := make([]interface{}, cap(p.queue))
+	for i := range held {
+		held[i] = fmt.Sprintf("held-%d", i)
+	}
+	p.flushBatch(held)
+	assert.Empty(t, exporter.data)
+	assert.Equal(t, float64(len(held)), testutil.ToFloat64(p.metrics.HookFailed.WithLabelValues("flaky-test")))
+
+	// 保留的数据达到队列容量后拒绝写入
+	assert.ErrorIs(t, p.Push("b"), ErrHooksFailing)
+
+	// 下次刷新时保留的数据排在前面重新执行钩子
+	p.flushBatch([]interface{}{"c"})
+	assert.Equal(t, append(held, "c"), exporter.data)
+	assert.NoError(t, p.Push("d"))
+
+	// 过滤后为空的批次同样计入成功
+	before := testutil.ToFloat64(p.metrics.SuccessCounter.WithLabelValues(p.Name))
+	p.flushBatch(nil)
+	assert.Equal(t, before+1, testutil.ToFloat64(p.metrics.SuccessCounter.WithLabelValues(p.Name)))
+}
+
+// sealingHook 模拟计算内容哈希的钩子
+type sealingHook struct {
+	NoopLifecycleHook
+}
+
+func (h *sealingHook) SealsContent() {}
+
+func TestPipeline_ValidateSealedRoutes(t *testing.T) {
+	p := New(config.PiplineConfig{
+		Name:       "test_sealed_pipeline",
+		BatchSize:  10,
+		StorageDir: t.TempDir(),
+	})
+	p.RegisterExporter(&namedExporter{name: "raw"})
+	p.RegisterRoute("filtered", &namedExporter{name: "filtered"}, []plugin.Filter{&prefixFilter{prefix: "fail-"}}, nil)
+	p.RegisterRoute("masked", &namedExporter{name: "masked"}, nil, []plugin.Transformer{&maskTransformer{}})
+	assert.NoError(t, p.Validate())
+
+	// 入链后路由转换器会修改已计算哈希的记录
+	p.RegisterLifecycleHook(&sealingHook{})
+	assert.ErrorContains(t, p.Validate(), "masked")
+}
+
+// observingFilter 记录导出成功后收到的确认
+type observingFilter struct {
+	prefixFilter
+	exported []interface{}
+}
+
+func (f *observingFilter) OnExported(ctx context.Context, batch []interface{}) {
+	f.exported = append(f.exported, batch...)
+}
+
+func TestPipeline_NotifyExported(t *testing.T) {
+	p := New(config.PiplineConfig{
+		Name:       "test_notify_pipeline",
+		BatchSize:  10,
+		StorageDir: t.TempDir(),
+	})
+	global := &observingFilter{prefixFilter: prefixFilter{prefix: "ok-"}}
+	routeOK := &observingFilter{prefixFilter: prefixFilter{prefix: "ok-1"}}
+	routeFailed := &observingFilter{prefixFilter: prefixFilter{prefix: "ok-"}}
+	p.RegisterFilter(global)
+	p.RegisterRoute("ok", &namedExporter{name: "ok"}, []plugin.Filter{routeOK}, nil)
+	p.RegisterRoute("failing", &FailingExporter{}, []plugin.Filter{routeFailed}, nil)
+
+	p.flushBatch([]interface{}{"ok-1", "ok-2", "dropped"})
+
+	// 有导出器失败时全局过滤器不确认，路由过滤器只在对应导出器成功时确认
+	assert.Empty(t, global.exported)
+	assert.Equal(t, []interface{}{"ok-1"}, routeOK.exported)
+	assert.Empty(t, routeFailed.exported)
+
+	p = New(config.PiplineConfig{
+		Name:       "test_notify_all_pipeline",
+		BatchSize:  10,
+		StorageDir: t.TempDir(),
+	})
+	global.exported = nil
+	p.RegisterFilter(global)
+	p.RegisterExporter(&namedExporter{name: "ok"})
+	p.flushBatch([]interface{}{"ok-1", "dropped"})
+	assert.Equal(t, []interface{}{"ok-1"}, global.exported)
+}
+
+func TestPipeline_RoutesByInstanceId(t *testing.T) {
+	p := New(config.PiplineConfig{
+		Name:       "test_instance_pipeline",
+		BatchSize:  10,
+		StorageDir: t.TempDir(),
+	})
+	// 同类型的两个导出器按实例ID区分路由，互不覆盖
+	failures := &namedExporter{name: "webhook"}
+	successes := &namedExporter{name: "webhook"}
+	p.RegisterRoute("webhook#0", failures, []plugin.Filter{&prefixFilter{prefix: "fail-"}}, nil)
+	p.RegisterRoute("webhook#1", successes, []plugin.Filter{&prefixFilter{prefix: "success-"}}, nil)
+
+	p.flushBatch([]interface{}{"success-1", "fail-1"})
+	assert.Equal(t, []interface{}{"fail-1"}, failures.data)
+	assert.Equal(t, []interface{}{"success-1"}, successes.data)
+	assert.Equal(t, float64(1), testutil.ToFloat64(p.metrics.RouteRouted.WithLabelValues("webhook#0")))
+}
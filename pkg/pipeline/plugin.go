@@ -2,30 +2,63 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
 
 	"codexie.com/auditlog/pkg/plugin"
 )
 
-// 插件注册方法
+// RegisterExporter 以插件名称作为实例ID注册导出器，同类型只能注册一个
 func (p *Pipeline) RegisterExporter(exporter plugin.Exporter) {
-	p.plugins.exporter[exporter.Name()] = exporter
+	p.RegisterRoute(exporter.Name(), exporter, nil, nil)
+}
+
+// RegisterRoute 以实例ID注册导出器及其路由，filters和transformers仅作用于该导出器
+// 实例ID区分同类型的多个导出器，也是指标标签和落盘数据的归属，配置变更时应保持不变
+func (p *Pipeline) RegisterRoute(id string, exporter plugin.Exporter, filters []plugin.Filter, transformers []plugin.Transformer) {
+	p.plugins.exporter[id] = exporter
+	if len(filters) > 0 || len(transformers) > 0 {
+		p.plugins.routes[id] = &route{
+			filters:      filters,
+			transformers: transformers,
+		}
+	}
 }
 
 func (p *Pipeline) RegisterFilter(filter plugin.Filter) {
 	p.plugins.filters = append(p.plugins.filters, filter)
 }
 
+func (p *Pipeline) RegisterTransformer(transformer plugin.Transformer) {
+	p.plugins.transformers = append(p.plugins.transformers, transformer)
+}
+
 func (p *Pipeline) RegisterLifecycleHook(hook plugin.LifecycleHook) {
 	p.plugins.lifecycles = append(p.plugins.lifecycles, hook)
 }
 
+// Validate 校验插件组合，在注册完所有插件后调用
+// 路由转换器在前置钩子之后执行，会使Sealer计算的哈希与导出的内容不一致
+func (p *Pipeline) Validate() error {
+	for _, hook := range p.plugins.lifecycles {
+		if _, ok := hook.(plugin.Sealer); !ok {
+			continue
+		}
+		for name, r := range p.plugins.routes {
+			if len(r.transformers) > 0 {
+				return fmt.Errorf("pipeline %s: exporter %s has route transformers, which would modify records after lifecycle hook %s sealed them", p.Name, name, hook.Name())
+			}
+		}
+	}
+	return nil
+}
+
 // 默认插件实现需要修改为泛型实现
 type NoopLifecycleHook struct{}
 
 func (h *NoopLifecycleHook) Name() string { return "noop-lifecycle" }
 
-func (h *NoopLifecycleHook) BeforeExport(ctx context.Context, batch []interface{}) context.Context {
-	return ctx
+func (h *NoopLifecycleHook) BeforeExport(ctx context.Context, batch []interface{}) (context.Context, error) {
+	return ctx, nil
 }
 
 func (h *NoopLifecycleHook) OnError(ctx context.Context, err error, batch []interface{}) {
@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Conf 插件配置，对应yaml中插件的Config节点
+// 标量值在加载时统一为字符串，列表和对象保持原始结构
+type Conf map[string]any
+
+// String 读取字符串配置，不存在时返回默认值
+func (c Conf) String(key string, def string) string {
+	v, ok := c[key]
+	if !ok || v == nil {
+		return def
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// Int 读取整型配置，不存在或格式错误时返回默认值
+func (c Conf) Int(key string, def int) int {
+	v, ok := c[key]
+	if !ok || v == nil {
+		return def
+	}
+	switch val := v.(type) {
+	case int:
+		return val
+	case int64:
+		return int(val)
+	case float64:
+		return int(val)
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(fmt.Sprint(v)))
+	if err != nil {
+		return def
+	}
+	return i
+}
+
+// Float 读取浮点配置，不存在或格式错误时返回默认值
+func (c Conf) Float(key string, def float64) float64 {
+	v, ok := c[key]
+	if !ok || v == nil {
+		return def
+	}
+	switch val := v.(type) {
+	case float64:
+		return val
+	case int:
+		return float64(val)
+	case int64:
+		return float64(val)
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(v)), 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
+
+// Bool 读取布尔配置，不存在或格式错误时返回默认值
+func (c Conf) Bool(key string, def bool) bool {
+	v, ok := c[key]
+	if !ok || v == nil {
+		return def
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(fmt.Sprint(v)))
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// Strings 读取字符串列表，支持yaml列表或逗号分隔的字符串
+func (c Conf) Strings(key string) []string {
+	v, ok := c[key]
+	if !ok || v == nil {
+		return nil
+	}
+	var res []string
+	switch val := v.(type) {
+	case []string:
+		return val
+	case []any:
+		for _, item := range val {
+			res = append(res, fmt.Sprint(item))
+		}
+	default:
+		for _, item := range strings.Split(fmt.Sprint(val), ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				res = append(res, item)
+			}
+		}
+	}
+	return res
+}
+
+// List 读取对象列表，如过滤规则列表
+func (c Conf) List(key string) ([]Conf, error) {
+	v, ok := c[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("config %s must be a list, got %T", key, v)
+	}
+	res := make([]Conf, 0, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("config %s[%d] must be an object, got %T", key, i, item)
+		}
+		res = append(res, Conf(m))
+	}
+	return res, nil
+}
+
+// Map 读取对象配置，如字段名映射
+func (c Conf) Map(key string) (Conf, error) {
+	v, ok := c[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("config %s must be an object, got %T", key, v)
+	}
+	return Conf(m), nil
+}
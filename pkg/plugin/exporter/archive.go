@@ -0,0 +1,130 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"codexie.com/auditlog/pkg/blobstore"
+	"codexie.com/auditlog/pkg/parquet"
+	"codexie.com/auditlog/pkg/plugin"
+	"codexie.com/auditlog/pkg/tabular"
+)
+
+// Archive 归档导出器，将每批记录写为一个文件存入制品存储，供Spark、DuckDB等离线分析
+// 对象键为 prefix/年/月/日/首条log_id_条数.扩展名，同一批记录重试时覆盖同一个对象；
+// 记录没有log_id时以导出时间命名，重试可能产生重复文件
+//
+// 配置示例:
+//
+//	exporters:
+//	  - Name: archive
+//	    Config:
+//	      store: "#svc.ExportStore"
+//	      format: parquet       # parquet|ndjson|csv
+//	      prefix: archive
+//	      columns: [log_id, tenant_id, action, result, created_at]  # 为空时写出全部字段
+type Archive struct {
+	store   blobstore.Store
+	format  string
+	prefix  string
+	columns []string
+	now     func() time.Time
+}
+
+// NewArchive 根据配置创建归档导出器
+func NewArchive(conf plugin.Conf) (*Archive, error) {
+	store, ok := conf["store"].(blobstore.Store)
+	if !ok || store == nil {
+		return nil, fmt.Errorf("archive exporter: store is required")
+	}
+	format := conf.String("format", tabular.FormatParquet)
+	switch format {
+	case tabular.FormatParquet, tabular.FormatNDJSON, tabular.FormatCSV:
+	default:
+		return nil, fmt.Errorf("archive exporter: unsupported format %q", format)
+	}
+	return &Archive{
+		store:   store,
+		format:  format,
+		prefix:  conf.String("prefix", "archive"),
+		columns: conf.Strings("columns"),
+		now:     time.Now,
+	}, nil
+}
+
+// Name 返回插件名称
+func (e *Archive) Name() string { return "archive" }
+
+// Export 将一批记录写为一个文件，列类型按第一条记录推导
+// 创建时间由数据库写入时生成，导出时尚未赋值的以导出时间代替，不修改记录本身
+func (e *Archive) Export(ctx context.Context, data []interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	now := e.now().UTC()
+	columns := e.columns
+	if len(columns) == 0 {
+		schema, err := parquet.ColumnsOf(data[0], nil)
+		if err != nil {
+			return fmt.Errorf("archive exporter: %w", err)
+		}
+		for _, col := range schema {
+			columns = append(columns, col.Name)
+		}
+	}
+
+	var buf bytes.Buffer
+	w, err := tabular.New(e.format, &buf, columns, data[0])
+	if err != nil {
+		return fmt.Errorf("archive exporter: %w", err)
+	}
+	row := make([]any, len(columns))
+	for _, d := range data {
+		for i, col := range columns {
+			v, _ := plugin.FieldValue(d, col)
+			if t, ok := v.(time.Time); ok && t.IsZero() && col == "created_at" {
+				v = now
+			}
+			row[i] = v
+		}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("archive exporter: %w", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("archive exporter: %w", err)
+	}
+
+	key := e.key(data, now)
+	if err := e.store.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		return fmt.Errorf("archive exporter: put %s: %w", key, err)
+	}
+	return nil
+}
+
+// key 按导出日期分目录，以首条记录的log_id和条数命名
+func (e *Archive) key(data []interface{}, now time.Time) string {
+	name := fmt.Sprintf("%d", now.UnixNano())
+	if v, ok := plugin.FieldValue(data[0], "log_id"); ok {
+		if id, ok := v.(string); ok && id != "" {
+			name = id
+		}
+	}
+	return path.Join(e.prefix, now.Format("2006/01/02"), fmt.Sprintf("%s_%d.%s", name, len(data), tabular.Ext(e.format)))
+}
+
+func init() {
+	plugin.RegisterExporterFactory("archive", func(config map[string]any) plugin.Exporter {
+		e, err := NewArchive(config)
+		if err != nil {
+			panic(err)
+		}
+		return e
+	})
+}
+
+// 确保Archive实现了Exporter接口
+var _ plugin.Exporter = (*Archive)(nil)
@@ -0,0 +1,82 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/blobstore"
+	"codexie.com/auditlog/pkg/plugin"
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// archivedLog 按parquet标签读取归档文件中的部分列
+type archivedLog struct {
+	LogId     string    `parquet:"log_id,optional"`
+	Action    string    `parquet:"action,optional"`
+	Seq       int64     `parquet:"seq,optional"`
+	CreatedAt time.Time `parquet:"created_at,optional,timestamp(millisecond)"`
+}
+
+func readObject(t *testing.T, store blobstore.Store, key string) []byte {
+	t.Helper()
+	r, err := store.Open(context.Background(), key, 0)
+	require.NoError(t, err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return data
+}
+
+func TestArchive_Export(t *testing.T) {
+	_, err := NewArchive(plugin.Conf{})
+	assert.Error(t, err)
+	store, err := blobstore.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	_, err = NewArchive(plugin.Conf{"store": store, "format": "xlsx"})
+	assert.Error(t, err)
+
+	e, err := NewArchive(plugin.Conf{"store": store})
+	require.NoError(t, err)
+	now := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+	created := now.Add(-time.Hour)
+	batch := []interface{}{
+		&model.AuditLog{LogId: "01J_1", TenantID: "t1", Action: "LOGIN", Seq: 3, CreatedAt: created},
+		&model.AuditLog{LogId: "01J_2", TenantID: "t1", Action: "LOGOUT"},
+	}
+	require.NoError(t, e.Export(context.Background(), batch))
+
+	data := readObject(t, store, "archive/2024/06/01/01J_1_2.parquet")
+	rows, err := pq.Read[archivedLog](bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "LOGIN", rows[0].Action)
+	assert.Equal(t, int64(3), rows[0].Seq)
+	assert.True(t, created.Equal(rows[0].CreatedAt))
+	// 尚未写入数据库的记录以导出时间作为创建时间，记录本身不被修改
+	assert.True(t, now.Equal(rows[1].CreatedAt))
+	assert.True(t, batch[1].(*model.AuditLog).CreatedAt.IsZero())
+
+	// 同一批记录重试时覆盖同一个对象
+	require.NoError(t, e.Export(context.Background(), batch))
+	assert.Equal(t, data, readObject(t, store, "archive/2024/06/01/01J_1_2.parquet"))
+}
+
+func TestArchive_Columns(t *testing.T) {
+	store, err := blobstore.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	e, err := NewArchive(plugin.Conf{"store": store, "format": "csv", "prefix": "audit", "columns": []any{"log_id", "action"}})
+	require.NoError(t, err)
+	e.now = func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	require.NoError(t, e.Export(context.Background(), []interface{}{&model.AuditLog{LogId: "01J_1", Action: "=cmd"}}))
+	data := readObject(t, store, "audit/2024/06/01/01J_1_1.csv")
+	assert.Equal(t, "log_id,action\n01J_1,'=cmd\n", strings.ReplaceAll(string(data), "\r\n", "\n"))
+}
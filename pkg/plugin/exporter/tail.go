@@ -0,0 +1,66 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"codexie.com/auditlog/pkg/plugin"
+	"codexie.com/auditlog/pkg/tail"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// Tail 实时订阅导出器，将通过管道的记录发布给GET /v1/audit/tail的订阅者
+// 实时订阅只是尽力而为的旁路，发布失败只记录日志，不触发管道落盘重试
+//
+// 配置示例:
+//
+//	exporters:
+//	  - Name: tail
+//	    Config:
+//	      hub: "#svc.Tail"
+type Tail struct {
+	hub *tail.Hub
+}
+
+// NewTail 根据配置创建实时订阅导出器
+func NewTail(conf plugin.Conf) (*Tail, error) {
+	hub, ok := conf["hub"].(*tail.Hub)
+	if !ok || hub == nil {
+		return nil, fmt.Errorf("tail exporter: hub is required")
+	}
+	return &Tail{hub: hub}, nil
+}
+
+// Name 返回插件名称
+func (e *Tail) Name() string { return "tail" }
+
+// Export 将数据发布给订阅者
+// 创建时间由数据库写入时生成，发布时尚未赋值，以发布时间代替
+func (e *Tail) Export(ctx context.Context, data []interface{}) error {
+	now := time.Now()
+	for _, d := range data {
+		if v, ok := plugin.FieldValue(d, "created_at"); ok {
+			if t, ok := v.(time.Time); ok && t.IsZero() {
+				plugin.SetFieldValue(d, "created_at", now)
+			}
+		}
+	}
+	if err := e.hub.Publish(ctx, data); err != nil {
+		logx.Errorf("tail exporter: publish %d records failed: %v", len(data), err)
+	}
+	return nil
+}
+
+func init() {
+	plugin.RegisterExporterFactory("tail", func(config map[string]any) plugin.Exporter {
+		e, err := NewTail(config)
+		if err != nil {
+			panic(err)
+		}
+		return e
+	})
+}
+
+// 确保Tail实现了Exporter接口
+var _ plugin.Exporter = (*Tail)(nil)
@@ -0,0 +1,37 @@
+package exporter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/plugin"
+	"codexie.com/auditlog/pkg/tail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTail_Export(t *testing.T) {
+	_, err := NewTail(plugin.Conf{})
+	assert.Error(t, err)
+
+	hub := tail.NewHub(nil, tail.Config{Buffer: 4}, nil)
+	e, err := NewTail(plugin.Conf{"hub": hub})
+	require.NoError(t, err)
+	sub, err := hub.Subscribe(nil)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	created := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, e.Export(context.Background(), []interface{}{
+		&model.AuditLog{LogId: "1"},
+		&model.AuditLog{LogId: "2", CreatedAt: created},
+	}))
+
+	// 尚未写入数据库的记录以发布时间作为创建时间
+	first := (<-sub.C).(*model.AuditLog)
+	assert.Equal(t, "1", first.LogId)
+	assert.WithinDuration(t, time.Now(), first.CreatedAt, time.Minute)
+	assert.Equal(t, created, (<-sub.C).(*model.AuditLog).CreatedAt)
+}
@@ -0,0 +1,178 @@
+package plugin
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 结构体类型 -> json字段名 -> 字段下标
+var fieldIndexCache sync.Map
+
+// FieldValue 按json字段名读取数据中的字段值，支持结构体(指针)和map
+func FieldValue(data any, name string) (any, bool) {
+	if m, ok := data.(map[string]any); ok {
+		v, ok := m[name]
+		return v, ok
+	}
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	index, ok := fieldIndex(v.Type())[name]
+	if !ok {
+		return nil, false
+	}
+	return v.Field(index).Interface(), true
+}
+
+func fieldIndex(t reflect.Type) map[string]int {
+	if cached, ok := fieldIndexCache.Load(t); ok {
+		return cached.(map[string]int)
+	}
+
+	index := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		index[name] = i
+	}
+	fieldIndexCache.Store(t, index)
+	return index
+}
+
+// FieldMap 将数据转换为json字段名到字段值的映射，非结构体或map数据返回nil
+func FieldMap(data any) map[string]any {
+	if m, ok := data.(map[string]any); ok {
+		return m
+	}
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	index := fieldIndex(v.Type())
+	res := make(map[string]any, len(index))
+	for name, i := range index {
+		res[name] = v.Field(i).Interface()
+	}
+	return res
+}
+
+// SetFieldValue 按json字段名设置数据中的字段值，结构体必须以指针传入
+// 字符串值会按字段类型解析为数值或布尔值
+func SetFieldValue(data any, name string, value any) error {
+	if m, ok := data.(map[string]any); ok {
+		m[name] = value
+		return nil
+	}
+
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("set field %s: unsupported data type %T", name, data)
+	}
+	v = v.Elem()
+	index, ok := fieldIndex(v.Type())[name]
+	if !ok {
+		return fmt.Errorf("set field %s: no such field in %s", name, v.Type())
+	}
+
+	field := v.Field(index)
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	val := reflect.ValueOf(value)
+	if val.Type().AssignableTo(field.Type()) {
+		field.Set(val)
+		return nil
+	}
+	if str, ok := value.(string); ok {
+		return setFromString(field, name, str)
+	}
+	if val.Kind() == field.Kind() && val.Type().ConvertibleTo(field.Type()) {
+		field.Set(val.Convert(field.Type()))
+		return nil
+	}
+	return fmt.Errorf("set field %s: cannot assign %T to %s", name, value, field.Type())
+}
+
+func setFromString(field reflect.Value, name, str string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(str)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return fmt.Errorf("set field %s: %w", name, err)
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return fmt.Errorf("set field %s: %w", name, err)
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return fmt.Errorf("set field %s: %w", name, err)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return fmt.Errorf("set field %s: %w", name, err)
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("set field %s: cannot assign string to %s", name, field.Type())
+	}
+	return nil
+}
+
+// Clone 浅拷贝数据，结构体指针返回指向副本的新指针，map返回新map，其他值原样返回
+// 用于转换前复制数据，避免修改其他导出器共享的数据
+func Clone(data any) any {
+	if m, ok := data.(map[string]any); ok {
+		res := make(map[string]any, len(m))
+		for k, v := range m {
+			res[k] = v
+		}
+		return res
+	}
+
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return data
+	}
+	cp := reflect.New(v.Elem().Type())
+	cp.Elem().Set(v.Elem())
+	return cp.Interface()
+}
@@ -0,0 +1,132 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/plugin"
+	"github.com/google/cel-go/cel"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// CEL 表达式过滤器，启动时基于AuditLog结构编译表达式，逐条数据求值
+//
+// 配置示例(丢弃服务账号的成功READ操作):
+//
+//	filters:
+//	  - Name: cel
+//	    Config:
+//	      mode: deny      # allow|deny，默认allow
+//	      expr: 'action == "READ" && username.startsWith("svc-") && result != "fail"'
+type CEL struct {
+	mode    string
+	expr    string
+	program cel.Program
+}
+
+// NewCEL 编译表达式并创建过滤器，表达式必须返回bool
+func NewCEL(conf plugin.Conf) (*CEL, error) {
+	f := &CEL{
+		mode: strings.ToLower(conf.String("mode", ModeAllow)),
+		expr: conf.String("expr", ""),
+	}
+	if f.mode != ModeAllow && f.mode != ModeDeny {
+		return nil, fmt.Errorf("cel filter: invalid mode %q", f.mode)
+	}
+	if f.expr == "" {
+		return nil, fmt.Errorf("cel filter: expr is required")
+	}
+
+	env, err := cel.NewEnv(schemaVariables(reflect.TypeOf(model.AuditLog{}))...)
+	if err != nil {
+		return nil, fmt.Errorf("cel filter: %w", err)
+	}
+	ast, iss := env.Compile(f.expr)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("cel filter: compile %q: %w", f.expr, iss.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("cel filter: expr %q must return bool, got %s", f.expr, ast.OutputType())
+	}
+	f.program, err = env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("cel filter: %w", err)
+	}
+
+	return f, nil
+}
+
+// schemaVariables 按json字段名将结构体字段声明为CEL变量
+func schemaVariables(t reflect.Type) []cel.EnvOption {
+	timeType := reflect.TypeOf(time.Time{})
+	opts := make([]cel.EnvOption, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if !f.IsExported() || name == "" || name == "-" {
+			continue
+		}
+
+		var celType *cel.Type
+		switch {
+		case f.Type == timeType:
+			celType = cel.TimestampType
+		case f.Type.Kind() == reflect.String:
+			celType = cel.StringType
+		case f.Type.Kind() == reflect.Bool:
+			celType = cel.BoolType
+		case f.Type.Kind() >= reflect.Int && f.Type.Kind() <= reflect.Int64:
+			celType = cel.IntType
+		case f.Type.Kind() >= reflect.Uint && f.Type.Kind() <= reflect.Uint64:
+			celType = cel.UintType
+		case f.Type.Kind() == reflect.Float32 || f.Type.Kind() == reflect.Float64:
+			celType = cel.DoubleType
+		default:
+			celType = cel.DynType
+		}
+		opts = append(opts, cel.Variable(name, celType))
+	}
+	return opts
+}
+
+// Name 返回插件名称
+func (f *CEL) Name() string { return "cel" }
+
+// Filter 对数据求值，求值失败时保留数据，避免审计日志丢失
+func (f *CEL) Filter(data interface{}) bool {
+	vars := plugin.FieldMap(data)
+	if vars == nil {
+		return true
+	}
+
+	out, _, err := f.program.Eval(vars)
+	if err != nil {
+		logx.Errorf("cel filter: eval %q failed: %v", f.expr, err)
+		return true
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return true
+	}
+
+	if f.mode == ModeDeny {
+		return !matched
+	}
+	return matched
+}
+
+func init() {
+	plugin.RegisterFilterFactory("cel", func(config map[string]any) plugin.Filter {
+		f, err := NewCEL(config)
+		if err != nil {
+			panic(err)
+		}
+		return f
+	})
+}
+
+// 确保CEL实现了Filter接口
+var _ plugin.Filter = (*CEL)(nil)
@@ -0,0 +1,45 @@
+package filter
+
+import (
+	"testing"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCEL_Filter(t *testing.T) {
+	f, err := NewCEL(plugin.Conf{
+		"mode": "deny",
+		"expr": `action == "READ" && username.startsWith("svc-") && result != "fail"`,
+	})
+	require.NoError(t, err)
+
+	assert.False(t, f.Filter(&model.AuditLog{Action: "READ", Username: "svc-backup", Result: "success"}))
+	assert.True(t, f.Filter(&model.AuditLog{Action: "READ", Username: "svc-backup", Result: "fail"}))
+	assert.True(t, f.Filter(&model.AuditLog{Action: "READ", Username: "alice", Result: "success"}))
+	assert.True(t, f.Filter("not an audit log"))
+}
+
+func TestCEL_TypedFields(t *testing.T) {
+	f, err := NewCEL(plugin.Conf{"expr": `timestamp > 100 && id == 0`})
+	require.NoError(t, err)
+
+	assert.True(t, f.Filter(&model.AuditLog{TimeStamp: 200}))
+	assert.False(t, f.Filter(&model.AuditLog{TimeStamp: 50}))
+}
+
+func TestNewCEL_InvalidConfig(t *testing.T) {
+	cases := []plugin.Conf{
+		{},
+		{"expr": `action ==`},
+		{"expr": `unknown_field == "x"`},
+		{"expr": `action`},
+		{"expr": `true`, "mode": "drop"},
+	}
+	for _, conf := range cases {
+		_, err := NewCEL(conf)
+		assert.Error(t, err, "%v", conf)
+	}
+}
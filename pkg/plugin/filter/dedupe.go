@@ -0,0 +1,181 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"codexie.com/auditlog/pkg/plugin"
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/collection"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	DedupeBackendRedis = "redis" // 多副本共享去重窗口
+	DedupeBackendLocal = "local" // 进程内LRU，仅对单副本生效
+
+	defaultDedupeTTL      = 600 // 去重窗口，单位秒
+	defaultDedupePending  = 60  // 导出确认前幂等键的预留时间，单位秒
+	defaultDedupeCapacity = 100000
+	defaultDedupePrefix   = "audit:dedupe:"
+	dedupeRedisTimeout    = 500 * time.Millisecond
+)
+
+// Dedupe 幂等去重过滤器，按租户+幂等键在时间窗口内只保留第一条数据
+// 未携带幂等键的数据直接保留；Redis异常时放行，避免审计日志丢失
+//
+// 过滤时只预留幂等键(pending_ttl)，导出成功后才确认并保留完整的去重窗口；
+// 导出未完成(进程退出、导出失败等)时预留到期释放，客户端重试的数据可以再次通过，宁可重复也不丢失
+//
+// 配置示例:
+//
+//	filters:
+//	  - Name: dedupe
+//	    Config:
+//	      backend: redis            # redis|local，默认redis
+//	      redis: "#svc.Redis"
+//	      ttl: 600                  # 去重窗口(秒)
+//	      pending_ttl: 60           # 导出确认前的预留时间(秒)，需长于管道刷新间隔
+//	      key_field: idempotency_key
+//	      capacity: 100000          # local模式下LRU容量
+type Dedupe struct {
+	backend  string
+	keyField string
+	prefix   string
+	ttl      time.Duration
+	pending  time.Duration
+	redis    *redis.Client
+	cache    *collection.Cache
+	now      func() time.Time
+}
+
+// NewDedupe 根据配置创建去重过滤器
+func NewDedupe(conf plugin.Conf) (*Dedupe, error) {
+	d := &Dedupe{
+		backend:  strings.ToLower(conf.String("backend", DedupeBackendRedis)),
+		keyField: conf.String("key_field", "idempotency_key"),
+		prefix:   conf.String("prefix", defaultDedupePrefix),
+		ttl:      time.Duration(conf.Int("ttl", defaultDedupeTTL)) * time.Second,
+		pending:  time.Duration(conf.Int("pending_ttl", defaultDedupePending)) * time.Second,
+		now:      time.Now,
+	}
+	if d.ttl <= 0 {
+		return nil, fmt.Errorf("dedupe filter: ttl must be positive")
+	}
+	if d.pending <= 0 || d.pending > d.ttl {
+		return nil, fmt.Errorf("dedupe filter: pending_ttl must be positive and not exceed ttl")
+	}
+
+	switch d.backend {
+	case DedupeBackendRedis:
+		client, ok := conf["redis"].(*redis.Client)
+		if !ok || client == nil {
+			return nil, fmt.Errorf("dedupe filter: redis backend requires redis client")
+		}
+		d.redis = client
+	case DedupeBackendLocal:
+		cache, err := collection.NewCache(d.ttl,
+			collection.WithLimit(conf.Int("capacity", defaultDedupeCapacity)),
+			collection.WithName("dedupe-filter"))
+		if err != nil {
+			return nil, fmt.Errorf("dedupe filter: %w", err)
+		}
+		d.cache = cache
+	default:
+		return nil, fmt.Errorf("dedupe filter: invalid backend %q", d.backend)
+	}
+
+	return d, nil
+}
+
+// Name 返回插件名称
+func (d *Dedupe) Name() string { return "dedupe" }
+
+// Filter 首次出现的幂等键返回true并预留该键，已确认或预留中的键返回false
+func (d *Dedupe) Filter(data interface{}) bool {
+	key := d.dedupeKey(data)
+	if key == "" {
+		return true
+	}
+
+	if d.cache != nil {
+		// 本地缓存的值为键的到期时间，预留到期后视为不存在
+		if v, ok := d.cache.Get(key); ok && d.now().Before(v.(time.Time)) {
+			return false
+		}
+		d.cache.Set(key, d.now().Add(d.pending))
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dedupeRedisTimeout)
+	defer cancel()
+	first, err := d.redis.SetNX(ctx, d.prefix+key, 0, d.pending).Result()
+	if err != nil {
+		logx.Errorf("dedupe filter: setnx %s failed: %v", key, err)
+		return true
+	}
+	return first
+}
+
+// OnExported 导出成功后确认幂等键，保留完整的去重窗口
+func (d *Dedupe) OnExported(ctx context.Context, batch []interface{}) {
+	keys := make([]string, 0, len(batch))
+	for _, data := range batch {
+		if key := d.dedupeKey(data); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	if d.cache != nil {
+		for _, key := range keys {
+			d.cache.Set(key, d.now().Add(d.ttl))
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dedupeRedisTimeout)
+	defer cancel()
+	pipe := d.redis.Pipeline()
+	for _, key := range keys {
+		pipe.Set(ctx, d.prefix+key, 1, d.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		// 确认失败时预留到期释放，重试的数据可能重复写入
+		logx.Errorf("dedupe filter: confirm %d keys failed: %v", len(keys), err)
+	}
+}
+
+// dedupeKey 以租户隔离幂等键，避免不同租户的键冲突
+func (d *Dedupe) dedupeKey(data interface{}) string {
+	val, ok := plugin.FieldValue(data, d.keyField)
+	if !ok {
+		return ""
+	}
+	key, _ := val.(string)
+	if key == "" {
+		return ""
+	}
+	tenant, _ := plugin.FieldValue(data, "tenant_id")
+	return fmt.Sprintf("%v:%s", tenant, key)
+}
+
+func init() {
+	plugin.RegisterFilterFactory("dedupe", func(config map[string]any) plugin.Filter {
+		d, err := NewDedupe(config)
+		if err != nil {
+			panic(err)
+		}
+		return d
+	})
+}
+
+// 确保Dedupe实现了Filter和ExportObserver接口
+var (
+	_ plugin.Filter         = (*Dedupe)(nil)
+	_ plugin.ExportObserver = (*Dedupe)(nil)
+)
@@ -0,0 +1,80 @@
+package filter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/plugin"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupe_Local(t *testing.T) {
+	d, err := NewDedupe(plugin.Conf{"backend": "local", "ttl": "60"})
+	require.NoError(t, err)
+
+	assert.True(t, d.Filter(&model.AuditLog{TenantID: "t1", IdempotencyKey: "k1"}))
+	assert.False(t, d.Filter(&model.AuditLog{TenantID: "t1", IdempotencyKey: "k1"}), "retry should be dropped")
+	assert.True(t, d.Filter(&model.AuditLog{TenantID: "t2", IdempotencyKey: "k1"}), "keys are scoped by tenant")
+	assert.True(t, d.Filter(&model.AuditLog{TenantID: "t1"}), "records without key are kept")
+	assert.True(t, d.Filter(&model.AuditLog{TenantID: "t1"}))
+}
+
+func TestDedupe_LocalPending(t *testing.T) {
+	d, err := NewDedupe(plugin.Conf{"backend": "local", "ttl": "600", "pending_ttl": "30"})
+	require.NoError(t, err)
+	now := time.Now()
+	d.now = func() time.Time { return now }
+	log := &model.AuditLog{TenantID: "t1", IdempotencyKey: "k1"}
+
+	// 未确认的预留到期后，重试的数据可以再次通过
+	assert.True(t, d.Filter(log))
+	now = now.Add(31 * time.Second)
+	assert.True(t, d.Filter(log), "unconfirmed key is released after pending_ttl")
+
+	// 导出成功确认后保留完整窗口
+	d.OnExported(context.Background(), []interface{}{log})
+	now = now.Add(5 * time.Minute)
+	assert.False(t, d.Filter(log))
+}
+
+func TestDedupe_Redis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	d, err := NewDedupe(plugin.Conf{"redis": client, "ttl": "600", "pending_ttl": "30"})
+	require.NoError(t, err)
+	log := &model.AuditLog{TenantID: "t1", IdempotencyKey: "k1"}
+	key := defaultDedupePrefix + "t1:k1"
+
+	assert.True(t, d.Filter(log))
+	assert.False(t, d.Filter(log))
+	assert.Equal(t, 30*time.Second, mr.TTL(key))
+
+	// 导出失败未确认：预留到期后重试通过
+	mr.FastForward(31 * time.Second)
+	assert.True(t, d.Filter(log))
+
+	d.OnExported(context.Background(), []interface{}{log, &model.AuditLog{TenantID: "t1"}})
+	assert.Equal(t, 600*time.Second, mr.TTL(key))
+	mr.FastForward(5 * time.Minute)
+	assert.False(t, d.Filter(log))
+}
+
+func TestNewDedupe_InvalidConfig(t *testing.T) {
+	cases := []plugin.Conf{
+		{"backend": "memcache"},
+		{"backend": "redis"},
+		{"backend": "local", "ttl": "0"},
+		{"backend": "local", "pending_ttl": "0"},
+		{"backend": "local", "ttl": "60", "pending_ttl": "120"},
+	}
+	for _, conf := range cases {
+		_, err := NewDedupe(conf)
+		assert.Error(t, err, "%v", conf)
+	}
+}
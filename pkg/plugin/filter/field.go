@@ -1,30 +1,233 @@
 package filter
 
-import "codexie.com/auditlog/pkg/plugin"
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
-// Field 字段过滤器
+	"codexie.com/auditlog/pkg/plugin"
+)
+
+const (
+	ModeAllow = "allow" // 命中规则的数据保留，其余丢弃
+	ModeDeny  = "deny"  // 命中规则的数据丢弃，其余保留
+
+	LogicAnd = "and"
+	LogicOr  = "or"
+)
+
+// 规则操作符
+const (
+	OpEq     = "eq"
+	OpIn     = "in"
+	OpRegex  = "regex"
+	OpPrefix = "prefix"
+	OpRange  = "range"
+)
+
+// Field 字段过滤器，按数据字段上的规则决定保留或丢弃
+//
+// 配置示例:
+//
+//	filters:
+//	  - Name: field
+//	    Config:
+//	      mode: deny      # allow|deny，默认allow
+//	      logic: and      # and|or，默认and
+//	      rules:
+//	        - {field: action, op: in, values: [READ, LIST]}
+//	        - {field: username, op: prefix, value: "svc-"}
+//	        - {field: timestamp, op: range, min: 1700000000000}
 type Field struct {
-	allowedFields map[string]bool
+	mode  string
+	logic string
+	rules []*fieldRule
 }
 
-// NewField 创建新的字段过滤器
-func NewField(allowedFields []string) *Field {
-	allowed := make(map[string]bool)
-	for _, field := range allowedFields {
-		allowed[field] = true
+type fieldRule struct {
+	field    string
+	op       string
+	value    string
+	values   map[string]bool
+	re       *regexp.Regexp
+	min, max *float64
+}
+
+// NewField 根据配置创建字段过滤器
+func NewField(conf plugin.Conf) (*Field, error) {
+	f := &Field{
+		mode:  strings.ToLower(conf.String("mode", ModeAllow)),
+		logic: strings.ToLower(conf.String("logic", LogicAnd)),
+	}
+	if f.mode != ModeAllow && f.mode != ModeDeny {
+		return nil, fmt.Errorf("field filter: invalid mode %q", f.mode)
+	}
+	if f.logic != LogicAnd && f.logic != LogicOr {
+		return nil, fmt.Errorf("field filter: invalid logic %q", f.logic)
 	}
 
-	return &Field{
-		allowedFields: allowed,
+	ruleConfs, err := conf.List("rules")
+	if err != nil {
+		return nil, fmt.Errorf("field filter: %w", err)
+	}
+	for i, rc := range ruleConfs {
+		rule, err := newFieldRule(rc)
+		if err != nil {
+			return nil, fmt.Errorf("field filter: rules[%d]: %w", i, err)
+		}
+		f.rules = append(f.rules, rule)
 	}
+
+	return f, nil
+}
+
+func newFieldRule(conf plugin.Conf) (*fieldRule, error) {
+	r := &fieldRule{
+		field: conf.String("field", ""),
+		op:    strings.ToLower(conf.String("op", OpEq)),
+		value: conf.String("value", ""),
+	}
+	if r.field == "" {
+		return nil, fmt.Errorf("field is required")
+	}
+
+	switch r.op {
+	case OpEq, OpPrefix:
+	case OpIn:
+		r.values = make(map[string]bool)
+		for _, v := range conf.Strings("values") {
+			r.values[v] = true
+		}
+	case OpRegex:
+		re, err := regexp.Compile(r.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", r.value, err)
+		}
+		r.re = re
+	case OpRange:
+		if _, ok := conf["min"]; ok {
+			v := conf.Float("min", 0)
+			r.min = &v
+		}
+		if _, ok := conf["max"]; ok {
+			v := conf.Float("max", 0)
+			r.max = &v
+		}
+		if r.min == nil && r.max == nil {
+			return nil, fmt.Errorf("range requires min or max")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported op %q", r.op)
+	}
+	return r, nil
 }
 
 // Name 返回插件名称
-func (f *Field) Name() string { return "field-filter" }
+func (f *Field) Name() string { return "field" }
 
-// Filter 过滤数据
+// Filter 过滤数据，返回true表示保留
 func (f *Field) Filter(data interface{}) bool {
-	return true
+	if len(f.rules) == 0 {
+		return true
+	}
+
+	// and: 任一规则不命中即不命中；or: 任一规则命中即命中
+	matched := f.logic == LogicAnd
+	for _, rule := range f.rules {
+		ok := rule.match(data)
+		if f.logic == LogicAnd && !ok {
+			matched = false
+			break
+		}
+		if f.logic == LogicOr && ok {
+			matched = true
+			break
+		}
+	}
+
+	if f.mode == ModeDeny {
+		return !matched
+	}
+	return matched
+}
+
+func (r *fieldRule) match(data interface{}) bool {
+	val, ok := plugin.FieldValue(data, r.field)
+	if !ok {
+		return false
+	}
+
+	switch r.op {
+	case OpEq:
+		return toString(val) == r.value
+	case OpIn:
+		return r.values[toString(val)]
+	case OpRegex:
+		return r.re.MatchString(toString(val))
+	case OpPrefix:
+		return strings.HasPrefix(toString(val), r.value)
+	case OpRange:
+		num, ok := toFloat(val)
+		if !ok {
+			return false
+		}
+		if r.min != nil && num < *r.min {
+			return false
+		}
+		if r.max != nil && num > *r.max {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func toString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case time.Time:
+		return val.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+// toFloat 将字段值转为数值，时间类型取毫秒时间戳
+func toFloat(v any) (float64, bool) {
+	switch val := v.(type) {
+	case int:
+		return float64(val), true
+	case int32:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case uint:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	case float32:
+		return float64(val), true
+	case float64:
+		return val, true
+	case time.Time:
+		return float64(val.UnixMilli()), true
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func init() {
+	plugin.RegisterFilterFactory("field", func(config map[string]any) plugin.Filter {
+		f, err := NewField(config)
+		if err != nil {
+			panic(err)
+		}
+		return f
+	})
 }
 
 // 确保Field实现了Filter接口
@@ -0,0 +1,97 @@
+package filter
+
+import (
+	"testing"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rules(items ...map[string]any) []any {
+	res := make([]any, 0, len(items))
+	for _, item := range items {
+		res = append(res, item)
+	}
+	return res
+}
+
+func TestField_Filter(t *testing.T) {
+	read := &model.AuditLog{Action: "READ", Username: "svc-backup", Result: "success", TimeStamp: 100}
+	del := &model.AuditLog{Action: "DELETE_VM", Username: "alice", Result: "fail", TimeStamp: 200}
+
+	t.Run("no rules keeps everything", func(t *testing.T) {
+		f, err := NewField(plugin.Conf{})
+		require.NoError(t, err)
+		assert.True(t, f.Filter(read))
+	})
+
+	t.Run("deny in list", func(t *testing.T) {
+		f, err := NewField(plugin.Conf{
+			"mode":  "deny",
+			"rules": rules(map[string]any{"field": "action", "op": "in", "values": []any{"READ", "LIST"}}),
+		})
+		require.NoError(t, err)
+		assert.False(t, f.Filter(read))
+		assert.True(t, f.Filter(del))
+	})
+
+	t.Run("allow with and", func(t *testing.T) {
+		f, err := NewField(plugin.Conf{
+			"rules": rules(
+				map[string]any{"field": "result", "value": "fail"},
+				map[string]any{"field": "action", "op": "regex", "value": "^DELETE_"},
+			),
+		})
+		require.NoError(t, err)
+		assert.False(t, f.Filter(read))
+		assert.True(t, f.Filter(del))
+	})
+
+	t.Run("allow with or", func(t *testing.T) {
+		f, err := NewField(plugin.Conf{
+			"logic": "or",
+			"rules": rules(
+				map[string]any{"field": "username", "op": "prefix", "value": "svc-"},
+				map[string]any{"field": "timestamp", "op": "range", "min": "150", "max": "250"},
+			),
+		})
+		require.NoError(t, err)
+		assert.True(t, f.Filter(read))
+		assert.True(t, f.Filter(del))
+		assert.False(t, f.Filter(&model.AuditLog{Username: "bob", TimeStamp: 300}))
+	})
+
+	t.Run("unknown field never matches", func(t *testing.T) {
+		f, err := NewField(plugin.Conf{
+			"rules": rules(map[string]any{"field": "not_exist", "value": "x"}),
+		})
+		require.NoError(t, err)
+		assert.False(t, f.Filter(read))
+	})
+
+	t.Run("map data", func(t *testing.T) {
+		f, err := NewField(plugin.Conf{
+			"rules": rules(map[string]any{"field": "action", "value": "READ"}),
+		})
+		require.NoError(t, err)
+		assert.True(t, f.Filter(map[string]any{"action": "READ"}))
+	})
+}
+
+func TestNewField_InvalidConfig(t *testing.T) {
+	cases := []plugin.Conf{
+		{"mode": "block"},
+		{"logic": "xor"},
+		{"rules": "action=READ"},
+		{"rules": rules(map[string]any{"op": "eq", "value": "x"})},
+		{"rules": rules(map[string]any{"field": "action", "op": "like"})},
+		{"rules": rules(map[string]any{"field": "action", "op": "regex", "value": "("})},
+		{"rules": rules(map[string]any{"field": "timestamp", "op": "range"})},
+	}
+	for _, conf := range cases {
+		_, err := NewField(conf)
+		assert.Error(t, err, "%v", conf)
+	}
+}
@@ -0,0 +1,153 @@
+package filter
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+
+	"codexie.com/auditlog/pkg/plugin"
+)
+
+const samplingBuckets = 10000
+
+// Sampling 采样过滤器，按动作或模块配置保留比例
+// 按trace_id/user_id哈希确定性采样，同一链路或用户的数据同时保留或丢弃；失败结果的数据始终保留
+//
+// 配置示例:
+//
+//	filters:
+//	  - Name: sampling
+//	    Config:
+//	      keys: [trace_id, user_id]   # 依次取第一个非空字段作为哈希键
+//	      default_rate: 1             # 未匹配的数据保留比例
+//	      actions:                    # 按动作配置，优先于modules
+//	        HEARTBEAT: 0.01
+//	        READ: 0.1
+//	      modules:
+//	        monitor: 0.05
+//	      keep_results: [denied]      # 额外始终保留的结果，fail总会保留
+type Sampling struct {
+	keys        []string
+	defaultRate float64
+	actions     map[string]float64
+	modules     map[string]float64
+	keepResults map[string]bool
+}
+
+// NewSampling 根据配置创建采样过滤器
+func NewSampling(conf plugin.Conf) (*Sampling, error) {
+	s := &Sampling{
+		keys:        conf.Strings("keys"),
+		defaultRate: conf.Float("default_rate", 1),
+		keepResults: make(map[string]bool),
+	}
+	if _, ok := conf["keys"]; !ok {
+		s.keys = []string{"trace_id", "user_id"}
+	}
+	if err := checkRate("default_rate", s.defaultRate); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if s.actions, err = samplingRates(conf, "actions"); err != nil {
+		return nil, err
+	}
+	if s.modules, err = samplingRates(conf, "modules"); err != nil {
+		return nil, err
+	}
+
+	// 失败结果始终保留，配置只能追加，不能通过配置丢弃失败记录
+	results := append([]string{"fail"}, conf.Strings("keep_results")...)
+	for _, r := range results {
+		s.keepResults[strings.ToLower(r)] = true
+	}
+
+	return s, nil
+}
+
+func samplingRates(conf plugin.Conf, key string) (map[string]float64, error) {
+	m, err := conf.Map(key)
+	if err != nil {
+		return nil, fmt.Errorf("sampling filter: %w", err)
+	}
+	rates := make(map[string]float64, len(m))
+	for name := range m {
+		rate := m.Float(name, -1)
+		if err := checkRate(key+"."+name, rate); err != nil {
+			return nil, err
+		}
+		rates[name] = rate
+	}
+	return rates, nil
+}
+
+func checkRate(name string, rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("sampling filter: %s must be between 0 and 1", name)
+	}
+	return nil
+}
+
+// Name 返回插件名称
+func (s *Sampling) Name() string { return "sampling" }
+
+// Filter 按采样比例决定是否保留
+func (s *Sampling) Filter(data interface{}) bool {
+	if result, ok := plugin.FieldValue(data, "result"); ok && s.keepResults[strings.ToLower(toString(result))] {
+		return true
+	}
+
+	rate := s.rateFor(data)
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+
+	return float64(s.bucket(data))/samplingBuckets < rate
+}
+
+func (s *Sampling) rateFor(data interface{}) float64 {
+	if action, ok := plugin.FieldValue(data, "action"); ok {
+		if rate, ok := s.actions[toString(action)]; ok {
+			return rate
+		}
+	}
+	if module, ok := plugin.FieldValue(data, "module"); ok {
+		if rate, ok := s.modules[toString(module)]; ok {
+			return rate
+		}
+	}
+	return s.defaultRate
+}
+
+// bucket 按哈希键计算采样桶，所有键为空时随机采样
+func (s *Sampling) bucket(data interface{}) uint64 {
+	for _, key := range s.keys {
+		val, ok := plugin.FieldValue(data, key)
+		if !ok {
+			continue
+		}
+		if str := toString(val); str != "" {
+			h := fnv.New64a()
+			h.Write([]byte(str))
+			return h.Sum64() % samplingBuckets
+		}
+	}
+	return uint64(rand.Intn(samplingBuckets))
+}
+
+func init() {
+	plugin.RegisterFilterFactory("sampling", func(config map[string]any) plugin.Filter {
+		s, err := NewSampling(config)
+		if err != nil {
+			panic(err)
+		}
+		return s
+	})
+}
+
+// 确保Sampling实现了Filter接口
+var _ plugin.Filter = (*Sampling)(nil)
@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampling_Filter(t *testing.T) {
+	s, err := NewSampling(plugin.Conf{
+		"actions": map[string]any{"HEARTBEAT": "0", "READ": "0.2"},
+		"modules": map[string]any{"monitor": "0.5"},
+	})
+	require.NoError(t, err)
+
+	assert.False(t, s.Filter(&model.AuditLog{Action: "HEARTBEAT", TraceID: "t"}))
+	assert.True(t, s.Filter(&model.AuditLog{Action: "HEARTBEAT", Result: "FAIL"}), "failures are always kept")
+	assert.True(t, s.Filter(&model.AuditLog{Action: "CREATE_VM"}), "default rate keeps everything")
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		if s.Filter(&model.AuditLog{Action: "READ", TraceID: fmt.Sprintf("trace-%d", i)}) {
+			kept++
+		}
+	}
+	assert.InDelta(t, 2000, kept, 300)
+}
+
+func TestSampling_KeepResults(t *testing.T) {
+	// 配置的结果追加到fail之后，不能替换掉fail
+	s, err := NewSampling(plugin.Conf{"default_rate": "0", "keep_results": []any{"denied"}})
+	require.NoError(t, err)
+	assert.True(t, s.Filter(&model.AuditLog{Result: "fail"}))
+	assert.True(t, s.Filter(&model.AuditLog{Result: "DENIED"}))
+	assert.False(t, s.Filter(&model.AuditLog{Result: "success"}))
+
+	s, err = NewSampling(plugin.Conf{"default_rate": "0", "keep_results": []any{}})
+	require.NoError(t, err)
+	assert.True(t, s.Filter(&model.AuditLog{Result: "fail"}), "an empty list still keeps failures")
+}
+
+func TestSampling_Deterministic(t *testing.T) {
+	s, err := NewSampling(plugin.Conf{"default_rate": "0.5"})
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		traceID := fmt.Sprintf("trace-%d", i)
+		first := s.Filter(&model.AuditLog{Action: "READ", TraceID: traceID})
+		second := s.Filter(&model.AuditLog{Action: "LIST", TraceID: traceID, UserID: "other"})
+		assert.Equal(t, first, second, "events of the same trace are kept or dropped together")
+	}
+}
+
+func TestNewSampling_InvalidConfig(t *testing.T) {
+	cases := []plugin.Conf{
+		{"default_rate": "2"},
+		{"actions": map[string]any{"READ": "-0.1"}},
+		{"modules": "monitor=0.1"},
+	}
+	for _, conf := range cases {
+		_, err := NewSampling(conf)
+		assert.Error(t, err, "%v", conf)
+	}
+}
@@ -21,9 +21,30 @@ type Filter interface {
 	Filter(data interface{}) bool
 }
 
+// ExportObserver 需要在数据导出成功后执行确认的插件，如去重过滤器在此确认幂等键
+// 全局过滤器在所有导出器都成功后收到整批数据，导出器路由上的过滤器在对应导出器成功后收到路由后的数据
+type ExportObserver interface {
+	OnExported(ctx context.Context, batch []interface{})
+}
+
+// Transformer 数据转换插件接口，逐条处理数据
+// 返回空切片表示丢弃该数据，返回多条表示将其拆分
+type Transformer interface {
+	Plugin
+	Transform(data interface{}) []interface{}
+}
+
 // LifecycleHook 生命周期钩子插件接口，支持泛型
+// BeforeExport返回错误时整批数据不导出，由管道保留并在下次刷新时重新执行钩子
 type LifecycleHook interface {
 	Plugin
-	BeforeExport(ctx context.Context, batch []interface{}) context.Context
+	BeforeExport(ctx context.Context, batch []interface{}) (context.Context, error)
 	OnError(ctx context.Context, err error, batch []interface{})
 }
+
+// Sealer 对导出内容计算哈希或签名的钩子(如哈希链)，钩子之后数据不能再被修改
+// 管道中注册了Sealer时，导出路由上不能配置转换器
+type Sealer interface {
+	LifecycleHook
+	SealsContent()
+}
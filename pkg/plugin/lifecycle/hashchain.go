@@ -0,0 +1,110 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/plugin"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+)
+
+const hashChainRetry = 3
+
+// HashChainHook 哈希链钩子，为每条记录分配租户内递增序号并计算 hash = H(prev_hash || canonical(record))
+// 链头保存在audit_chain_head表中，通过行锁保证多副本下序号连续
+// 需配置在logid钩子之后、作为最后一个钩子，使log_id参与哈希计算且入链后的记录不再被修改
+//
+// 序号一经分配即属于该记录，不会重新分配：钩子失败被管道保留的批次重试时跳过已入链的记录，
+// 导出失败的数据连同序号落盘后恢复导出，因此只有记录最终丢失时校验才会报告缺失
+type HashChainHook struct {
+	db *gorm.DB
+}
+
+func NewHashChainHook(conf map[string]any) *HashChainHook {
+	return &HashChainHook{
+		db: conf["db"].(*gorm.DB),
+	}
+}
+
+// Name 返回插件名称
+func (h *HashChainHook) Name() string { return "hashchain" }
+
+// BeforeExport 导出前为记录入链，重试后仍失败时返回错误，整批由管道保留重试，不导出未入链的记录
+func (h *HashChainHook) BeforeExport(ctx context.Context, batch []interface{}) (context.Context, error) {
+	logs := make([]*model.AuditLog, 0, len(batch))
+	tenantSet := make(map[string]bool)
+	for _, data := range batch {
+		// 已入链的记录来自之前保留的批次，保持原序号
+		if log, ok := data.(*model.AuditLog); ok && log.Seq == 0 {
+			logs = append(logs, log)
+			tenantSet[log.TenantID] = true
+		}
+	}
+	if len(logs) == 0 {
+		return ctx, nil
+	}
+	tenants := make([]string, 0, len(tenantSet))
+	for tenant := range tenantSet {
+		tenants = append(tenants, tenant)
+	}
+
+	var err error
+	for i := 0; i < hashChainRetry; i++ {
+		if err = h.link(ctx, tenants, logs); err == nil {
+			return ctx, nil
+		}
+		time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
+	}
+
+	logx.Errorf("hash chain: link %d records failed: %v", len(logs), err)
+	return ctx, fmt.Errorf("hash chain: %w", err)
+}
+
+// link 在一个事务中锁定链头、按批次顺序为记录分配序号并更新链头
+// 事务提交后才写回记录，失败时记录保持未入链，重试时重新分配
+func (h *HashChainHook) link(ctx context.Context, tenants []string, logs []*model.AuditLog) error {
+	linked := make([]model.AuditLog, len(logs))
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		heads, err := model.LockChainHeads(tx, tenants)
+		if err != nil {
+			return err
+		}
+		for i, log := range logs {
+			head := heads[log.TenantID]
+			head.Seq++
+			linked[i] = *log
+			linked[i].Seq = head.Seq
+			linked[i].PrevHash = head.Hash
+			linked[i].Hash = linked[i].ComputeHash()
+			head.Hash = linked[i].Hash
+		}
+		return model.SaveChainHeads(tx, heads)
+	})
+	if err != nil {
+		return err
+	}
+	for i, log := range logs {
+		log.Seq, log.PrevHash, log.Hash = linked[i].Seq, linked[i].PrevHash, linked[i].Hash
+	}
+	return nil
+}
+
+// OnError 错误处理钩子
+// 钩子失败的批次由管道保留、导出失败的批次落盘，重试时都沿用已分配的序号，无需回收
+func (h *HashChainHook) OnError(ctx context.Context, err error, batch []interface{}) {}
+
+// SealsContent 入链后记录内容不能再被修改，管道据此拒绝在导出路由上配置转换器
+func (h *HashChainHook) SealsContent() {}
+
+func init() {
+	plugin.RegisterLifecycleFactory("hashchain", func(config map[string]any) plugin.LifecycleHook {
+		return NewHashChainHook(config)
+	})
+}
+
+// 确保HashChainHook实现了Sealer接口
+var _ plugin.Sealer = (*HashChainHook)(nil)
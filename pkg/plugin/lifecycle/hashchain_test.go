@@ -0,0 +1,94 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"codexie.com/auditlog/internal/model"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func chainDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	require.NoError(t, db.AutoMigrate(&model.ChainHead{}))
+	return db
+}
+
+func chainBatch(tenants ...string) []interface{} {
+	batch := make([]interface{}, 0, len(tenants))
+	for i, tenant := range tenants {
+		batch = append(batch, &model.AuditLog{LogId: fmt.Sprintf("log-%d", i), TenantID: tenant, Action: "login"})
+	}
+	return batch
+}
+
+func TestHashChainHook_Link(t *testing.T) {
+	db := chainDB(t)
+	h := NewHashChainHook(map[string]any{"db": db})
+
+	first := chainBatch("a", "b", "a")
+	_, err := h.BeforeExport(context.Background(), first)
+	require.NoError(t, err)
+	second := chainBatch("a")
+	_, err = h.BeforeExport(context.Background(), second)
+	require.NoError(t, err)
+
+	// 租户内序号连续，prev_hash指向租户内的前一条记录
+	logs := []*model.AuditLog{first[0].(*model.AuditLog), first[2].(*model.AuditLog), second[0].(*model.AuditLog)}
+	prev := ""
+	for i, log := range logs {
+		assert.Equal(t, int64(i+1), log.Seq)
+		assert.Equal(t, prev, log.PrevHash)
+		assert.Equal(t, log.ComputeHash(), log.Hash)
+		prev = log.Hash
+	}
+	assert.Equal(t, int64(1), first[1].(*model.AuditLog).Seq)
+
+	head := &model.ChainHead{}
+	require.NoError(t, db.First(head, "tenant_id = ?", "a").Error)
+	assert.Equal(t, int64(3), head.Seq)
+	assert.Equal(t, prev, head.Hash)
+}
+
+func TestHashChainHook_HeldBatchKeepsSeq(t *testing.T) {
+	db := chainDB(t)
+	h := NewHashChainHook(map[string]any{"db": db})
+
+	// 后续钩子失败时管道保留整批，重试时已入链的记录不重新分配序号
+	held := chainBatch("a", "a")
+	_, err := h.BeforeExport(context.Background(), held)
+	require.NoError(t, err)
+	hashes := []string{held[0].(*model.AuditLog).Hash, held[1].(*model.AuditLog).Hash}
+
+	retry := append(held, chainBatch("a")...)
+	_, err = h.BeforeExport(context.Background(), retry)
+	require.NoError(t, err)
+	assert.Equal(t, hashes, []string{held[0].(*model.AuditLog).Hash, held[1].(*model.AuditLog).Hash})
+	assert.Equal(t, int64(3), retry[2].(*model.AuditLog).Seq)
+	assert.Equal(t, hashes[1], retry[2].(*model.AuditLog).PrevHash)
+}
+
+func TestHashChainHook_FailedLinkLeavesRecords(t *testing.T) {
+	db := chainDB(t)
+	h := NewHashChainHook(map[string]any{"db": db})
+	require.NoError(t, db.Migrator().DropTable(&model.ChainHead{}))
+
+	batch := chainBatch("a")
+	_, err := h.BeforeExport(context.Background(), batch)
+	require.Error(t, err)
+	// 事务失败时记录保持未入链，重试时重新分配
+	log := batch[0].(*model.AuditLog)
+	assert.Zero(t, log.Seq)
+	assert.Empty(t, log.Hash)
+}
@@ -2,77 +2,192 @@ package lifecycle
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
+	"sync"
 	"time"
 
-	"codexie.com/auditlog/internal/constant"
 	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/idgen"
 	"codexie.com/auditlog/pkg/plugin"
 
-	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/zeromicro/go-zero/core/logx"
-	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
 )
 
+const (
+	defaultWorkerTTL       = 30 // 雪花机器号租约，单位秒
+	defaultPositionRefresh = 30 // 分表位置与MySQL对齐的间隔，单位秒
+)
+
+// LogIdHook 日志ID钩子，生成 <id>_<shard> 格式的日志ID，分表序号用于写入路由
+//
+// 配置示例:
+//
+//	lifecycles:
+//	  - Name: logid
+//	    Config:
+//	      redis: "#svc.Redis"
+//	      db: "#svc.DB"
+//	      generator: ulid   # ulid|snowflake|uuid，默认ulid
+//	      worker_ttl: 30    # snowflake机器号租约(秒)
+//	      refresh_interval: 30 # 分表位置与MySQL对齐的间隔(秒)
+//	      route_field: created_at # 按时间分表时的路由字段，created_at(入库时间)|timestamp(客户端时间)
 type LogIdHook struct {
-	redis        *redis.Client
-	db           *gorm.DB
-	singleFilght singleflight.Group
+	redis      *redis.Client
+	db         *gorm.DB
+	positions  *positionCache
+	generator  idgen.Generator
+	lease      *idgen.WorkerLease
+	routeField string
+	shards     sync.Map // 已确认存在的分表，key为<entity>_<shard>
 }
 
 func NewLogIdHook(conf map[string]any) *LogIdHook {
-	return &LogIdHook{
-		redis:        conf["redis"].(*redis.Client),
-		db:           conf["db"].(*gorm.DB),
-		singleFilght: singleflight.Group{},
+	c := plugin.Conf(conf)
+	h := &LogIdHook{
+		redis:      conf["redis"].(*redis.Client),
+		db:         conf["db"].(*gorm.DB),
+		routeField: c.String("route_field", "created_at"),
+	}
+	refresh := c.Int("refresh_interval", defaultPositionRefresh)
+	if refresh <= 0 {
+		panic(fmt.Sprintf("logid: refresh_interval must be positive, got %d", refresh))
+	}
+	h.positions = newPositionCache(h.db, h.redis, time.Duration(refresh)*time.Second)
+
+	kind := c.String("generator", idgen.KindULID)
+	var lease *idgen.WorkerLease
+	if kind == idgen.KindSnowflake {
+		workerTTL := c.Int("worker_ttl", defaultWorkerTTL)
+		if workerTTL <= 0 {
+			panic(fmt.Sprintf("logid: worker_ttl must be positive, got %d", workerTTL))
+		}
+		ttl := time.Duration(workerTTL) * time.Second
+		var err error
+		if lease, err = idgen.LeaseWorker(context.Background(), h.redis, "", ttl); err != nil {
+			panic(err)
+		}
+	}
+	generator, err := idgen.New(kind, lease)
+	if err != nil {
+		panic(err)
 	}
+	h.generator = generator
+	h.lease = lease
+	return h
 }
 
 // Name 返回插件名称
 func (h *LogIdHook) Name() string { return "logid" }
 
-// BeforeExport 导出前钩子
-func (h *LogIdHook) BeforeExport(ctx context.Context, batch []interface{}) context.Context {
-	entity := batch[0].(model.Entity)
-	schedulePos := &model.SchedulePos{
-		Name: entity.Name(),
+// BeforeExport 导出前钩子，分表位置取自进程内缓存，按实体的分表策略路由
+// 获取位置或生成ID失败时返回错误，整批由管道保留并在下次刷新时重新分配，避免导出没有日志ID的数据
+// 批次中没有实现Entity的数据时不做处理
+func (h *LogIdHook) BeforeExport(ctx context.Context, batch []interface{}) (context.Context, error) {
+	var entity model.Entity
+	for _, data := range batch {
+		if e, ok := data.(model.Entity); ok {
+			entity = e
+			break
+		}
 	}
-	key := fmt.Sprintf("%s:%s", constant.SchedulePosKey, entity.Name())
-	res, err := h.redis.Get(ctx, key).Result()
-	if err != nil {
-		_, err, _ := h.singleFilght.Do(key, func() (interface{}, error) {
-			schedulePos, err = schedulePos.GetSchedulePos(h.db, schedulePos.Name)
-			if err != nil {
-				return nil, err
+	if entity == nil {
+		return ctx, nil
+	}
+	pos, err := h.positions.get(ctx, entity.Name())
+	if err == nil {
+		var strategy model.ShardStrategy
+		if strategy, err = pos.ShardStrategy(); err == nil {
+			err = h.assign(batch, pos, strategy)
+		}
+	}
+	return ctx, err
+}
+
+// assign 为数据生成日志ID，入库时间在此固定，使按时间分表的路由与最终入库时间一致
+// 生成ID失败时返回错误，整批由管道保留重试；重试时已有ID的数据保留原ID，
+// 避免后续钩子(如哈希链)已基于该ID计算的结果失效，仍会确认其分表存在
+func (h *LogIdHook) assign(batch []interface{}, pos *model.SchedulePos, strategy model.ShardStrategy) error {
+	now := time.Now()
+	routed := make(map[int]bool)
+	for _, data := range batch {
+		entity, ok := data.(model.Entity)
+		if !ok {
+			continue
+		}
+		if v, ok := plugin.FieldValue(data, "log_id"); ok {
+			if _, shard, ok := idgen.Parse(fmt.Sprint(v)); ok {
+				routed[shard] = true
+				continue
 			}
-			endPos := strconv.Itoa(schedulePos.ScheduleEndPos)
-			h.redis.Set(ctx, key, endPos, 0)
-			res = endPos
-			return schedulePos, nil
-		})
+		}
+		if created, ok := plugin.FieldValue(data, "created_at"); ok {
+			if t, ok := created.(time.Time); ok && t.IsZero() {
+				plugin.SetFieldValue(data, "created_at", now)
+			}
+		}
+		id, err := h.generator.Next()
 		if err != nil {
-			logx.Errorf("获取日志ID失败: %v", err)
-			time.Sleep(time.Second * 10)
-			return h.BeforeExport(ctx, batch)
+			return fmt.Errorf("generate log id: %w", err)
 		}
+		shard := strategy.Route(pos, h.routeTime(data, now))
+		routed[shard] = true
+		entity.SetId(idgen.Format(id, shard))
 	}
-
-	for _, entity := range batch {
-		if entity, ok := entity.(model.Entity); ok {
-			logId := fmt.Sprintf("%s_%s", uuid.New().String(), res)
-			entity.SetId(logId)
+	for shard := range routed {
+		if err := h.ensureShard(pos.Name, shard); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// ensureShard 确保路由到的分表存在，避免写入轮转任务尚未创建的分表，已确认的分表不再检查
+func (h *LogIdHook) ensureShard(name string, shard int) error {
+	key := fmt.Sprintf("%s_%d", name, shard)
+	if _, ok := h.shards.Load(key); ok {
+		return nil
+	}
+	if err := model.EnsureShard(h.db, name, shard); err != nil {
+		return err
+	}
+	h.shards.Store(key, struct{}{})
+	return nil
+}
 
-	return ctx
+// routeTime 取用于分表路由的时间，字段缺失或为空时使用当前时间
+func (h *LogIdHook) routeTime(data interface{}, now time.Time) time.Time {
+	val, ok := plugin.FieldValue(data, h.routeField)
+	if !ok {
+		return now
+	}
+	switch v := val.(type) {
+	case time.Time:
+		if !v.IsZero() {
+			return v
+		}
+	case int64:
+		if v > 0 {
+			return time.UnixMilli(v)
+		}
+	}
+	return now
 }
 
-// OnError 错误处理钩子
+// OnError 错误处理钩子，本钩子失败时管道同样会调用，由管道统一记录失败原因
 func (h *LogIdHook) OnError(ctx context.Context, err error, batch []interface{}) {
-	// 无操作
+	logx.WithContext(ctx).Errorf("logid hook: %d records failed: %v", len(batch), err)
+}
+
+// Close 停止分表位置的订阅，释放雪花机器号租约，使其他实例可以立即复用
+func (h *LogIdHook) Close() error {
+	err := h.positions.Close()
+	if h.lease != nil {
+		err = errors.Join(err, h.lease.Close())
+	}
+	return err
 }
 
 func init() {
@@ -0,0 +1,44 @@
+package lifecycle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"codexie.com/auditlog/internal/constant"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogIdHook(t *testing.T) (*LogIdHook, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewLogIdHook(map[string]any{"redis": client, "db": chainDB(t)}), mr
+}
+
+func subscribers(mr *miniredis.Miniredis) int {
+	return mr.PubSubNumSub(constant.SchedulePosChannel)[constant.SchedulePosChannel]
+}
+
+func TestLogIdHook_CloseStopsWatch(t *testing.T) {
+	h, mr := newTestLogIdHook(t)
+	require.Eventually(t, func() bool { return subscribers(mr) == 1 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, h.Close())
+	// Close等待订阅协程退出，返回时订阅已关闭
+	assert.Eventually(t, func() bool { return subscribers(mr) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestLogIdHook_NonEntityBatch(t *testing.T) {
+	h, _ := newTestLogIdHook(t)
+	defer h.Close()
+
+	batch := []interface{}{"not an entity"}
+	_, err := h.BeforeExport(context.Background(), batch)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"not an entity"}, batch)
+}
@@ -18,8 +18,8 @@ func NewNoopHook() *NoopHook {
 func (h *NoopHook) Name() string { return "noop-lifecycle" }
 
 // BeforeExport 导出前钩子
-func (h *NoopHook) BeforeExport(ctx context.Context, batch []interface{}) context.Context {
-	return ctx
+func (h *NoopHook) BeforeExport(ctx context.Context, batch []interface{}) (context.Context, error) {
+	return ctx, nil
 }
 
 // OnError 错误处理钩子
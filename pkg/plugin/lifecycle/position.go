@@ -0,0 +1,143 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"codexie.com/auditlog/internal/constant"
+	"codexie.com/auditlog/internal/model"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+const (
+	positionLoadRetry   = 3
+	positionLoadBackoff = 200 * time.Millisecond
+)
+
+// positionCache 进程内缓存的分表写入位置
+// 分表轮转后SchedulePositionTask通过Redis发布通知，收到后立即从MySQL重新加载；
+// 同时按固定间隔与MySQL对齐，Redis订阅中断时位置最多滞后一个间隔，旧分表仍可正常写入
+type positionCache struct {
+	db        *gorm.DB
+	redis     *redis.Client
+	interval  time.Duration
+	mu        sync.RWMutex
+	positions map[string]*model.SchedulePos
+	group     singleflight.Group
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+func newPositionCache(db *gorm.DB, client *redis.Client, interval time.Duration) *positionCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &positionCache{
+		db:        db,
+		redis:     client,
+		interval:  interval,
+		positions: make(map[string]*model.SchedulePos),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go c.watch(ctx)
+	return c
+}
+
+// Close 停止订阅与定时对齐，等待后台协程退出
+func (c *positionCache) Close() error {
+	c.cancel()
+	<-c.done
+	return nil
+}
+
+// get 返回实体当前的分表位置，未缓存时同步加载，失败时有限次重试后返回错误
+// 返回的位置为只读快照，刷新时整体替换
+func (c *positionCache) get(ctx context.Context, name string) (*model.SchedulePos, error) {
+	c.mu.RLock()
+	pos, ok := c.positions[name]
+	c.mu.RUnlock()
+	if ok {
+		return pos, nil
+	}
+
+	v, err, _ := c.group.Do(name, func() (interface{}, error) {
+		var err error
+		for i := 0; i < positionLoadRetry; i++ {
+			var pos *model.SchedulePos
+			if pos, err = c.load(ctx, name); err == nil {
+				return pos, nil
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(i+1) * positionLoadBackoff):
+			}
+		}
+		return nil, fmt.Errorf("load schedule position of %s: %w", name, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*model.SchedulePos), nil
+}
+
+func (c *positionCache) load(ctx context.Context, name string) (*model.SchedulePos, error) {
+	pos, err := (&model.SchedulePos{}).GetSchedulePos(c.db.WithContext(ctx), name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if old, ok := c.positions[name]; ok && old.ScheduleEndPos != pos.ScheduleEndPos {
+		logx.Infof("schedule position of %s changed: %d -> %d", name, old.ScheduleEndPos, pos.ScheduleEndPos)
+	}
+	c.positions[name] = pos
+	c.mu.Unlock()
+	return pos, nil
+}
+
+// reload 重新加载已缓存的实体位置，name为空时加载全部
+func (c *positionCache) reload(ctx context.Context, name string) {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.positions))
+	for n := range c.positions {
+		if name == "" || n == name {
+			names = append(names, n)
+		}
+	}
+	c.mu.RUnlock()
+
+	for _, n := range names {
+		if _, err := c.load(ctx, n); err != nil {
+			logx.Errorf("reload schedule position of %s failed: %v", n, err)
+		}
+	}
+}
+
+// watch 订阅位置变更通知并定时对齐，订阅断开由go-redis自动重连，ctx取消时关闭订阅并退出
+func (c *positionCache) watch(ctx context.Context) {
+	defer close(c.done)
+	sub := c.redis.Subscribe(ctx, constant.SchedulePosChannel)
+	defer sub.Close()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			c.reload(ctx, msg.Payload)
+		case <-ticker.C:
+			c.reload(ctx, "")
+		}
+	}
+}
@@ -5,10 +5,11 @@ import (
 )
 
 var (
-	exporterFactoryRegistry  = make(map[string]func(config map[string]any) Exporter)
-	filterFactoryRegistry    = make(map[string]func(config map[string]any) Filter)
-	lifecycleFactoryRegistry = make(map[string]func(config map[string]any) LifecycleHook)
-	mutex                    sync.RWMutex
+	exporterFactoryRegistry    = make(map[string]func(config map[string]any) Exporter)
+	filterFactoryRegistry      = make(map[string]func(config map[string]any) Filter)
+	transformerFactoryRegistry = make(map[string]func(config map[string]any) Transformer)
+	lifecycleFactoryRegistry   = make(map[string]func(config map[string]any) LifecycleHook)
+	mutex                      sync.RWMutex
 )
 
 // RegisterExporter 注册Exporter插件工厂
@@ -45,6 +46,23 @@ func GetFilter(name string, config map[string]any) Filter {
 	return nil
 }
 
+// RegisterTransformer 注册Transformer插件工厂
+func RegisterTransformerFactory(name string, factory func(config map[string]any) Transformer) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	transformerFactoryRegistry[name] = factory
+}
+
+// GetTransformer 根据名称和配置获取Transformer实例
+func GetTransformer(name string, config map[string]any) Transformer {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if factory, ok := transformerFactoryRegistry[name]; ok {
+		return factory(config)
+	}
+	return nil
+}
+
 // RegisterLifecycle 注册Lifecycle插件工厂
 func RegisterLifecycleFactory(name string, factory func(config map[string]any) LifecycleHook) {
 	mutex.Lock()
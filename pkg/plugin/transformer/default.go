@@ -0,0 +1,85 @@
+package transformer
+
+import (
+	"fmt"
+	"reflect"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/plugin"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// Default 默认值转换器，字段为零值时填充配置的默认值
+//
+// 配置示例:
+//
+//	transformers:
+//	  - Name: default
+//	    Config:
+//	      values:
+//	        module: unknown
+//	        result: success
+type Default struct {
+	values plugin.Conf
+}
+
+// NewDefault 根据配置创建默认值转换器
+func NewDefault(conf plugin.Conf) (*Default, error) {
+	values, err := conf.Map("values")
+	if err != nil {
+		return nil, fmt.Errorf("default transformer: %w", err)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("default transformer: values is required")
+	}
+
+	// 启动时校验字段及默认值类型
+	for name := range values {
+		if err := checkField(name); err != nil {
+			return nil, fmt.Errorf("default transformer: %w", err)
+		}
+	}
+	d := &Default{values: values}
+	if err := d.apply(&model.AuditLog{}); err != nil {
+		return nil, fmt.Errorf("default transformer: %w", err)
+	}
+
+	return d, nil
+}
+
+// Name 返回插件名称
+func (d *Default) Name() string { return "default" }
+
+// Transform 填充默认值
+func (d *Default) Transform(data interface{}) []interface{} {
+	if err := d.apply(data); err != nil {
+		logx.Errorf("default transformer: %v", err)
+	}
+	return []interface{}{data}
+}
+
+func (d *Default) apply(data interface{}) error {
+	for name, value := range d.values {
+		current, ok := plugin.FieldValue(data, name)
+		if ok && current != nil && !reflect.ValueOf(current).IsZero() {
+			continue
+		}
+		if err := plugin.SetFieldValue(data, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	plugin.RegisterTransformerFactory("default", func(config map[string]any) plugin.Transformer {
+		d, err := NewDefault(config)
+		if err != nil {
+			panic(err)
+		}
+		return d
+	})
+}
+
+// 确保Default实现了Transformer接口
+var _ plugin.Transformer = (*Default)(nil)
@@ -0,0 +1,163 @@
+package transformer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"codexie.com/auditlog/pkg/plugin"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	RedactModeMask = "mask" // 用掩码字符替换
+	RedactModeHash = "hash" // 替换为加盐哈希，相同原文得到相同结果，便于关联分析
+)
+
+// 内置敏感信息检测器，按顺序组合为一个正则，单次扫描替换
+var redactDetectors = []struct {
+	name    string
+	pattern string
+}{
+	{"email", `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`},
+	{"idcard", `\b[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`},
+	{"phone", `(?:\+?86[\- ]?)?\b1[3-9]\d{9}\b`},
+	{"ip", `\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b|\b(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}\b`},
+}
+
+// Redact 敏感信息脱敏转换器
+// fields中的字段整体脱敏，scan_fields中的字段仅脱敏检测器命中的片段
+// 通常配置在导出器的transformers下，使外部导出器收到脱敏副本而MySQL保留原始数据
+//
+// 配置示例:
+//
+//	exporters:
+//	  - Name: webhook
+//	    Transformers:
+//	      - Name: redact
+//	        Config:
+//	          mode: hash                          # mask|hash，默认mask
+//	          salt: "change-me"                   # hash模式的盐
+//	          fields: [username, client_ip]       # 整体脱敏的字段
+//	          scan_fields: [message]              # 按检测器扫描的字段，默认message
+//	          detectors: [email, phone, ip, idcard] # 默认全部内置检测器
+//	          patterns: ['token=\w+']             # 自定义正则
+type Redact struct {
+	mode       string
+	salt       []byte
+	maskChar   string
+	fields     []string
+	scanFields []string
+	re         *regexp.Regexp
+}
+
+// NewRedact 根据配置创建脱敏转换器
+func NewRedact(conf plugin.Conf) (*Redact, error) {
+	r := &Redact{
+		mode:       strings.ToLower(conf.String("mode", RedactModeMask)),
+		salt:       []byte(conf.String("salt", "")),
+		maskChar:   conf.String("mask_char", "*"),
+		fields:     conf.Strings("fields"),
+		scanFields: conf.Strings("scan_fields"),
+	}
+	if r.mode != RedactModeMask && r.mode != RedactModeHash {
+		return nil, fmt.Errorf("redact transformer: invalid mode %q", r.mode)
+	}
+	if _, ok := conf["scan_fields"]; !ok {
+		r.scanFields = []string{"message"}
+	}
+	for _, name := range append(append([]string{}, r.fields...), r.scanFields...) {
+		if err := checkField(name); err != nil {
+			return nil, fmt.Errorf("redact transformer: %w", err)
+		}
+	}
+
+	detectors := conf.Strings("detectors")
+	if _, ok := conf["detectors"]; !ok {
+		for _, d := range redactDetectors {
+			detectors = append(detectors, d.name)
+		}
+	}
+	patterns := make([]string, 0, len(detectors))
+	for _, name := range detectors {
+		pattern := ""
+		for _, d := range redactDetectors {
+			if d.name == name {
+				pattern = d.pattern
+			}
+		}
+		if pattern == "" {
+			return nil, fmt.Errorf("redact transformer: unknown detector %q", name)
+		}
+		patterns = append(patterns, pattern)
+	}
+	for _, pattern := range conf.Strings("patterns") {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("redact transformer: invalid pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	if len(patterns) > 0 {
+		r.re = regexp.MustCompile("(?:" + strings.Join(patterns, ")|(?:") + ")")
+	}
+	if len(r.fields) == 0 && (r.re == nil || len(r.scanFields) == 0) {
+		return nil, fmt.Errorf("redact transformer: nothing to redact")
+	}
+
+	return r, nil
+}
+
+// Name 返回插件名称
+func (r *Redact) Name() string { return "redact" }
+
+// Transform 对数据进行脱敏
+func (r *Redact) Transform(data interface{}) []interface{} {
+	for _, name := range r.fields {
+		r.replaceField(data, name, func(s string) string { return r.redact(s) })
+	}
+	if r.re != nil {
+		for _, name := range r.scanFields {
+			r.replaceField(data, name, func(s string) string { return r.re.ReplaceAllStringFunc(s, r.redact) })
+		}
+	}
+	return []interface{}{data}
+}
+
+func (r *Redact) replaceField(data interface{}, name string, replace func(string) string) {
+	val, ok := plugin.FieldValue(data, name)
+	if !ok {
+		return
+	}
+	str, ok := val.(string)
+	if !ok || str == "" {
+		return
+	}
+	if err := plugin.SetFieldValue(data, name, replace(str)); err != nil {
+		logx.Errorf("redact transformer: %v", err)
+	}
+}
+
+func (r *Redact) redact(s string) string {
+	if r.mode == RedactModeHash {
+		mac := hmac.New(sha256.New, r.salt)
+		mac.Write([]byte(s))
+		return "h:" + hex.EncodeToString(mac.Sum(nil))[:16]
+	}
+	return strings.Repeat(r.maskChar, len([]rune(s)))
+}
+
+func init() {
+	plugin.RegisterTransformerFactory("redact", func(config map[string]any) plugin.Transformer {
+		r, err := NewRedact(config)
+		if err != nil {
+			panic(err)
+		}
+		return r
+	})
+}
+
+// 确保Redact实现了Transformer接口
+var _ plugin.Transformer = (*Redact)(nil)
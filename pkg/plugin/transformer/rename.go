@@ -0,0 +1,90 @@
+package transformer
+
+import (
+	"fmt"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/plugin"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// Rename 字段重命名转换器，将源字段的值移动到目标字段并清空源字段
+//
+// 配置示例:
+//
+//	transformers:
+//	  - Name: rename
+//	    Config:
+//	      fields:
+//	        module: resource_type   # 源字段: 目标字段
+type Rename struct {
+	fields map[string]string
+}
+
+// NewRename 根据配置创建字段重命名转换器
+func NewRename(conf plugin.Conf) (*Rename, error) {
+	fields, err := conf.Map("fields")
+	if err != nil {
+		return nil, fmt.Errorf("rename transformer: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("rename transformer: fields is required")
+	}
+
+	r := &Rename{fields: make(map[string]string, len(fields))}
+	for from := range fields {
+		to := fields.String(from, "")
+		if err := checkField(from); err != nil {
+			return nil, fmt.Errorf("rename transformer: %w", err)
+		}
+		if err := checkField(to); err != nil {
+			return nil, fmt.Errorf("rename transformer: %w", err)
+		}
+		r.fields[from] = to
+	}
+	return r, nil
+}
+
+// Name 返回插件名称
+func (r *Rename) Name() string { return "rename" }
+
+// Transform 移动字段值
+func (r *Rename) Transform(data interface{}) []interface{} {
+	for from, to := range r.fields {
+		val, ok := plugin.FieldValue(data, from)
+		if !ok {
+			continue
+		}
+		if err := plugin.SetFieldValue(data, to, val); err != nil {
+			logx.Errorf("rename transformer: %v", err)
+			continue
+		}
+		if m, ok := data.(map[string]any); ok {
+			delete(m, from)
+		} else if err := plugin.SetFieldValue(data, from, nil); err != nil {
+			logx.Errorf("rename transformer: %v", err)
+		}
+	}
+	return []interface{}{data}
+}
+
+// checkField 校验字段是否为AuditLog中的字段
+func checkField(name string) error {
+	if _, ok := plugin.FieldValue(&model.AuditLog{}, name); !ok {
+		return fmt.Errorf("unknown field %q", name)
+	}
+	return nil
+}
+
+func init() {
+	plugin.RegisterTransformerFactory("rename", func(config map[string]any) plugin.Transformer {
+		r, err := NewRename(config)
+		if err != nil {
+			panic(err)
+		}
+		return r
+	})
+}
+
+// 确保Rename实现了Transformer接口
+var _ plugin.Transformer = (*Rename)(nil)
@@ -0,0 +1,104 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+
+	"codexie.com/auditlog/internal/model"
+	"codexie.com/auditlog/pkg/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRename_Transform(t *testing.T) {
+	r, err := NewRename(plugin.Conf{"fields": map[string]any{"module": "resource_type"}})
+	require.NoError(t, err)
+
+	log := &model.AuditLog{Module: "vm-service"}
+	out := r.Transform(log)
+	require.Len(t, out, 1)
+	assert.Equal(t, "vm-service", log.ResourceType)
+	assert.Empty(t, log.Module)
+
+	_, err = NewRename(plugin.Conf{"fields": map[string]any{"module": "not_exist"}})
+	assert.Error(t, err)
+}
+
+func TestDefault_Transform(t *testing.T) {
+	d, err := NewDefault(plugin.Conf{"values": map[string]any{"module": "unknown", "timestamp": "1"}})
+	require.NoError(t, err)
+
+	log := &model.AuditLog{TimeStamp: 100}
+	d.Transform(log)
+	assert.Equal(t, "unknown", log.Module)
+	assert.Equal(t, int64(100), log.TimeStamp)
+
+	_, err = NewDefault(plugin.Conf{"values": map[string]any{"timestamp": "yesterday"}})
+	assert.Error(t, err)
+}
+
+func TestTruncate_Transform(t *testing.T) {
+	tr, err := NewTruncate(plugin.Conf{"max_length": "10", "suffix": "..."})
+	require.NoError(t, err)
+
+	short := &model.AuditLog{Message: "short"}
+	tr.Transform(short)
+	assert.Equal(t, "short", short.Message)
+
+	long := &model.AuditLog{Message: "审计日志审计日志"}
+	tr.Transform(long)
+	assert.Equal(t, "审计...", long.Message)
+	assert.LessOrEqual(t, len(long.Message), 10)
+
+	ascii := &model.AuditLog{Message: strings.Repeat("a", 20)}
+	tr.Transform(ascii)
+	assert.Equal(t, "aaaaaaa...", ascii.Message)
+
+	_, err = NewTruncate(plugin.Conf{"max_length": "2"})
+	assert.Error(t, err)
+}
+
+func TestRedact_Mask(t *testing.T) {
+	r, err := NewRedact(plugin.Conf{"fields": []any{"username"}})
+	require.NoError(t, err)
+
+	log := &model.AuditLog{
+		Username: "alice",
+		Message:  "mail alice@example.com phone 13812345678 from 10.0.0.1 id 11010519491231002X ok",
+	}
+	r.Transform(log)
+	assert.Equal(t, "*****", log.Username)
+	assert.Equal(t, "mail ***************** phone *********** from ******** id ****************** ok", log.Message)
+}
+
+func TestRedact_HashAndPatterns(t *testing.T) {
+	r, err := NewRedact(plugin.Conf{
+		"mode":      "hash",
+		"salt":      "s",
+		"detectors": []any{},
+		"patterns":  []any{`token=\w+`},
+	})
+	require.NoError(t, err)
+
+	a := &model.AuditLog{Message: "login token=abc123 from 10.0.0.1"}
+	b := &model.AuditLog{Message: "token=abc123"}
+	r.Transform(a)
+	r.Transform(b)
+	assert.True(t, strings.HasPrefix(a.Message, "login h:"))
+	assert.True(t, strings.HasSuffix(a.Message, " from 10.0.0.1"))
+	assert.Equal(t, b.Message, strings.Fields(a.Message)[1], "same input should hash the same")
+}
+
+func TestNewRedact_InvalidConfig(t *testing.T) {
+	cases := []plugin.Conf{
+		{"mode": "drop"},
+		{"detectors": []any{"passport"}},
+		{"patterns": []any{"("}},
+		{"fields": []any{"not_exist"}},
+		{"detectors": []any{}},
+	}
+	for _, conf := range cases {
+		_, err := NewRedact(conf)
+		assert.Error(t, err, "%v", conf)
+	}
+}
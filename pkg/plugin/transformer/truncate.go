@@ -0,0 +1,84 @@
+package transformer
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"codexie.com/auditlog/pkg/plugin"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	defaultTruncateField  = "message"
+	defaultTruncateLength = 65535 // MySQL TEXT类型最大字节数
+	defaultTruncateSuffix = "...(truncated)"
+)
+
+// Truncate 超长字段截断转换器，按字节截断且不会截断多字节字符
+//
+// 配置示例:
+//
+//	transformers:
+//	  - Name: truncate
+//	    Config:
+//	      field: message        # 默认message
+//	      max_length: 4096      # 最大字节数(含后缀)，默认65535
+//	      suffix: "..."         # 截断后追加的后缀
+type Truncate struct {
+	field     string
+	maxLength int
+	suffix    string
+}
+
+// NewTruncate 根据配置创建截断转换器
+func NewTruncate(conf plugin.Conf) (*Truncate, error) {
+	t := &Truncate{
+		field:     conf.String("field", defaultTruncateField),
+		maxLength: conf.Int("max_length", defaultTruncateLength),
+		suffix:    conf.String("suffix", defaultTruncateSuffix),
+	}
+	if err := checkField(t.field); err != nil {
+		return nil, fmt.Errorf("truncate transformer: %w", err)
+	}
+	if t.maxLength <= len(t.suffix) {
+		return nil, fmt.Errorf("truncate transformer: max_length must be greater than suffix length %d", len(t.suffix))
+	}
+	return t, nil
+}
+
+// Name 返回插件名称
+func (t *Truncate) Name() string { return "truncate" }
+
+// Transform 截断超长字段
+func (t *Truncate) Transform(data interface{}) []interface{} {
+	val, ok := plugin.FieldValue(data, t.field)
+	if !ok {
+		return []interface{}{data}
+	}
+	str, ok := val.(string)
+	if !ok || len(str) <= t.maxLength {
+		return []interface{}{data}
+	}
+
+	cut := t.maxLength - len(t.suffix)
+	for cut > 0 && !utf8.RuneStart(str[cut]) {
+		cut--
+	}
+	if err := plugin.SetFieldValue(data, t.field, str[:cut]+t.suffix); err != nil {
+		logx.Errorf("truncate transformer: %v", err)
+	}
+	return []interface{}{data}
+}
+
+func init() {
+	plugin.RegisterTransformerFactory("truncate", func(config map[string]any) plugin.Transformer {
+		t, err := NewTruncate(config)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	})
+}
+
+// 确保Truncate实现了Transformer接口
+var _ plugin.Transformer = (*Truncate)(nil)
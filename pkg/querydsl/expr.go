@@ -0,0 +1,209 @@
+package querydsl
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+type expr interface {
+	sql(b *strings.Builder, args *[]any)
+	match(get func(field string) (any, bool)) bool
+}
+
+type andExpr struct{ children []expr }
+
+type orExpr struct{ children []expr }
+
+type notExpr struct{ child expr }
+
+// cmpExpr 叶子条件，values已按字段类型归一化，cidr条件使用network
+type cmpExpr struct {
+	field   string
+	typ     string
+	op      string
+	values  []any
+	network *net.IPNet
+}
+
+func (e *andExpr) sql(b *strings.Builder, args *[]any) { joinSQL(b, args, e.children, " AND ") }
+
+func (e *orExpr) sql(b *strings.Builder, args *[]any) { joinSQL(b, args, e.children, " OR ") }
+
+func (e *notExpr) sql(b *strings.Builder, args *[]any) {
+	b.WriteString("NOT ")
+	e.child.sql(b, args)
+}
+
+func joinSQL(b *strings.Builder, args *[]any, children []expr, sep string) {
+	b.WriteByte('(')
+	for i, child := range children {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		child.sql(b, args)
+	}
+	b.WriteByte(')')
+}
+
+var sqlOps = map[string]string{OpEq: "=", OpNe: "<>", OpGt: ">", OpGte: ">=", OpLt: "<", OpLte: "<="}
+
+func (e *cmpExpr) sql(b *strings.Builder, args *[]any) {
+	col := "`" + e.field + "`"
+	b.WriteByte('(')
+	switch e.op {
+	case OpIn, OpNotIn:
+		b.WriteString(col)
+		if e.op == OpNotIn {
+			b.WriteString(" NOT")
+		}
+		b.WriteString(" IN (")
+		for i, v := range e.values {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('?')
+			*args = append(*args, v)
+		}
+		b.WriteByte(')')
+	case OpBetween:
+		b.WriteString(col + " >= ? AND " + col + " < ?")
+		*args = append(*args, e.values[0], e.values[1])
+	case OpPrefix:
+		b.WriteString(col + " LIKE ?")
+		*args = append(*args, escapeLike(e.values[0].(string))+"%")
+	case OpCIDR:
+		// INET6_ATON返回4字节(IPv4)或16字节(IPv6)，先按长度区分协议再比较网段上下界
+		first, last := cidrBounds(e.network)
+		b.WriteString("LENGTH(INET6_ATON(" + col + ")) = ? AND INET6_ATON(" + col + ") BETWEEN ? AND ?")
+		*args = append(*args, len(first), []byte(first), []byte(last))
+	default:
+		b.WriteString(col + " " + sqlOps[e.op] + " ?")
+		*args = append(*args, e.values[0])
+	}
+	b.WriteByte(')')
+}
+
+func (e *andExpr) match(get func(string) (any, bool)) bool {
+	for _, child := range e.children {
+		if !child.match(get) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *orExpr) match(get func(string) (any, bool)) bool {
+	for _, child := range e.children {
+		if child.match(get) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *notExpr) match(get func(string) (any, bool)) bool { return !e.child.match(get) }
+
+func (e *cmpExpr) match(get func(string) (any, bool)) bool {
+	raw, ok := get(e.field)
+	if !ok {
+		return false
+	}
+	val, ok := value(e.typ, raw)
+	if !ok {
+		return false
+	}
+
+	switch e.op {
+	case OpIn, OpNotIn:
+		found := false
+		for _, v := range e.values {
+			if compare(e.typ, val, v) == 0 {
+				found = true
+				break
+			}
+		}
+		return found == (e.op == OpIn)
+	case OpBetween:
+		return compare(e.typ, val, e.values[0]) >= 0 && compare(e.typ, val, e.values[1]) < 0
+	case OpPrefix:
+		return strings.HasPrefix(strings.ToLower(val.(string)), strings.ToLower(e.values[0].(string)))
+	case OpCIDR:
+		// 与INET6_ATON一致按书写形式区分协议，IPv4映射地址(::ffff:a.b.c.d)不匹配IPv4网段
+		s := val.(string)
+		ip := net.ParseIP(s)
+		return ip != nil && strings.Contains(s, ":") == (e.network.IP.To4() == nil) && e.network.Contains(ip)
+	}
+
+	c := compare(e.typ, val, e.values[0])
+	switch e.op {
+	case OpEq:
+		return c == 0
+	case OpNe:
+		return c != 0
+	case OpGt:
+		return c > 0
+	case OpGte:
+		return c >= 0
+	case OpLt:
+		return c < 0
+	case OpLte:
+		return c <= 0
+	}
+	return false
+}
+
+// value 将数据中的字段值转换为字段类型对应的Go类型，时间字段兼容毫秒时间戳
+func value(typ string, raw any) (any, bool) {
+	switch typ {
+	case TypeString, TypeIP:
+		s, ok := raw.(string)
+		return s, ok
+	case TypeInt:
+		n, err := toInt(raw)
+		return n, err == nil
+	case TypeTime:
+		if t, ok := raw.(time.Time); ok {
+			return t, true
+		}
+		if n, err := toInt(raw); err == nil {
+			return time.UnixMilli(n), true
+		}
+	}
+	return nil, false
+}
+
+func compare(typ string, a, b any) int {
+	switch typ {
+	case TypeInt:
+		x, y := a.(int64), b.(int64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case TypeTime:
+		return a.(time.Time).Compare(b.(time.Time))
+	}
+	return strings.Compare(strings.ToLower(a.(string)), strings.ToLower(b.(string)))
+}
+
+// cidrBounds 返回网段的首末地址，IPv4网段返回4字节形式以匹配INET6_ATON的结果
+func cidrBounds(network *net.IPNet) (net.IP, net.IP) {
+	first := network.IP
+	if v4 := first.To4(); v4 != nil {
+		first = v4
+	}
+	mask := network.Mask[len(network.Mask)-len(first):]
+	last := make(net.IP, len(first))
+	for i := range first {
+		last[i] = first[i] | ^mask[i]
+	}
+	return first, last
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
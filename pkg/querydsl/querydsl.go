@@ -0,0 +1,333 @@
+package querydsl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// 字段类型
+const (
+	TypeString = "string" // 字符串，比较时忽略大小写，与MySQL默认排序规则一致
+	TypeInt    = "int"    // 整数
+	TypeTime   = "time"   // 时间，取值为毫秒时间戳或RFC3339字符串
+	TypeIP     = "ip"     // IP地址字符串，额外支持cidr
+)
+
+// 操作符
+const (
+	OpEq      = "eq"
+	OpNe      = "ne"
+	OpIn      = "in"
+	OpNotIn   = "not_in"
+	OpPrefix  = "prefix"
+	OpCIDR    = "cidr"
+	OpGt      = "gt"
+	OpGte     = "gte"
+	OpLt      = "lt"
+	OpLte     = "lte"
+	OpBetween = "between" // 左闭右开区间 [values[0], values[1])
+)
+
+// 查询规模限制，防止构造出过深或过大的SQL
+const (
+	MaxDepth  = 8
+	MaxNodes  = 64
+	MaxValues = 500
+)
+
+var ErrInvalidQuery = errors.New("querydsl: invalid query")
+
+// Node 查询条件节点，and/or/not与叶子条件(field+op)四者只能取其一
+//
+// 示例:
+//
+//	{"and": [
+//	  {"field": "action", "op": "in", "values": ["DELETE_VM", "STOP_VM"]},
+//	  {"not": {"field": "result", "op": "eq", "value": "success"}},
+//	  {"field": "client_ip", "op": "cidr", "value": "10.0.0.0/8"},
+//	  {"field": "created_at", "op": "between", "values": [1717171200000, "2024-06-02T00:00:00Z"]}
+//	]}
+type Node struct {
+	And    []*Node `json:"and,omitempty"`
+	Or     []*Node `json:"or,omitempty"`
+	Not    *Node   `json:"not,omitempty"`
+	Field  string  `json:"field,omitempty"`
+	Op     string  `json:"op,omitempty"`
+	Value  any     `json:"value,omitempty"`
+	Values []any   `json:"values,omitempty"`
+}
+
+// Schema 可查询的字段白名单，字段名 -> 字段类型
+type Schema map[string]string
+
+// Parse 从JSON解析查询条件，未知的键视为错误
+func Parse(data []byte) (*Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	var n Node
+	if err := dec.Decode(&n); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	return &n, nil
+}
+
+// FromMap 从已解码的JSON对象解析查询条件
+func FromMap(m map[string]any) (*Node, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+	}
+	return Parse(data)
+}
+
+// Query 编译后的查询条件，可生成参数化SQL，也可在内存中匹配数据
+type Query struct {
+	root expr
+}
+
+// Compile 按字段白名单校验并编译查询条件，n为空时返回匹配全部数据的查询
+func Compile(schema Schema, n *Node) (*Query, error) {
+	if n == nil {
+		return &Query{}, nil
+	}
+	c := &compiler{schema: schema}
+	root, err := c.compile(n, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{root: root}, nil
+}
+
+// SQL 返回WHERE条件及参数，字段名均来自白名单，取值全部以占位符传入
+// 查询为空时返回空字符串
+func (q *Query) SQL() (string, []any) {
+	if q.root == nil {
+		return "", nil
+	}
+	var b strings.Builder
+	var args []any
+	q.root.sql(&b, &args)
+	return b.String(), args
+}
+
+// Match 在内存中判断数据是否满足条件，get按字段名取值，字段缺失时叶子条件不成立
+func (q *Query) Match(get func(field string) (any, bool)) bool {
+	return q.root == nil || q.root.match(get)
+}
+
+// TimeRange 从顶层AND链中提取字段的时间范围，用于分表裁剪，无约束的一端返回零值
+// OR/NOT下的条件不参与提取，返回的范围只会比实际条件更宽
+func (q *Query) TimeRange(field string) (start, end time.Time) {
+	var walk func(e expr)
+	walk = func(e expr) {
+		switch e := e.(type) {
+		case *andExpr:
+			for _, child := range e.children {
+				walk(child)
+			}
+		case *cmpExpr:
+			if e.field != field || e.typ != TypeTime {
+				return
+			}
+			lower := func(t time.Time) {
+				if start.IsZero() || t.After(start) {
+					start = t
+				}
+			}
+			upper := func(t time.Time) {
+				if end.IsZero() || t.Before(end) {
+					end = t
+				}
+			}
+			switch e.op {
+			case OpEq:
+				lower(e.values[0].(time.Time))
+				upper(e.values[0].(time.Time))
+			case OpGt, OpGte:
+				lower(e.values[0].(time.Time))
+			case OpLt, OpLte:
+				upper(e.values[0].(time.Time))
+			case OpBetween:
+				lower(e.values[0].(time.Time))
+				upper(e.values[1].(time.Time))
+			}
+		}
+	}
+	if q.root != nil {
+		walk(q.root)
+	}
+	return start, end
+}
+
+// 各字段类型允许的操作符
+var typeOps = map[string]map[string]bool{
+	TypeString: {OpEq: true, OpNe: true, OpIn: true, OpNotIn: true, OpPrefix: true, OpGt: true, OpGte: true, OpLt: true, OpLte: true},
+	TypeInt:    {OpEq: true, OpNe: true, OpIn: true, OpNotIn: true, OpGt: true, OpGte: true, OpLt: true, OpLte: true, OpBetween: true},
+	TypeTime:   {OpEq: true, OpNe: true, OpGt: true, OpGte: true, OpLt: true, OpLte: true, OpBetween: true},
+	TypeIP:     {OpEq: true, OpNe: true, OpIn: true, OpNotIn: true, OpPrefix: true, OpCIDR: true},
+}
+
+type compiler struct {
+	schema Schema
+	nodes  int
+}
+
+func (c *compiler) compile(n *Node, depth int) (expr, error) {
+	if n == nil {
+		return nil, fmt.Errorf("%w: empty node", ErrInvalidQuery)
+	}
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("%w: nesting deeper than %d", ErrInvalidQuery, MaxDepth)
+	}
+	if c.nodes++; c.nodes > MaxNodes {
+		return nil, fmt.Errorf("%w: more than %d conditions", ErrInvalidQuery, MaxNodes)
+	}
+
+	kinds := 0
+	for _, set := range []bool{n.And != nil, n.Or != nil, n.Not != nil, n.Field != "" || n.Op != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, fmt.Errorf("%w: node must have exactly one of and/or/not/field", ErrInvalidQuery)
+	}
+
+	switch {
+	case n.And != nil, n.Or != nil:
+		list := n.And
+		if n.Or != nil {
+			list = n.Or
+		}
+		if len(list) == 0 {
+			return nil, fmt.Errorf("%w: empty and/or", ErrInvalidQuery)
+		}
+		children := make([]expr, 0, len(list))
+		for _, child := range list {
+			e, err := c.compile(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, e)
+		}
+		if n.Or != nil {
+			return &orExpr{children: children}, nil
+		}
+		return &andExpr{children: children}, nil
+	case n.Not != nil:
+		e, err := c.compile(n.Not, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{child: e}, nil
+	default:
+		return c.leaf(n)
+	}
+}
+
+func (c *compiler) leaf(n *Node) (expr, error) {
+	typ, ok := c.schema[n.Field]
+	if !ok {
+		return nil, fmt.Errorf("%w: field %q is not queryable", ErrInvalidQuery, n.Field)
+	}
+	if !typeOps[typ][n.Op] {
+		return nil, fmt.Errorf("%w: op %q is not supported on %s field %q", ErrInvalidQuery, n.Op, typ, n.Field)
+	}
+
+	var raw []any
+	switch n.Op {
+	case OpIn, OpNotIn:
+		if n.Value != nil || len(n.Values) == 0 {
+			return nil, fmt.Errorf("%w: %s on %q requires non-empty values", ErrInvalidQuery, n.Op, n.Field)
+		}
+		if len(n.Values) > MaxValues {
+			return nil, fmt.Errorf("%w: %s on %q has more than %d values", ErrInvalidQuery, n.Op, n.Field, MaxValues)
+		}
+		raw = n.Values
+	case OpBetween:
+		if n.Value != nil || len(n.Values) != 2 {
+			return nil, fmt.Errorf("%w: between on %q requires exactly two values", ErrInvalidQuery, n.Field)
+		}
+		raw = n.Values
+	default:
+		if n.Value == nil || n.Values != nil {
+			return nil, fmt.Errorf("%w: %s on %q requires a single value", ErrInvalidQuery, n.Op, n.Field)
+		}
+		raw = []any{n.Value}
+	}
+
+	e := &cmpExpr{field: n.Field, typ: typ, op: n.Op}
+	if n.Op == OpCIDR {
+		s, ok := raw[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: cidr on %q requires a string value", ErrInvalidQuery, n.Field)
+		}
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cidr on %q: %v", ErrInvalidQuery, n.Field, err)
+		}
+		e.network = network
+		return e, nil
+	}
+
+	for _, v := range raw {
+		val, err := normalize(typ, v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %q: %v", ErrInvalidQuery, n.Field, err)
+		}
+		e.values = append(e.values, val)
+	}
+	if n.Op == OpPrefix && e.values[0].(string) == "" {
+		return nil, fmt.Errorf("%w: prefix on %q must not be empty", ErrInvalidQuery, n.Field)
+	}
+	return e, nil
+}
+
+// normalize 将JSON取值转换为字段类型对应的Go类型: string/int64/time.Time
+func normalize(typ string, v any) (any, error) {
+	switch typ {
+	case TypeString, TypeIP:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("expect string, got %v", v)
+	case TypeInt:
+		return toInt(v)
+	case TypeTime:
+		if s, ok := v.(string); ok {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, err
+			}
+			return t, nil
+		}
+		ms, err := toInt(v)
+		if err != nil {
+			return nil, err
+		}
+		return time.UnixMilli(ms), nil
+	}
+	return nil, fmt.Errorf("unknown field type %q", typ)
+}
+
+func toInt(v any) (int64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Int64()
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case float64:
+		if n == float64(int64(n)) {
+			return int64(n), nil
+		}
+	}
+	return 0, fmt.Errorf("expect integer, got %v", v)
+}
@@ -0,0 +1,133 @@
+package querydsl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var schema = Schema{
+	"action":     TypeString,
+	"result":     TypeString,
+	"client_ip":  TypeIP,
+	"seq":        TypeInt,
+	"created_at": TypeTime,
+}
+
+func compile(t *testing.T, js string) *Query {
+	t.Helper()
+	n, err := Parse([]byte(js))
+	require.NoError(t, err)
+	q, err := Compile(schema, n)
+	require.NoError(t, err)
+	return q
+}
+
+func record(m map[string]any) func(string) (any, bool) {
+	return func(field string) (any, bool) {
+		v, ok := m[field]
+		return v, ok
+	}
+}
+
+func TestCompile_SQL(t *testing.T) {
+	q := compile(t, `{"and": [
+		{"field": "action", "op": "in", "values": ["DELETE_VM", "STOP_VM"]},
+		{"not": {"field": "result", "op": "eq", "value": "success"}},
+		{"field": "client_ip", "op": "cidr", "value": "10.0.0.0/8"},
+		{"or": [{"field": "seq", "op": "gt", "value": 10}, {"field": "action", "op": "prefix", "value": "a_%"}]}
+	]}`)
+
+	sql, args := q.SQL()
+	assert.Equal(t, "((`action` IN (?, ?)) AND NOT (`result` = ?) AND "+
+		"(LENGTH(INET6_ATON(`client_ip`)) = ? AND INET6_ATON(`client_ip`) BETWEEN ? AND ?) AND "+
+		"((`seq` > ?) OR (`action` LIKE ?)))", sql)
+	assert.Equal(t, []any{"DELETE_VM", "STOP_VM", "success", 4,
+		[]byte{10, 0, 0, 0}, []byte{10, 255, 255, 255}, int64(10), `a\_\%%`}, args)
+}
+
+func TestCompile_Empty(t *testing.T) {
+	q, err := Compile(schema, nil)
+	require.NoError(t, err)
+	sql, args := q.SQL()
+	assert.Empty(t, sql)
+	assert.Nil(t, args)
+	assert.True(t, q.Match(record(nil)))
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for name, js := range map[string]string{
+		"unknown field":   `{"field": "password", "op": "eq", "value": "x"}`,
+		"unsupported op":  `{"field": "created_at", "op": "prefix", "value": "2024"}`,
+		"cidr on string":  `{"field": "action", "op": "cidr", "value": "10.0.0.0/8"}`,
+		"bad cidr":        `{"field": "client_ip", "op": "cidr", "value": "10.0.0.0/33"}`,
+		"empty in":        `{"field": "action", "op": "in", "values": []}`,
+		"value type":      `{"field": "seq", "op": "eq", "value": "ten"}`,
+		"mixed node":      `{"field": "action", "op": "eq", "value": "x", "not": {"field": "action", "op": "eq", "value": "y"}}`,
+		"empty and":       `{"and": []}`,
+		"between arity":   `{"field": "seq", "op": "between", "values": [1]}`,
+		"empty prefix":    `{"field": "action", "op": "prefix", "value": ""}`,
+		"single for list": `{"field": "action", "op": "eq", "values": ["x"]}`,
+	} {
+		n, err := Parse([]byte(js))
+		if err == nil {
+			_, err = Compile(schema, n)
+		}
+		assert.ErrorIs(t, err, ErrInvalidQuery, name)
+	}
+
+	_, err := Parse([]byte(`{"field": "action", "op": "eq", "value": "x", "raw": "1=1"}`))
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+
+	deep := &Node{Field: "action", Op: OpEq, Value: "x"}
+	for i := 0; i < MaxDepth; i++ {
+		deep = &Node{Not: deep}
+	}
+	_, err = Compile(schema, deep)
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+}
+
+func TestQuery_Match(t *testing.T) {
+	q := compile(t, `{"and": [
+		{"field": "action", "op": "in", "values": ["DELETE_VM", "STOP_VM"]},
+		{"field": "result", "op": "ne", "value": "success"},
+		{"field": "client_ip", "op": "cidr", "value": "10.0.0.0/8"}
+	]}`)
+
+	assert.True(t, q.Match(record(map[string]any{"action": "delete_vm", "result": "fail", "client_ip": "10.1.2.3"})))
+	assert.False(t, q.Match(record(map[string]any{"action": "DELETE_VM", "result": "SUCCESS", "client_ip": "10.1.2.3"})))
+	assert.False(t, q.Match(record(map[string]any{"action": "DELETE_VM", "result": "fail", "client_ip": "11.0.0.1"})))
+	assert.False(t, q.Match(record(map[string]any{"action": "DELETE_VM", "result": "fail", "client_ip": "::ffff:a00:1"})))
+	assert.False(t, q.Match(record(map[string]any{"action": "DELETE_VM", "result": "fail"})))
+
+	v6 := compile(t, `{"field": "client_ip", "op": "cidr", "value": "2001:db8::/32"}`)
+	assert.True(t, v6.Match(record(map[string]any{"client_ip": "2001:db8::1"})))
+	assert.False(t, v6.Match(record(map[string]any{"client_ip": "10.0.0.1"})))
+}
+
+func TestQuery_MatchTime(t *testing.T) {
+	q := compile(t, `{"field": "created_at", "op": "between", "values": [1717200000000, "2024-06-02T00:00:00Z"]}`)
+
+	assert.True(t, q.Match(record(map[string]any{"created_at": time.UnixMilli(1717200000000)})))
+	assert.True(t, q.Match(record(map[string]any{"created_at": int64(1717250000000)})))
+	assert.False(t, q.Match(record(map[string]any{"created_at": time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)})))
+	assert.False(t, q.Match(record(map[string]any{"created_at": "yesterday"})))
+}
+
+func TestQuery_TimeRange(t *testing.T) {
+	q := compile(t, `{"and": [
+		{"field": "created_at", "op": "gte", "value": 1000},
+		{"field": "created_at", "op": "gt", "value": 2000},
+		{"field": "created_at", "op": "lt", "value": 9000},
+		{"or": [{"field": "created_at", "op": "lt", "value": 3000}, {"field": "action", "op": "eq", "value": "x"}]}
+	]}`)
+	start, end := q.TimeRange("created_at")
+	assert.Equal(t, time.UnixMilli(2000), start)
+	assert.Equal(t, time.UnixMilli(9000), end)
+
+	start, end = compile(t, `{"not": {"field": "created_at", "op": "gt", "value": 1000}}`).TimeRange("created_at")
+	assert.True(t, start.IsZero())
+	assert.True(t, end.IsZero())
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLimiter(t *testing.T, conf Config) *TenantLimiter {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return NewTenantLimiter(client, conf)
+}
+
+func TestTenantLimiter_LimitFor(t *testing.T) {
+	l := NewTenantLimiter(nil, Config{
+		Default: Limit{Rate: 100},
+		Tenants: map[string]Limit{"vip": {Rate: 1000, Burst: 2000}},
+	})
+	assert.Equal(t, Limit{Rate: 100, Burst: 100}, l.LimitFor("t1"))
+	assert.Equal(t, Limit{Rate: 1000, Burst: 2000}, l.LimitFor("vip"))
+}
+
+func TestTenantLimiter_LimitForMerge(t *testing.T) {
+	l := NewTenantLimiter(nil, Config{
+		Default: Limit{Rate: 100, Burst: 200, DailyQuota: 1000},
+		Tenants: map[string]Limit{
+			"quota":     {DailyQuota: 5},
+			"rate":      {Rate: 500},
+			"burst":     {Burst: 50},
+			"unlimited": {Rate: -1, DailyQuota: -1},
+		},
+	})
+	// 只覆盖配置了的项，其余继承Default
+	assert.Equal(t, Limit{Rate: 100, Burst: 200, DailyQuota: 5}, l.LimitFor("quota"))
+	assert.Equal(t, Limit{Rate: 500, Burst: 500, DailyQuota: 1000}, l.LimitFor("rate"))
+	assert.Equal(t, Limit{Rate: 100, Burst: 50, DailyQuota: 1000}, l.LimitFor("burst"))
+	assert.Equal(t, Limit{}, l.LimitFor("unlimited"))
+}
+
+func TestTenantLimiter_TenantLabel(t *testing.T) {
+	l := NewTenantLimiter(nil, Config{Tenants: map[string]Limit{"vip": {Rate: 1}}})
+	assert.Equal(t, "vip", l.tenantLabel("vip"))
+	assert.Equal(t, defaultTenantLabel, l.tenantLabel("t-123"))
+}
+
+func TestTenantLimiter_Rate(t *testing.T) {
+	l := newTestLimiter(t, Config{
+		Enabled: true,
+		Default: Limit{Rate: 1, Burst: 3},
+	})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, Allowed, l.Allow(ctx, "t1"))
+	}
+	assert.Equal(t, RateLimited, l.Allow(ctx, "t1"))
+	assert.Equal(t, Allowed, l.Allow(ctx, "t2"), "tenants have separate buckets")
+}
+
+func TestTenantLimiter_Quota(t *testing.T) {
+	l := newTestLimiter(t, Config{
+		Enabled: true,
+		Tenants: map[string]Limit{"t1": {DailyQuota: 2}},
+	})
+	ctx := context.Background()
+	assert.Equal(t, Allowed, l.Allow(ctx, "t1"))
+	assert.Equal(t, Allowed, l.Allow(ctx, "t1"))
+	assert.Equal(t, QuotaExceeded, l.Allow(ctx, "t1"))
+	assert.Equal(t, Allowed, l.Allow(ctx, "t2"), "tenant without limits is not throttled")
+}
+
+func TestTenantLimiter_Disabled(t *testing.T) {
+	l := NewTenantLimiter(nil, Config{Default: Limit{Rate: 1}})
+	assert.Equal(t, Allowed, l.Allow(context.Background(), "t1"))
+}
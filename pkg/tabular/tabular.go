@@ -0,0 +1,146 @@
+// Package tabular 以流式方式将行数据写为CSV、NDJSON、XLSX或Parquet文件
+package tabular
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"codexie.com/auditlog/pkg/parquet"
+)
+
+// 输出格式
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatXLSX    = "xlsx"
+	FormatParquet = "parquet"
+)
+
+// Writer 逐行写入数据，row与创建时给出的列一一对应
+// Close只刷新缓冲并写入文件尾，不关闭底层io.Writer
+type Writer interface {
+	Write(row []any) error
+	Close() error
+}
+
+// New 按格式创建写入器，json视为ndjson
+// prototype为行数据对应的结构体，parquet按其字段类型确定列类型，其他格式可传nil
+func New(format string, w io.Writer, columns []string, prototype any) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSV(w, columns)
+	case FormatNDJSON, "json":
+		return &ndjsonWriter{enc: json.NewEncoder(w), columns: columns}, nil
+	case FormatXLSX:
+		return newXLSX(w, columns)
+	case FormatParquet:
+		schema, err := parquet.ColumnsOf(prototype, columns)
+		if err != nil {
+			return nil, err
+		}
+		pw, err := parquet.NewWriter(w, schema, 0)
+		if err != nil {
+			return nil, err
+		}
+		return pw, nil
+	}
+	return nil, fmt.Errorf("tabular: unsupported format %q", format)
+}
+
+// Ext 返回格式对应的文件扩展名
+func Ext(format string) string {
+	if format == "json" {
+		return FormatNDJSON
+	}
+	return format
+}
+
+// ContentType 返回格式对应的MIME类型
+func ContentType(format string) string {
+	switch Ext(format) {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	}
+	return "application/octet-stream"
+}
+
+type csvWriter struct {
+	w   *csv.Writer
+	buf []string
+}
+
+func newCSV(w io.Writer, columns []string) (*csvWriter, error) {
+	c := &csvWriter{w: csv.NewWriter(w), buf: make([]string, len(columns))}
+	if err := c.w.Write(columns); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *csvWriter) Write(row []any) error {
+	for i, v := range row {
+		c.buf[i] = cellText(v)
+	}
+	return c.w.Write(c.buf)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	enc     *json.Encoder
+	columns []string
+}
+
+func (n *ndjsonWriter) Write(row []any) error {
+	obj := make(map[string]any, len(n.columns))
+	for i, col := range n.columns {
+		obj[col] = row[i]
+	}
+	return n.enc.Encode(obj)
+}
+
+func (n *ndjsonWriter) Close() error { return nil }
+
+// cellText 将表格软件中的文本单元格格式化为文本，以=、+、-、@、制表符或回车开头的值前加单引号，
+// 避免被表格软件当作公式执行(CSV/公式注入)；数值单元格不受影响
+func cellText(v any) string {
+	s := String(v)
+	if _, ok := v.(string); ok && s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// String 将单元格的值格式化为文本，时间使用RFC3339(毫秒精度)
+func String(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format("2006-01-02T15:04:05.000Z07:00")
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+	return fmt.Sprint(v)
+}
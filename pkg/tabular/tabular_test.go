@@ -0,0 +1,127 @@
+package tabular
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var columns = []string{"log_id", "seq", "message", "created_at"}
+
+type row struct {
+	LogId     string    `json:"log_id"`
+	Seq       int64     `json:"seq"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func write(t *testing.T, format string, rows ...[]any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := New(format, &buf, columns, row{})
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.NoError(t, w.Write(row))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	at := time.Date(2024, 6, 1, 8, 0, 0, 123e6, time.UTC)
+	out := write(t, FormatCSV, []any{"01J_1", int64(7), "a,\"b\"", at})
+	assert.Equal(t, "log_id,seq,message,created_at\n01J_1,7,\"a,\"\"b\"\"\",2024-06-01T08:00:00.123Z\n", string(out))
+}
+
+func TestFormulaInjection(t *testing.T) {
+	for _, message := range []string{"=HYPERLINK(\"http://x\")", "+1", "-1+1", "@SUM(A1)", "\tx", "\rx"} {
+		out := write(t, FormatCSV, []any{"01J_1", int64(-7), message, nil})
+		records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, "'"+message, records[1][2], "text cells starting with a formula trigger are quoted")
+		assert.Equal(t, "-7", records[1][1], "numeric cells are kept")
+
+		doc := readSheet(t, write(t, FormatXLSX, []any{"01J_1", int64(-7), message, nil}))
+		assert.Equal(t, "'"+message, doc.Rows[1].Cells[2].S)
+		assert.Equal(t, "-7", doc.Rows[1].Cells[1].V)
+	}
+	out := write(t, FormatCSV, []any{"01J_1", int64(7), "a=b", nil})
+	assert.Contains(t, string(out), ",a=b,")
+}
+
+func TestNDJSON(t *testing.T) {
+	out := write(t, "json", []any{"01J_1", int64(7), "hi", nil}, []any{"01J_2", int64(8), "", nil})
+	assert.Equal(t, `{"created_at":null,"log_id":"01J_1","message":"hi","seq":7}`+"\n"+
+		`{"created_at":null,"log_id":"01J_2","message":"","seq":8}`+"\n", string(out))
+}
+
+func TestXLSX(t *testing.T) {
+	out := write(t, FormatXLSX, []any{"01J_1", int64(7), "<x> & \x01y", time.Time{}})
+
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+	names := make([]string, 0, len(zr.File))
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Contains(t, names, "[Content_Types].xml")
+	assert.Contains(t, names, "xl/workbook.xml")
+
+	// 工作表需是合法的XML，控制字符被去掉，数字以数值单元格写入
+	doc := readSheet(t, out)
+	require.Len(t, doc.Rows, 2)
+	assert.Equal(t, "log_id", doc.Rows[0].Cells[0].S)
+	assert.Equal(t, 2, doc.Rows[1].R)
+	assert.Equal(t, "7", doc.Rows[1].Cells[1].V)
+	assert.Equal(t, "<x> & y", doc.Rows[1].Cells[2].S)
+	assert.Equal(t, "", doc.Rows[1].Cells[3].S)
+}
+
+type sheetDoc struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			T string `xml:"t,attr"`
+			V string `xml:"v"`
+			S string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readSheet(t *testing.T, out []byte) *sheetDoc {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+	rc, err := zr.Open("xl/worksheets/sheet1.xml")
+	require.NoError(t, err)
+	defer rc.Close()
+	sheet, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	doc := &sheetDoc{}
+	require.NoError(t, xml.Unmarshal(sheet, doc))
+	return doc
+}
+
+func TestParquet(t *testing.T) {
+	at := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	out := write(t, FormatParquet, []any{"01J_1", int64(7), "hi", at}, []any{"01J_2", int64(8), "", time.Time{}})
+	assert.Equal(t, "PAR1", string(out[:4]))
+	assert.Equal(t, "PAR1", string(out[len(out)-4:]))
+	assert.Equal(t, "parquet", Ext(FormatParquet))
+
+	// 列类型由原型推导，不存在的列无法写出
+	_, err := New(FormatParquet, io.Discard, []string{"missing"}, row{})
+	assert.Error(t, err)
+}
+
+func TestUnsupported(t *testing.T) {
+	_, err := New("xml", io.Discard, columns, nil)
+	assert.Error(t, err)
+}
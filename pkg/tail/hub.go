@@ -0,0 +1,363 @@
+package tail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	redisTimeout   = time.Second
+	listenersCache = time.Second // 其他副本订阅数的缓存时间
+)
+
+var (
+	ErrTooManySubscribers = errors.New("tail: too many subscribers")
+	ErrSlowConsumer       = errors.New("tail: subscriber is too slow")
+	ErrClosed             = errors.New("tail: hub is closed")
+	ErrQueueFull          = errors.New("tail: broadcast queue is full")
+)
+
+// Config 实时订阅配置
+type Config struct {
+	Channel        string `json:",default=audit:tail" yaml:"Channel"`  // Redis频道，多副本需配置相同的值
+	Buffer         int    `json:",default=256" yaml:"Buffer"`          // 每个订阅者缓冲的记录数
+	MaxSubscribers int    `json:",default=1000" yaml:"MaxSubscribers"` // 单个实例的订阅者上限
+	SlowTimeout    int    `json:",default=30" yaml:"SlowTimeout"`      // 缓冲区持续满载超过该时间(秒)的订阅者被断开
+	Heartbeat      int    `json:",default=15" yaml:"Heartbeat"`        // 空闲时发送心跳的间隔(秒)
+	Queue          int    `json:",default=64" yaml:"Queue"`            // 等待广播给其他副本的批次上限，队列满时丢弃新的批次
+	// AllowedOrigins 允许发起WebSocket订阅的页面来源(scheme://host[:port])，与服务同源或不带Origin的请求始终允许
+	AllowedOrigins []string `json:",optional" yaml:"AllowedOrigins"`
+}
+
+// OriginAllowed 判断WebSocket握手请求的Origin是否允许，host为请求的Host
+// 浏览器跨站发起的WebSocket不受同源策略限制，需由服务端校验来源，防止跨站劫持订阅
+func (c Config) OriginAllowed(origin, host string) bool {
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, host) {
+		return true
+	}
+	origin = u.Scheme + "://" + u.Host
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// message 通过Redis广播的一批记录，origin为发布实例，实例忽略自己发布的消息
+type message struct {
+	Origin  string            `json:"origin"`
+	Records []json.RawMessage `json:"records"`
+}
+
+// Hub 将记录分发给本实例的订阅者，配置Redis时同时通过pub/sub转发给其他副本
+// 分发不会阻塞发布方：订阅者缓冲区满时丢弃记录并计数，持续满载的订阅者被断开
+// 实例只在有订阅者时订阅Redis频道，发布方据此在没有任何订阅者时跳过广播
+// 广播经有界队列由后台协程发送，Redis缓慢或不可用时丢弃批次，不拖慢发布方
+type Hub struct {
+	conf      Config
+	id        string
+	redis     *redis.Client
+	newRecord func() any // 创建用于解码其他副本记录的对象，为空时解码为map
+
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	pubsub *redis.PubSub
+	closed bool
+
+	queue chan []byte   // 等待广播的消息
+	stop  chan struct{} // 关闭时通知广播协程退出
+
+	listeners   atomic.Int64 // 订阅了Redis频道的实例数缓存，-1表示查询失败、按有订阅处理
+	listenersAt atomic.Int64 // 缓存时间，UnixNano
+}
+
+// NewHub 创建订阅中心，client为空时只在本实例内分发
+func NewHub(client *redis.Client, conf Config, newRecord func() any) *Hub {
+	id := make([]byte, 8)
+	rand.Read(id)
+	h := &Hub{
+		conf:      conf,
+		id:        hex.EncodeToString(id),
+		redis:     client,
+		newRecord: newRecord,
+		subs:      make(map[*Subscription]struct{}),
+		stop:      make(chan struct{}),
+	}
+	if client != nil {
+		h.queue = make(chan []byte, max(conf.Queue, 1))
+		go h.broadcast()
+	}
+	return h
+}
+
+// Subscription 一个订阅者，C中按发布顺序收到匹配的记录
+type Subscription struct {
+	C <-chan any
+
+	ch        chan any
+	hub       *Hub
+	match     func(any) bool
+	skipped   atomic.Int64
+	fullSince atomic.Int64 // 缓冲区开始持续满载的时间，UnixNano，0表示未满
+	done      chan struct{}
+	once      sync.Once
+	err       error
+}
+
+// Subscribe 订阅满足match的记录，match为空时接收全部记录
+func (h *Hub) Subscribe(match func(any) bool) (*Subscription, error) {
+	ch := make(chan any, max(h.conf.Buffer, 1))
+	s := &Subscription{C: ch, ch: ch, hub: h, match: match, done: make(chan struct{})}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+	if h.conf.MaxSubscribers > 0 && len(h.subs) >= h.conf.MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+	h.subs[s] = struct{}{}
+	if h.redis != nil && h.pubsub == nil {
+		h.pubsub = h.redis.Subscribe(context.Background(), h.conf.Channel)
+		go h.receive(h.pubsub)
+	}
+	return s, nil
+}
+
+// Done 订阅结束(主动关闭、被断开或订阅中心关闭)时关闭
+func (s *Subscription) Done() <-chan struct{} { return s.done }
+
+// Err 订阅因消费过慢被断开时返回ErrSlowConsumer，订阅中心关闭时返回ErrClosed
+func (s *Subscription) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Skipped 返回上次调用以来因缓冲区已满被丢弃的记录数
+func (s *Subscription) Skipped() int64 { return s.skipped.Swap(0) }
+
+// Close 取消订阅
+func (s *Subscription) Close() { s.hub.remove(s, nil) }
+
+// offer 不阻塞地投递记录，返回订阅者是否应被断开
+func (s *Subscription) offer(record any, now time.Time, slowTimeout time.Duration) bool {
+	if s.match != nil && !s.match(record) {
+		return false
+	}
+	select {
+	case s.ch <- record:
+		s.fullSince.Store(0)
+		return false
+	default:
+	}
+	s.skipped.Add(1)
+	since := s.fullSince.Load()
+	if since == 0 {
+		s.fullSince.CompareAndSwap(0, now.UnixNano())
+		return false
+	}
+	return slowTimeout > 0 && now.Sub(time.Unix(0, since)) > slowTimeout
+}
+
+// remove 移除订阅者，最后一个订阅者离开时退订Redis频道
+// 订阅者的通道不关闭，避免与并发的分发冲突，结束由done通知
+func (h *Hub) remove(s *Subscription, err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, s)
+	if len(h.subs) == 0 && h.pubsub != nil {
+		h.pubsub.Close()
+		h.pubsub = nil
+	}
+}
+
+// Publish 分发一批记录，本实例订阅者直接投递，其他副本可能有订阅者时放入广播队列
+// 记录在返回前完成序列化，调用方之后可以修改记录；队列已满时丢弃该批广播并返回ErrQueueFull
+func (h *Hub) Publish(ctx context.Context, records []any) error {
+	if len(records) == 0 {
+		return nil
+	}
+	h.dispatch(records)
+	if h.redis == nil || !h.mayHaveRemoteListeners() {
+		return nil
+	}
+
+	msg := message{Origin: h.id, Records: make([]json.RawMessage, 0, len(records))}
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		msg.Records = append(msg.Records, data)
+	}
+	payload, err := json.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-h.stop:
+		return ErrClosed
+	default:
+	}
+	select {
+	case h.queue <- payload:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// broadcast 从队列取出消息，其他副本有订阅者时发布到Redis频道，订阅中心关闭后退出
+func (h *Hub) broadcast() {
+	for {
+		select {
+		case <-h.stop:
+			return
+		case payload := <-h.queue:
+			ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+			if h.hasRemoteListeners(ctx) {
+				if err := h.redis.Publish(ctx, h.conf.Channel, payload).Err(); err != nil {
+					logx.Errorf("tail: publish to %s failed: %v", h.conf.Channel, err)
+				}
+			}
+			cancel()
+		}
+	}
+}
+
+// mayHaveRemoteListeners 只根据缓存判断，缓存过期或查询失败时按有订阅处理，由广播协程重新查询
+func (h *Hub) mayHaveRemoteListeners() bool {
+	if time.Now().UnixNano()-h.listenersAt.Load() > int64(listenersCache) {
+		return true
+	}
+	return h.remoteListeners()
+}
+
+// hasRemoteListeners 判断是否有其他副本订阅了频道
+// 查询失败时按有订阅处理，并同样缓存该结果，避免Redis不可用时每批记录都重新查询
+func (h *Hub) hasRemoteListeners(ctx context.Context) bool {
+	now := time.Now().UnixNano()
+	if now-h.listenersAt.Load() > int64(listenersCache) {
+		res, err := h.redis.PubSubNumSub(ctx, h.conf.Channel).Result()
+		if err != nil {
+			logx.Errorf("tail: query subscribers of %s failed: %v", h.conf.Channel, err)
+			h.listeners.Store(-1)
+		} else {
+			h.listeners.Store(res[h.conf.Channel])
+		}
+		h.listenersAt.Store(now)
+	}
+	return h.remoteListeners()
+}
+
+// remoteListeners 根据缓存的订阅实例数判断其他副本是否有订阅者，订阅数需扣除本实例
+func (h *Hub) remoteListeners() bool {
+	n := h.listeners.Load()
+	if n < 0 {
+		return true
+	}
+	h.mu.RLock()
+	self := h.pubsub != nil
+	h.mu.RUnlock()
+	if self {
+		return n > 1
+	}
+	return n > 0
+}
+
+// dispatch 将记录投递给本实例的订阅者，断开持续满载的订阅者
+func (h *Hub) dispatch(records []any) {
+	now := time.Now()
+	slowTimeout := time.Duration(h.conf.SlowTimeout) * time.Second
+	var slow []*Subscription
+
+	h.mu.RLock()
+	for s := range h.subs {
+		for _, record := range records {
+			if s.offer(record, now, slowTimeout) {
+				slow = append(slow, s)
+				break
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, s := range slow {
+		logx.Infof("tail: subscriber dropped after skipping records for %s", slowTimeout)
+		h.remove(s, ErrSlowConsumer)
+	}
+}
+
+// receive 接收其他副本广播的记录，pubsub关闭后退出
+func (h *Hub) receive(pubsub *redis.PubSub) {
+	for m := range pubsub.Channel() {
+		var msg message
+		if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+			logx.Errorf("tail: decode message failed: %v", err)
+			continue
+		}
+		if msg.Origin == h.id {
+			continue
+		}
+		records := make([]any, 0, len(msg.Records))
+		for _, data := range msg.Records {
+			var record any
+			if h.newRecord != nil {
+				record = h.newRecord()
+			}
+			if err := json.Unmarshal(data, &record); err != nil {
+				logx.Errorf("tail: decode record failed: %v", err)
+				continue
+			}
+			records = append(records, record)
+		}
+		h.dispatch(records)
+	}
+}
+
+// Close 断开所有订阅者、退订Redis频道并停止广播，队列中尚未发送的消息被丢弃
+func (h *Hub) Close() {
+	h.mu.Lock()
+	if !h.closed {
+		close(h.stop)
+	}
+	h.closed = true
+	subs := make([]*Subscription, 0, len(h.subs))
+	for s := range h.subs {
+		subs = append(subs, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range subs {
+		h.remove(s, ErrClosed)
+	}
+}
@@ -0,0 +1,223 @@
+package tail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type event struct {
+	TenantID string `json:"tenant_id"`
+	Action   string `json:"action"`
+}
+
+func tenantIs(tenant string) func(any) bool {
+	return func(record any) bool { return record.(*event).TenantID == tenant }
+}
+
+func receive(t *testing.T, s *Subscription) *event {
+	t.Helper()
+	select {
+	case record := <-s.C:
+		return record.(*event)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no record received")
+		return nil
+	}
+}
+
+func assertEmpty(t *testing.T, s *Subscription) {
+	t.Helper()
+	select {
+	case record := <-s.C:
+		t.Fatalf("unexpected record %v", record)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHub_Local(t *testing.T) {
+	h := NewHub(nil, Config{Buffer: 8}, nil)
+	a, err := h.Subscribe(tenantIs("t1"))
+	require.NoError(t, err)
+	all, err := h.Subscribe(nil)
+	require.NoError(t, err)
+
+	require.NoError(t, h.Publish(context.Background(), []any{
+		&event{TenantID: "t1", Action: "LOGIN"},
+		&event{TenantID: "t2", Action: "LOGIN"},
+	}))
+	assert.Equal(t, "t1", receive(t, a).TenantID)
+	assertEmpty(t, a)
+	assert.Equal(t, "t1", receive(t, all).TenantID)
+	assert.Equal(t, "t2", receive(t, all).TenantID)
+
+	a.Close()
+	assert.NoError(t, a.Err())
+	<-a.Done()
+	require.NoError(t, h.Publish(context.Background(), []any{&event{TenantID: "t1"}}))
+	assertEmpty(t, a)
+}
+
+func TestHub_SlowConsumer(t *testing.T) {
+	h := NewHub(nil, Config{Buffer: 2}, nil)
+	s, err := h.Subscribe(nil)
+	require.NoError(t, err)
+
+	// 缓冲区满时丢弃记录，不阻塞发布方
+	records := []any{&event{Action: "1"}, &event{Action: "2"}, &event{Action: "3"}, &event{Action: "4"}}
+	require.NoError(t, h.Publish(context.Background(), records))
+	assert.Equal(t, int64(2), s.Skipped())
+	assert.Equal(t, int64(0), s.Skipped())
+	assert.Equal(t, "1", receive(t, s).Action)
+
+	// 未配置SlowTimeout时不断开
+	require.NoError(t, h.Publish(context.Background(), records))
+	assert.NoError(t, s.Err())
+
+	h = NewHub(nil, Config{Buffer: 1, SlowTimeout: 1}, nil)
+	s, err = h.Subscribe(nil)
+	require.NoError(t, err)
+	require.NoError(t, h.Publish(context.Background(), records))
+	assert.NoError(t, s.Err(), "buffer only just became full")
+	time.Sleep(1100 * time.Millisecond)
+	require.NoError(t, h.Publish(context.Background(), records[:1]))
+	select {
+	case <-s.Done():
+	default:
+		t.Fatal("slow subscriber is not dropped")
+	}
+	assert.ErrorIs(t, s.Err(), ErrSlowConsumer)
+}
+
+func TestHub_Limits(t *testing.T) {
+	h := NewHub(nil, Config{Buffer: 1, MaxSubscribers: 1}, nil)
+	s, err := h.Subscribe(nil)
+	require.NoError(t, err)
+	_, err = h.Subscribe(nil)
+	assert.ErrorIs(t, err, ErrTooManySubscribers)
+
+	h.Close()
+	<-s.Done()
+	assert.ErrorIs(t, s.Err(), ErrClosed)
+	_, err = h.Subscribe(nil)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestHub_Redis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	newHub := func() *Hub {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		return NewHub(client, Config{Channel: "audit:tail", Buffer: 8}, func() any { return &event{} })
+	}
+	publisher, replica := newHub(), newHub()
+	defer publisher.Close()
+	defer replica.Close()
+
+	// 没有任何订阅者时不广播
+	ctx := context.Background()
+	assert.False(t, publisher.hasRemoteListeners(ctx))
+
+	local, err := publisher.Subscribe(tenantIs("t1"))
+	require.NoError(t, err)
+	remote, err := replica.Subscribe(tenantIs("t1"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub("audit:tail")["audit:tail"] == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	publisher.listenersAt.Store(0)
+
+	require.NoError(t, publisher.Publish(ctx, []any{
+		&event{TenantID: "t2", Action: "LOGIN"},
+		&event{TenantID: "t1", Action: "DELETE_VM"},
+	}))
+	assert.Equal(t, &event{TenantID: "t1", Action: "DELETE_VM"}, receive(t, remote))
+	assertEmpty(t, remote)
+	// 发布实例的订阅者只收到本地分发的一份
+	assert.Equal(t, "DELETE_VM", receive(t, local).Action)
+	assertEmpty(t, local)
+
+	// 最后一个订阅者离开后退订频道
+	remote.Close()
+	local.Close()
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub("audit:tail")["audit:tail"] == 0
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestHub_ListenersErrorCached(t *testing.T) {
+	// 连接被拒绝，查询订阅数立即失败
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	h := NewHub(client, Config{Channel: "audit:tail"}, nil)
+	defer h.Close()
+
+	assert.True(t, h.hasRemoteListeners(context.Background()), "unknown subscribers are assumed")
+	at := h.listenersAt.Load()
+	assert.NotZero(t, at)
+	assert.Equal(t, int64(-1), h.listeners.Load())
+
+	// 缓存期内不重新查询
+	assert.True(t, h.hasRemoteListeners(context.Background()))
+	assert.True(t, h.mayHaveRemoteListeners())
+	assert.Equal(t, at, h.listenersAt.Load())
+}
+
+func TestHub_BroadcastQueue(t *testing.T) {
+	// 连接一直建立不上，广播协程阻塞在第一条消息上
+	release := make(chan struct{})
+	defer close(release)
+	client := redis.NewClient(&redis.Options{
+		MaxRetries: -1,
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-release:
+				return nil, errors.New("unreachable")
+			}
+		},
+	})
+	h := NewHub(client, Config{Channel: "audit:tail", Queue: 1}, nil)
+	defer h.Close()
+
+	// 广播阻塞时发布不被拖慢，超出队列的批次被丢弃
+	start := time.Now()
+	var errs []error
+	for i := 0; i < 3; i++ {
+		errs = append(errs, h.Publish(context.Background(), []any{&event{Action: strconv.Itoa(i)}}))
+	}
+	assert.Less(t, time.Since(start), redisTimeout/2)
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[2], ErrQueueFull)
+
+	h.Close()
+	assert.ErrorIs(t, h.Publish(context.Background(), []any{&event{}}), ErrClosed)
+}
+
+func TestConfig_OriginAllowed(t *testing.T) {
+	conf := Config{AllowedOrigins: []string{"https://console.example.com/"}}
+	tests := []struct {
+		origin string
+		host   string
+		want   bool
+	}{
+		{origin: "", host: "audit.example.com", want: true},
+		{origin: "https://audit.example.com", host: "audit.example.com", want: true},
+		{origin: "http://Audit.example.com:8080", host: "audit.example.com:8080", want: true},
+		{origin: "https://console.example.com", host: "audit.example.com", want: true},
+		{origin: "http://console.example.com", host: "audit.example.com", want: false},
+		{origin: "https://evil.example.org", host: "audit.example.com", want: false},
+		{origin: "null", host: "audit.example.com", want: false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, conf.OriginAllowed(tt.origin, tt.host), tt.origin)
+	}
+	assert.True(t, Config{AllowedOrigins: []string{"*"}}.OriginAllowed("https://evil.example.org", "audit.example.com"))
+}
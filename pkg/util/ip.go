@@ -2,7 +2,9 @@ package util
 
 import (
 	"errors"
+	"fmt"
 	"net"
+	"syscall"
 )
 
 // getLocalIP 获取本地IP地址
@@ -28,3 +30,44 @@ func GetLocalIP() (string, error) {
 
 	return "", errors.New("未找到本地IP地址")
 }
+
+// nonPublicNets net.IP方法未覆盖的保留网段：本网络和运营商级NAT
+var nonPublicNets = []*net.IPNet{
+	mustCIDR("0.0.0.0/8"),
+	mustCIDR("100.64.0.0/10"),
+}
+
+func mustCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// IsPublicIP 是否为公网地址，回环、私有、链路本地(含云厂商元数据地址169.254.169.254)、组播及保留地址都不是
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, n := range nonPublicNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// PublicOnly 用作net.Dialer的Control，拒绝连接非公网地址，防止出站请求被引导访问内网(SSRF)
+// Control在域名解析之后调用，解析结果指向内网的域名同样会被拒绝
+func PublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+		return fmt.Errorf("connect to non-public address %s is not allowed", host)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2001:4860:4860::8888"} {
+		assert.True(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{
+		// 回环和私有地址
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "fd00::1",
+		// 链路本地，含云厂商元数据地址
+		"169.254.169.254", "fe80::1",
+		// 未指定、本网络、运营商级NAT和组播
+		"0.0.0.0", "0.1.2.3", "::", "100.64.0.1", "224.0.0.1", "ff02::1",
+		// IPv4映射地址
+		"::ffff:127.0.0.1", "::ffff:10.0.0.1",
+	} {
+		assert.False(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestPublicOnly(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client := &http.Client{
+		Timeout:   time.Second,
+		Transport: &http.Transport{DialContext: (&net.Dialer{Control: PublicOnly}).DialContext},
+	}
+	_, err := client.Get(srv.URL)
+	assert.ErrorContains(t, err, "non-public address 127.0.0.1")
+
+	assert.NoError(t, PublicOnly("tcp", "8.8.8.8:443", nil))
+	assert.Error(t, PublicOnly("tcp", "[fe80::1]:443", nil))
+}